@@ -0,0 +1,511 @@
+// Package agent implements the chat loop between the user, the model and
+// the tools.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/config"
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/index"
+	"github.com/mschoch/dacs/middleware"
+	"github.com/mschoch/dacs/notify"
+	"github.com/mschoch/dacs/policy"
+	"github.com/mschoch/dacs/provider"
+	"github.com/mschoch/dacs/render"
+	"github.com/mschoch/dacs/session"
+	"github.com/mschoch/dacs/style"
+	"github.com/mschoch/dacs/tools"
+	"github.com/mschoch/dacs/voice"
+	"github.com/mschoch/dacs/workspace"
+)
+
+var (
+	FALSE = false
+	TRUE  = true
+)
+
+const SystemPrompt = "You are an assistant with access to tools, if you do not have a tool to deal with the user's request but you think you can answer do it so, if not provide a list of the tools you do have."
+
+func New(
+	client provider.Provider,
+	cfg *config.Config,
+	getUserMessage func(prompt string) (string, bool),
+	tools []tools.Tool) *Agent {
+	a := &Agent{
+		client:             client,
+		cfg:                cfg,
+		localBackend:       isLocalHost(cfg.OllamaHost),
+		toolsLLM:           cfg.ToolsLLM,
+		think:              cfg.Think,
+		showThoughts:       cfg.ShowThoughts,
+		autoContext:        cfg.AutoContext,
+		speak:              cfg.Speak,
+		dryRun:             cfg.DryRun,
+		systemPromptAppend: cfg.SystemPromptAppend,
+		fixedNumCtx:        cfg.NumCtx,
+		maxNumCtx:          cfg.MaxNumCtx,
+		maxToolRounds:      cfg.MaxToolRounds,
+		maxRepairs:         cfg.MaxRepairs,
+		draftLLM:           cfg.DraftLLM,
+		critic:             cfg.Critic,
+		criticLLM:          cfg.CriticLLM,
+		showTimings:        cfg.ShowTimings,
+		spinner:            true,
+		languagePacks:      cfg.LanguagePacks,
+		saveStats:          cfg.SessionStats,
+		getUserMessage:     getUserMessage,
+		tools:              tools,
+		allTools:           tools,
+		baseModel:          cfg.ToolsLLM,
+		session:            session.New(SystemPrompt),
+		renderer:           render.New(),
+		turnStart:          -1,
+		workspace:          workspace.FromContext(context.Background()),
+		systemTemplate:     template.Must(template.New("system").Funcs(templateFuncs).Parse(DefaultSystemTemplate)),
+	}
+	a.renderer.Width = cfg.Width
+	a.UseToolMiddleware(a.showToolCall, a.recordToolStats, a.labelResults, a.dedupReads, a.critique)
+	a.UseInferenceMiddleware(a.recordTimings)
+	return a
+}
+
+type Agent struct {
+	client provider.Provider
+	// cfg is what the agent was created with, for its workers
+	cfg *config.Config
+	// localBackend is set when Ollama runs on this machine
+	localBackend bool
+	toolsLLM     string
+	think        bool
+	showThoughts bool
+	autoContext  bool
+	index        *index.Index
+	transcriber  *voice.Transcriber
+	speaker      *voice.Speaker
+	speak        bool
+	notifier     *notify.Notifier
+	notifyAfter  time.Duration
+	turnStarted  time.Time
+	// attachments are added after the next user input
+	attachments   []api.Message
+	workspace     *workspace.Workspace
+	dryRun        bool
+	lastThoughts  string
+	fixedNumCtx   int
+	maxNumCtx     int
+	modelMaxCtx   int
+	currentNumCtx int
+	lastNumCtx    int
+	// modelChecked is the last model ensureModel looked for
+	modelChecked string
+	// turnStart is the index of the first message produced in response to
+	// the user's last input, -1 when unknown
+	turnStart int
+	retry     retryOptions
+	// toolChoice restricts the tools offered, see cmdAsk and cmdUse
+	toolChoice toolChoice
+	// toolRounds counts the consecutive tool rounds since the user's last
+	// input, the agent checks in with the user after maxToolRounds
+	toolRounds    int
+	maxToolRounds int
+	// draftLLM, when set, is a small model answering trivial turns
+	draftLLM string
+	// reads maps the file reads in the conversation to their results,
+	// lastRead is the last one and deduped whether the last call's result
+	// was replaced as a repeated read, see dedupReads
+	reads    map[readDigest]string
+	lastRead readDigest
+	deduped  bool
+	// critic, when set, has criticLLM (or the tools model) review changes
+	// before they are applied, see critique
+	critic           bool
+	criticLLM        string
+	criticRejections int
+	// timings of the current turn, shown after it when showTimings is set
+	timings     turnTimings
+	showTimings bool
+	// spinner is shown while waiting for the model, unless the agent is
+	// one of several working at once
+	spinner bool
+	// languagePacks adds the conventions of the workspace's languages to
+	// the system prompt, conventions is nil until they are detected
+	languagePacks bool
+	conventions   *string
+	// unknownTools counts the calls of tools that do not exist in the turn,
+	// see maxUnknownTools
+	unknownTools int
+	// repairs counts the tool calls with invalid arguments sent back to the
+	// model in the turn, up to maxRepairs
+	repairs    int
+	maxRepairs int
+	// saveStats saves the session's metadata after every turn
+	saveStats bool
+	// autosavePath is where the session is saved after every turn and tool
+	// call for crash recovery, empty when not autosaving
+	autosavePath string
+	// resume makes Run go back to inference after a command
+	resume         bool
+	getUserMessage func(prompt string) (string, bool)
+	tools          []tools.Tool
+	// allTools and baseModel are restored when leaving a persona
+	allTools       []tools.Tool
+	baseModel      string
+	disabledTools  map[string]bool
+	policy         *policy.Policy
+	personas       map[string]Persona
+	persona        string
+	session        *session.Session
+	systemTemplate *template.Template
+	// systemPromptAppend is added to the rendered system prompt
+	systemPromptAppend string
+	renderer           *render.Renderer
+
+	// middleware wrapping every inference and tool call, outermost first
+	inferenceMiddleware []middleware.Inference
+	toolMiddleware      []middleware.Tool
+}
+
+// UseWorkspace sets the roots the tools operate in, by default it is the
+// working directory.
+func (a *Agent) UseWorkspace(ws *workspace.Workspace) {
+	a.workspace = ws
+}
+
+// errTurnFailed ends the turn, Run reports it and reads the user's next
+// input rather than ending the session.
+var errTurnFailed = errors.New("the turn failed")
+
+func (a *Agent) Session() *session.Session {
+	return a.session
+}
+
+func (a *Agent) Run(ctx context.Context) error {
+	i18n.Printf("Chat with %s (use 'ctrl-c' to quit)\n", a.toolsLLM)
+	if a.dryRun {
+		fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("dry run")), i18n.T("tools report what they would change without changing anything"))
+	}
+	if a.cfg.Autosave {
+		a.startAutosave()
+	}
+
+	for {
+		userInput, ok := a.getUserMessage(style.Label(style.Blue, i18n.T("You")) + ": ")
+		if !ok {
+			break
+		}
+
+		if isCommand(userInput) {
+			err := a.runCommand(ctx, userInput)
+			if err != nil {
+				i18n.Printf("Error: %s\n", err.Error())
+			}
+			if !a.resume {
+				continue
+			}
+			a.resume = false
+		} else {
+			a.addUserInput(ctx, userInput)
+		}
+
+		if err := a.respond(ctx); errors.Is(err, errTurnFailed) {
+			i18n.Printf("Error: %s\n", err.Error())
+		} else if err != nil {
+			return err
+		}
+	}
+
+	a.discardAutosave()
+	return nil
+}
+
+// RunPrompt runs a single turn for the prompt, without reading any user
+// input, until the model stops calling tools.
+func (a *Agent) RunPrompt(ctx context.Context, prompt string) error {
+	a.addUserInput(ctx, prompt)
+	return a.respond(ctx)
+}
+
+// respond runs inference and the tools the model calls, round after round,
+// until the model answers without calling tools or the user stops it.
+func (a *Agent) respond(ctx context.Context) error {
+	a.ensureModel(ctx)
+	defer a.printTimings()
+	defer a.saveMetadata()
+	a.autosave(nil)
+	defer a.autosave(nil)
+	drafting := a.shouldDraft()
+	for {
+		var res api.ChatResponse
+		var err error
+		drafted := false
+		if drafting {
+			drafting = false
+			res, drafted = a.draft(ctx)
+		}
+		if !drafted {
+			res, err = a.runInference(ctx, a.session.Messages)
+			if err != nil {
+				return err
+			}
+		}
+		if !drafted && a.truncated(res) {
+			fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("Warning")), i18n.T("the conversation no longer fits in the context window (%d tokens), compacting", a.lastNumCtx))
+			if err = a.compact(ctx); err != nil {
+				i18n.Printf("Error: %s\n", err.Error())
+			} else if res, err = a.runInference(ctx, a.session.Messages); err != nil {
+				return err
+			}
+		}
+		thinking, answer := extractThinking(res.Message.Content, res.Message.Thinking)
+		res.Message.Content, res.Message.Thinking = answer, ""
+		a.session.Append(res.Message)
+		a.autosave(nil)
+
+		if thinking != "" {
+			a.lastThoughts = thinking
+			if a.showThoughts {
+				fmt.Printf("%s: %s\n", style.Label(style.Dim, i18n.T("Thinking")), style.Color(style.Dim, thinking))
+			}
+		}
+
+		if res.Message.Content != "" {
+			fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("Agent")), a.renderer.Render(res.Message.Content))
+		}
+		if a.speak && a.speaker != nil && len(res.Message.ToolCalls) == 0 {
+			a.speaker.Speak(res.Message.Content)
+		}
+
+		var toolResults []api.Message
+		toolCtx, interrupted, stop := interruptible(ctx)
+		for _, tc := range res.Message.ToolCalls {
+			if interrupted() {
+				toolResults = append(toolResults, api.Message{
+					Role:    "user",
+					Content: fmt.Sprintf("%s was not run, the user interrupted", tc.Function.Name),
+				})
+				continue
+			}
+			argsBuf, err2 := json.Marshal(tc.Function.Arguments)
+			if err2 != nil {
+				stop()
+				return fmt.Errorf("error marshaling json: %v", err2)
+			}
+			toolMsg, err3 := a.executeTool(toolCtx, tc.Function.Index, tc.Function.Name, argsBuf)
+			var panicked *toolPanic
+			var unknown *unknownToolError
+			var invalid *invalidArgumentsError
+			if err3 != nil && interrupted() {
+				toolMsg = fmt.Sprintf("%s was interrupted by the user: %v", tc.Function.Name, err3)
+			} else if errors.As(err3, &panicked) {
+				fmt.Fprintf(os.Stderr, "%s: %s\n%s", style.Label(style.Red, i18n.T("Error")), i18n.T("%s panicked: %v", tc.Function.Name, panicked.value), style.Color(style.Dim, string(panicked.stack)))
+				toolMsg = fmt.Sprintf("%s failed, it panicked: %v", tc.Function.Name, panicked.value)
+			} else if errors.As(err3, &unknown) && a.unknownTools < maxUnknownTools {
+				// let the model correct itself
+				a.unknownTools++
+				toolMsg = a.unknownToolResult(unknown)
+			} else if errors.As(err3, &invalid) && a.repairs < a.maxRepairs {
+				a.repairs++
+				toolMsg = invalid.result()
+			} else if unknown != nil || invalid != nil {
+				// the model did not correct itself, the call is answered so
+				// the conversation can go on in the next turn
+				stop()
+				a.session.Append(append(toolResults, api.Message{
+					Role:    "user",
+					Content: fmt.Sprintf("%s failed: %v", tc.Function.Name, err3),
+				})...)
+				return fmt.Errorf("%w: error executing tool %s: %v", errTurnFailed, tc.Function.Name, err3)
+			} else if err3 != nil {
+				i18n.Printf("Error: %s\n", fmt.Sprintf("%s: %v", tc.Function.Name, err3))
+				toolMsg = fmt.Sprintf("%s failed: %v", tc.Function.Name, err3)
+			}
+
+			toolUserMessage := api.Message{
+				Role:    "user",
+				Content: toolMsg,
+			}
+			toolResults = append(toolResults, toolUserMessage)
+			a.autosave(toolResults)
+		}
+		stop()
+
+		if interrupted() {
+			// let the user redirect the agent before it sees the results
+			redirect, ok := a.getUserMessage(style.Label(style.Blue, i18n.T("Redirect")) + " " + i18n.T("(empty to stop)") + ": ")
+			if !ok || strings.TrimSpace(redirect) == "" {
+				a.session.Append(toolResults...)
+				return nil
+			}
+			toolResults = append(toolResults, api.Message{
+				Role:    "user",
+				Content: a.expandMentions(redirect),
+			})
+		}
+
+		if len(toolResults) == 0 {
+			a.notifyDone(ctx, res.Message.Content)
+			return nil
+		}
+		a.session.Append(toolResults...)
+
+		if outcome := a.session.Outcome; outcome != nil {
+			// the model reported it is done, there is nothing to respond to
+			a.notifyDone(ctx, outcome.Summary)
+			return nil
+		}
+
+		a.toolRounds++
+		if a.maxToolRounds > 0 && a.toolRounds >= a.maxToolRounds {
+			cont, err := a.checkpoint(ctx)
+			if err != nil {
+				i18n.Printf("Error: %s\n", err.Error())
+			}
+			if !cont {
+				return nil
+			}
+		}
+	}
+}
+
+// addUserInput starts a new turn with the user's input.
+func (a *Agent) addUserInput(ctx context.Context, userInput string) {
+	a.retry = retryOptions{}
+	a.toolChoice = toolChoice{}
+	a.toolRounds = 0
+	a.unknownTools = 0
+	a.repairs = 0
+	a.timings = turnTimings{}
+	a.criticRejections = 0
+	a.turnStarted = time.Now()
+	a.session.Outcome = nil
+	a.session.StartTask()
+
+	userMessage := api.Message{
+		Role:    "user",
+		Content: a.expandMentions(userInput),
+	}
+	a.session.Append(userMessage)
+	a.session.Append(a.attachments...)
+	a.attachments = nil
+
+	if a.autoContext && a.index != nil && a.isNewTask() {
+		contextMessage, err := a.gatherContext(ctx, userInput)
+		if err != nil {
+			i18n.Printf("Error: gathering context: %s\n", err.Error())
+		} else {
+			a.session.Append(contextMessage)
+		}
+	}
+	a.turnStart = len(a.session.Messages)
+}
+
+func (a *Agent) executeTool(ctx context.Context, id int, name string, input json.RawMessage) (string, error) {
+	var toolDef tools.Tool
+	var found bool
+	for _, tool := range a.tools {
+		if tool.Definition.Name == name {
+			toolDef = tool
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", &unknownToolError{name: name, qualified: tools.Qualified(a.tools, name)}
+	}
+	if problems := validateArguments(toolDef.Definition.Parameters, input); len(problems) > 0 {
+		return "", &invalidArgumentsError{tool: toolDef, problems: problems}
+	}
+
+	ctx = workspace.NewContext(tools.WithOutput(ctx, os.Stdout), a.workspace)
+	ctx = session.NewContext(ctx, a.session)
+	ctx = tools.WithCapabilities(ctx, a.capabilities)
+	ctx = tools.WithCheckPath(ctx, func(path string) error {
+		if a.policy == nil {
+			return nil
+		}
+		return a.policy.CheckPath(a.workspace, name, path)
+	})
+	ctx = tools.WithApprove(ctx, func(paths []string, diff string) error {
+		return a.approve(name, paths, diff)
+	})
+	if a.dryRun {
+		ctx = tools.WithDryRun(ctx)
+	}
+	run := func(ctx context.Context, call middleware.ToolCall) (res string, err error) {
+		defer recoverTool(&err)
+		res, err = toolDef.Function(ctx, call.Input)
+		a.recordRead(call.Name, call.Input, res)
+		return res, err
+	}
+	return middleware.ChainTool(run, a.toolMiddleware...)(ctx, middleware.ToolCall{
+		Name:    name,
+		Input:   input,
+		Effect:  toolDef.Effect,
+		Message: len(a.session.Messages) - 1,
+	})
+}
+
+func (a *Agent) runInference(ctx context.Context, conversation []api.Message) (api.ChatResponse, error) {
+	var toolsList api.Tools
+	for _, td := range a.tools {
+		toolsList = append(toolsList, api.Tool{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        td.Definition.Name,
+				Description: td.Definition.Description,
+				Parameters:  td.Definition.Parameters,
+			},
+		})
+	}
+
+	conversation = a.withEphemeral(a.withSystemPrompt(ctx, conversation))
+	toolsList, instruction := a.offeredTools(toolsList)
+	if instruction != "" {
+		conversation = append(conversation[:len(conversation):len(conversation)], api.Message{
+			Role:    "user",
+			Content: instruction,
+		})
+	}
+	if !a.toolChoice.turn {
+		a.toolChoice = toolChoice{}
+	}
+	a.lastNumCtx = a.numCtx(ctx, conversation, toolsList)
+
+	var think *bool
+	if a.think {
+		think = &TRUE
+	}
+
+	model := a.toolsLLM
+	if a.retry.model != "" {
+		model = a.retry.model
+	}
+	options := map[string]interface{}{
+		"temperature":   0.0,
+		"repeat_last_n": 2,
+		"num_ctx":       a.lastNumCtx,
+	}
+	if a.retry.temperature != nil {
+		options["temperature"] = *a.retry.temperature
+	}
+
+	return a.chat(ctx, &api.ChatRequest{
+		Model:    model,
+		Messages: conversation,
+		Options:  options,
+		Tools:    toolsList,
+		// streamed, so the spinner can count the tokens
+		Stream: &TRUE,
+		Think:  think,
+	})
+}
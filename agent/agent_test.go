@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/config"
+	"github.com/mschoch/dacs/tools"
+)
+
+// scriptedModel answers with its responses in order, then with done.
+type scriptedModel struct {
+	responses []api.Message
+}
+
+func (m *scriptedModel) Chat(_ context.Context, _ *api.ChatRequest, fn api.ChatResponseFunc) error {
+	msg := api.Message{Role: "assistant", Content: "done"}
+	if len(m.responses) > 0 {
+		msg, m.responses = m.responses[0], m.responses[1:]
+	}
+	return fn(api.ChatResponse{Message: msg, Done: true})
+}
+
+func TestFailedTurnKeepsSession(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		call  api.ToolCall
+		limit int
+	}{
+		{"invalid arguments", api.ToolCall{Function: api.ToolCallFunction{Name: "read_file", Arguments: api.ToolCallFunctionArguments{"path": 1}}}, 2},
+		{"unknown tool", api.ToolCall{Function: api.ToolCallFunction{Name: "no_such_tool"}}, maxUnknownTools},
+	} {
+		cfg := config.Default()
+		cfg.Autosave, cfg.SessionStats, cfg.MaxRepairs = false, false, 2
+		model := &scriptedModel{}
+		for range test.limit + 1 {
+			model.responses = append(model.responses, api.Message{Role: "assistant", ToolCalls: []api.ToolCall{test.call}})
+		}
+		inputs := []string{"read it", "hello"}
+		a := New(model, cfg, func(string) (string, bool) {
+			if len(inputs) == 0 {
+				return "", false
+			}
+			input := inputs[0]
+			inputs = inputs[1:]
+			return input, true
+		}, []tools.Tool{tools.ReadFileDefinition})
+		a.spinner = false
+
+		if err := a.Run(context.Background()); err != nil {
+			t.Fatalf("%s: Run() = %v, want the session to go on", test.name, err)
+		}
+		msgs := a.session.Messages
+		if last := msgs[len(msgs)-1]; last.Role != "assistant" || last.Content != "done" {
+			t.Errorf("%s: last message %+v, want the answer to the second input", test.name, last)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/style"
+)
+
+// approve checks the paths a tool is about to change against the policy,
+// then shows the user the diff and asks whether to apply it. Without a user
+// to ask, such as for workers, the changes are not approved.
+func (a *Agent) approve(tool string, paths []string, diff string) error {
+	if a.policy != nil {
+		for _, path := range paths {
+			if err := a.policy.CheckPath(a.workspace, tool, path); err != nil {
+				return fmt.Errorf("policy violation: %v. Do not try to work around this policy, ask the user if the change is needed", err)
+			}
+		}
+	}
+	fmt.Print(diff)
+	answer, ok := a.getUserMessage(style.Label(style.Blue, i18n.T("Apply?")) + " " + i18n.T("[y/N]") + ": ")
+	if !ok || i18n.Answer(answer) != "y" {
+		return errors.New("the user did not approve the changes")
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Attach adds a document, such as content piped to stdin, to the next user
+// input as a message of its own, so it is kept apart from the prompt.
+func (a *Agent) Attach(name, content string) {
+	a.attachments = append(a.attachments, api.Message{
+		Role:    "user",
+		Content: fmt.Sprintf("The user attached %s:\n```\n%s\n```", name, strings.TrimRight(content, "\n")),
+	})
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/index"
+	"github.com/mschoch/dacs/style"
+)
+
+const (
+	autoContextChunks = 20
+	autoContextFiles  = 5
+)
+
+// UseIndex enables retrieval from the index, when auto-context is on the
+// most relevant files are attached to the first message of a task.
+func (a *Agent) UseIndex(idx *index.Index) {
+	a.index = idx
+}
+
+// isNewTask reports whether the session has no user messages yet, other
+// than the one just added.
+func (a *Agent) isNewTask() bool {
+	n := 0
+	for _, m := range a.session.Messages {
+		if m.Role == "user" {
+			n++
+		}
+	}
+	return n <= 1
+}
+
+// gatherContext builds a message with a map of the repository and the
+// files most relevant to the prompt.
+func (a *Agent) gatherContext(ctx context.Context, prompt string) (api.Message, error) {
+	repoMap, err := index.RepoMap(".")
+	if err != nil {
+		return api.Message{}, err
+	}
+	results, err := a.index.Search(ctx, prompt, autoContextChunks)
+	if err != nil {
+		return api.Message{}, err
+	}
+
+	var files []string
+	seen := map[string]bool{}
+	for _, r := range results {
+		if seen[r.Path] || len(files) == autoContextFiles {
+			continue
+		}
+		if a.policy != nil && a.policy.CheckPath(a.workspace, "read_file", r.Path) != nil {
+			continue
+		}
+		seen[r.Path] = true
+		files = append(files, r.Path)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Context gathered automatically for this task.\n\nRepository map:\n%s", repoMap)
+	for _, path := range files {
+		content, err := a.readFile(path)
+		if err != nil || len(content) > maxMentionBytes {
+			fmt.Fprintf(&sb, "\n\n%s may be relevant, use read_file to see it.", path)
+			continue
+		}
+		fmt.Fprintf(&sb, "\n\nContents of %s:\n```\n%s\n```", path, strings.TrimRight(string(content), "\n"))
+	}
+	fmt.Printf("%s: %s\n", style.Label(style.Green, "auto-context"), strings.Join(files, ", "))
+
+	return api.Message{
+		Role:    "user",
+		Content: sb.String(),
+	}, nil
+}
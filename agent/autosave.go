@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"errors"
+	"os"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/session"
+	"github.com/mschoch/dacs/style"
+)
+
+// startAutosave saves the session of the workspace from now on, after
+// offering to restore the one left behind by a crash, if any.
+func (a *Agent) startAutosave() {
+	dir := a.workspace.Primary().Path
+	path, err := session.AutosavePath(dir)
+	if err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+		return
+	}
+	a.autosavePath = path
+	// only an empty session is replaced, not one imported
+	if len(a.session.Messages) > 1 {
+		return
+	}
+	saved, err := session.LoadAutosave(path)
+	if err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+		return
+	}
+	if saved == nil || len(saved.Session.Messages) <= 1 {
+		return
+	}
+	i18n.Printf("found an unsaved session from %s with %d messages\n", saved.Saved.Format("2006-01-02 15:04"), len(saved.Session.Messages)+len(saved.Pending))
+	answer, ok := a.getUserMessage(style.Label(style.Blue, i18n.T("Restore?")) + " " + i18n.T("[y/N]") + ": ")
+	if !ok || i18n.Answer(answer) != "y" {
+		return
+	}
+	a.session = saved.Restore()
+	a.turnStart = -1
+	i18n.Printf("restored %d messages\n", len(a.session.Messages))
+}
+
+// autosave saves the session, with the results of the tools run so far in
+// the current round, when autosaving.
+func (a *Agent) autosave(pending []api.Message) {
+	if a.autosavePath == "" {
+		return
+	}
+	if err := a.session.Autosave(a.autosavePath, a.workspace.Primary().Path, pending, a.cfg.Encrypt); err != nil {
+		i18n.Printf("Error: autosaving: %s\n", err.Error())
+		a.autosavePath = ""
+	}
+}
+
+// discardAutosave removes the autosaved session once the session ended
+// normally.
+func (a *Agent) discardAutosave() {
+	if a.autosavePath == "" {
+		return
+	}
+	if err := os.Remove(a.autosavePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		i18n.Printf("Error: %s\n", err.Error())
+	}
+}
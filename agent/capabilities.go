@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mschoch/dacs/policy"
+	"github.com/mschoch/dacs/tools"
+)
+
+// UsePolicy denies tool calls on the paths the policy protects, its rules
+// are reported with the capabilities.
+func (a *Agent) UsePolicy(p *policy.Policy) {
+	a.policy = p
+	a.UseToolMiddleware(p.Middleware)
+}
+
+// capabilities describes what the agent can currently do, for the user and
+// the model.
+func (a *Agent) capabilities() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Model: %s", a.toolsLLM)
+	if a.persona != "" {
+		fmt.Fprintf(&sb, " (mode %s)", a.persona)
+	}
+	sb.WriteString("\nWorkspace:\n")
+	for n, r := range a.workspace.Roots {
+		primary := ""
+		if n == 0 {
+			primary = ", primary"
+		}
+		fmt.Fprintf(&sb, "- %s: %s%s\n", r.Name, r.Path, primary)
+	}
+	if a.dryRun {
+		sb.WriteString("Dry run: tools with an effect report what they would do without doing it\n")
+	}
+
+	fmt.Fprintf(&sb, "Tools (%d):\n", len(a.tools))
+	for _, t := range a.tools {
+		effect := ""
+		if t.Effect != tools.EffectNone {
+			effect = fmt.Sprintf(" [%s]", t.Effect)
+		}
+		fmt.Fprintf(&sb, "- %s%s: %s\n", t.Definition.Name, effect, firstSentence(t.Definition.Description))
+	}
+	var unavailable []string
+	for _, t := range a.allTools {
+		if a.disabledTools[t.Definition.Name] || !a.personaTool(t.Definition.Name) {
+			unavailable = append(unavailable, t.Definition.Name)
+		}
+	}
+	if len(unavailable) > 0 {
+		sort.Strings(unavailable)
+		fmt.Fprintf(&sb, "Tools not offered: %s\n", strings.Join(unavailable, ", "))
+	}
+
+	if a.policy != nil {
+		sb.WriteString("Policy:\n")
+		for _, rule := range a.policy.Describe() {
+			fmt.Fprintf(&sb, "- %s\n", rule)
+		}
+	}
+	return sb.String()
+}
+
+// firstSentence returns the first sentence of a tool description, without
+// its period.
+func firstSentence(s string) string {
+	s, _, _ = strings.Cut(s, "\n")
+	for i := 0; i < len(s); i++ {
+		if strings.HasPrefix(s[i:], ". ") && !strings.HasSuffix(s[:i], "e.g") && !strings.HasSuffix(s[:i], "i.e") {
+			return s[:i]
+		}
+	}
+	return strings.TrimSuffix(s, ".")
+}
+
+func (a *Agent) cmdCapabilities(context.Context, string) error {
+	fmt.Print(a.capabilities())
+	return nil
+}
@@ -0,0 +1,280 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mschoch/dacs/clipboard"
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/session"
+	"github.com/mschoch/dacs/style"
+)
+
+type command struct {
+	usage       string
+	description string
+	run         func(a *Agent, ctx context.Context, args string) error
+	// pathArg enables completion of file paths for the argument
+	pathArg bool
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help": {
+			usage:       "/help",
+			description: "list the available commands",
+			run:         (*Agent).cmdHelp,
+		},
+		"stats": {
+			usage:       "/stats",
+			description: "show per-tool call counts, failures and latency",
+			run:         (*Agent).cmdStats,
+		},
+		"thoughts": {
+			usage:       "/thoughts [last]",
+			description: "toggle display of the model's reasoning, or show the last reasoning",
+			run:         (*Agent).cmdThoughts,
+		},
+		"timings": {
+			usage:       "/timings",
+			description: "toggle showing where the time of each turn went",
+			run:         (*Agent).cmdTimings,
+		},
+		"copy": {
+			usage:       "/copy [N]",
+			description: "copy code block N (default the last) to the clipboard",
+			run:         (*Agent).cmdCopy,
+		},
+		"pin": {
+			usage:       "/pin [path]",
+			description: "keep a file's current contents in the context, or list pinned files",
+			run:         (*Agent).cmdPin,
+			pathArg:     true,
+		},
+		"unpin": {
+			usage:       "/unpin [path]",
+			description: "stop pinning a file, or all files",
+			run:         (*Agent).cmdUnpin,
+			pathArg:     true,
+		},
+		"ask": {
+			usage:       "/ask QUESTION",
+			description: "answer without using any tools",
+			run:         (*Agent).cmdAsk,
+		},
+		"use": {
+			usage:       "/use TOOL PROMPT",
+			description: "make the model start by calling the tool",
+			run:         (*Agent).cmdUse,
+		},
+		"tools": {
+			usage:       "/tools [enable|disable NAME | reload]",
+			description: "list the tools, turn one on or off, or reload the custom tools from the config",
+			run:         (*Agent).cmdTools,
+		},
+		"capabilities": {
+			usage:       "/capabilities",
+			description: "show the model, workspace, tools and policy in effect",
+			run:         (*Agent).cmdCapabilities,
+		},
+		"critic": {
+			usage:       "/critic",
+			description: "toggle having a reviewer check changes before they are applied",
+			run:         (*Agent).cmdCritic,
+		},
+		"orchestrate": {
+			usage:       "/orchestrate TASK",
+			description: "plan the task as subtasks, have worker agents do them, then check the results",
+			run:         (*Agent).cmdOrchestrate,
+		},
+		"mode": {
+			usage:       "/mode [NAME]",
+			description: "switch to a persona, or default, or list them",
+			run:         (*Agent).cmdMode,
+		},
+		"models": {
+			usage:       "/models",
+			description: "list the models available, with their size and family",
+			run:         (*Agent).cmdModels,
+		},
+		"pull": {
+			usage:       "/pull MODEL",
+			description: "download a model",
+			run:         (*Agent).cmdPull,
+		},
+		"retry": {
+			usage:       "/retry [temperature=N] [model=NAME]",
+			description: "drop the last response and run inference again",
+			run:         (*Agent).cmdRetry,
+		},
+		"todos": {
+			usage:       "/todos",
+			description: "show the model's plan for the current task",
+			run:         (*Agent).cmdTodos,
+		},
+		"history": {
+			usage:       "/history",
+			description: "list the messages in the conversation",
+			run:         (*Agent).cmdHistory,
+		},
+		"edit": {
+			usage:       "/edit N [text]",
+			description: "replace the content of message N, in $EDITOR when no text is given",
+			run:         (*Agent).cmdEdit,
+		},
+		"drop": {
+			usage:       "/drop N[-M]",
+			description: "delete message N, or messages N through M",
+			run:         (*Agent).cmdDrop,
+		},
+		"compact": {
+			usage:       "/compact",
+			description: "replace older messages with a summary to free up context",
+			run:         (*Agent).cmdCompact,
+		},
+		"voice": {
+			usage:       "/voice",
+			description: "speak your input, transcribed with whisper",
+			run:         (*Agent).cmdVoice,
+		},
+		"speak": {
+			usage:       "/speak",
+			description: "toggle reading final answers aloud",
+			run:         (*Agent).cmdSpeak,
+		},
+		"export": {
+			usage:       "/export [path]",
+			description: "write the session, including tool statistics, as JSON",
+			run:         (*Agent).cmdExport,
+		},
+		"import": {
+			usage:       "/import PATH",
+			description: "continue a conversation exported by dacs or from a Claude Code, Aider or markdown transcript",
+			run:         (*Agent).cmdImport,
+			pathArg:     true,
+		},
+	}
+}
+
+// isCommand reports whether the user input is a slash command rather than
+// a message for the model, which can start with a path such as /etc/hosts.
+func isCommand(input string) bool {
+	name, ok := strings.CutPrefix(input, "/")
+	if !ok {
+		return false
+	}
+	name, _, _ = strings.Cut(name, " ")
+	_, ok = commands[name]
+	return ok
+}
+
+func (a *Agent) runCommand(ctx context.Context, input string) error {
+	name, args, _ := strings.Cut(strings.TrimPrefix(input, "/"), " ")
+	cmd, ok := commands[name]
+	if !ok {
+		return i18n.Errorf("unknown command /%s, try /help", name)
+	}
+	return cmd.run(a, ctx, strings.TrimSpace(args))
+}
+
+func (a *Agent) cmdHelp(context.Context, string) error {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-24s %s\n", commands[name].usage, i18n.T(commands[name].description))
+	}
+	return nil
+}
+
+func (a *Agent) cmdStats(context.Context, string) error {
+	return a.session.WriteToolStats(os.Stdout)
+}
+
+func (a *Agent) cmdExport(_ context.Context, path string) error {
+	if path == "" {
+		path = fmt.Sprintf("dacs-session-%s.json", time.Now().Format("20060102-150405"))
+	}
+	err := a.session.Export(path, a.cfg.Encrypt)
+	if err != nil {
+		return err
+	}
+	i18n.Printf("session exported to %s\n", path)
+	return nil
+}
+
+func (a *Agent) cmdImport(_ context.Context, path string) error {
+	if path == "" {
+		return fmt.Errorf("usage: /import PATH")
+	}
+	return a.Import(path)
+}
+
+// Import appends the messages of the conversation in the file to the
+// session, see session.Import for the formats.
+func (a *Agent) Import(path string) error {
+	msgs, format, err := session.Import(path)
+	if err != nil {
+		return err
+	}
+	a.session.Append(msgs...)
+	i18n.Printf("imported %d messages from the %s transcript %s\n", len(msgs), format, path)
+	return nil
+}
+
+func (a *Agent) cmdThoughts(_ context.Context, args string) error {
+	if args == "last" {
+		if a.lastThoughts == "" {
+			fmt.Println(i18n.T("no thoughts yet"))
+			return nil
+		}
+		fmt.Println(style.Color(style.Dim, a.lastThoughts))
+		return nil
+	}
+	a.showThoughts = !a.showThoughts
+	if a.showThoughts {
+		fmt.Println(i18n.T("showing thoughts"))
+	} else {
+		fmt.Println(i18n.T("hiding thoughts"))
+	}
+	return nil
+}
+
+func (a *Agent) cmdCompact(ctx context.Context, _ string) error {
+	return a.compact(ctx)
+}
+
+func (a *Agent) cmdCopy(_ context.Context, args string) error {
+	blocks := a.renderer.CodeBlocks
+	if len(blocks) == 0 {
+		return fmt.Errorf("no code blocks yet")
+	}
+	n := len(blocks)
+	if args != "" {
+		var err error
+		n, err = strconv.Atoi(args)
+		if err != nil || n < 1 || n > len(blocks) {
+			return fmt.Errorf("invalid code block %q, expected 1-%d", args, len(blocks))
+		}
+	}
+	err := clipboard.Copy(os.Stdout, blocks[n-1])
+	if err != nil {
+		return err
+	}
+	i18n.Printf("copied code block %d\n", n)
+	return nil
+}
+
+func (a *Agent) cmdTodos(context.Context, string) error {
+	fmt.Println(session.FormatTodos(a.session.Todos))
+	return nil
+}
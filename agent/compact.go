@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/style"
+)
+
+const (
+	// messages at the end of the conversation kept verbatim by compaction
+	compactKeep = 6
+
+	compactPrompt = "Summarize the conversation so far so that it can replace it. Keep the user's goals, decisions made, files read or changed and their relevant content, tool results still needed and any open questions. Refer to tool results labeled with an ID, such as [result R12], by that ID rather than repeating them, they can be fetched again with recall_result. Be concise and factual, do not address the user."
+)
+
+// truncated reports whether the last response was generated from a prompt
+// that did not fit in the context window, which Ollama silently truncates.
+func (a *Agent) truncated(res api.ChatResponse) bool {
+	if a.lastNumCtx == 0 || res.PromptEvalCount == 0 {
+		return false
+	}
+	return res.PromptEvalCount+res.EvalCount >= a.lastNumCtx
+}
+
+// compact replaces all but the system prompt and the most recent messages
+// with a summary written by the model.
+func (a *Agent) compact(ctx context.Context) error {
+	msgs := a.session.Messages
+	start := 0
+	if len(msgs) > 0 && msgs[0].Role == "system" {
+		start = 1
+	}
+	end := compactCut(msgs, len(msgs)-compactKeep)
+	if end-start < 2 {
+		return fmt.Errorf("nothing to compact")
+	}
+
+	var transcript strings.Builder
+	for _, m := range msgs[start:end] {
+		fmt.Fprintf(&transcript, "%s: %s\n", m.Role, m.Content)
+		for _, tc := range m.ToolCalls {
+			fmt.Fprintf(&transcript, "%s called %s(%s)\n", m.Role, tc.Function.Name, tc.Function.Arguments.String())
+		}
+	}
+
+	summary, err := a.chat(ctx, &api.ChatRequest{
+		Model: a.toolsLLM,
+		Messages: []api.Message{
+			{Role: "system", Content: compactPrompt},
+			{Role: "user", Content: transcript.String()},
+		},
+		Options: map[string]interface{}{
+			"temperature": 0.0,
+			"num_ctx":     a.numCtx(ctx, msgs, nil),
+		},
+		Stream: &FALSE,
+	})
+	if err != nil {
+		return fmt.Errorf("error compacting conversation: %v", err)
+	}
+	_, content := extractThinking(summary.Message.Content, summary.Message.Thinking)
+
+	a.turnStart = -1
+	a.session.Replace(start, end, api.Message{
+		Role:    "user",
+		Content: "Summary of the earlier conversation:\n" + content,
+	})
+	fmt.Println(style.Color(style.Yellow, fmt.Sprintf("compacted %d messages", end-start)))
+	return nil
+}
+
+// compactCut moves the cut point back to the start of a user turn, so tool
+// results are never separated from the call that produced them. Within a
+// single turn running since before the cut, it is moved back to the start
+// of a round of tool calls instead, 0 when there is none.
+func compactCut(msgs []api.Message, cut int) int {
+	cut = min(cut, len(msgs)-1)
+	for i := cut; i > 1; i-- {
+		prev := msgs[i-1]
+		if prev.Role == "assistant" && len(prev.ToolCalls) == 0 {
+			return i
+		}
+	}
+	// tool results follow the assistant message calling the tools, and
+	// are user messages themselves
+	for i := cut; i > 1; i-- {
+		if msgs[i].Role == "assistant" {
+			return i
+		}
+	}
+	return 0
+}
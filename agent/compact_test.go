@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestCompactCut(t *testing.T) {
+	call := api.Message{Role: "assistant", ToolCalls: []api.ToolCall{{Function: api.ToolCallFunction{Name: "read_file"}}}}
+	result := api.Message{Role: "user", Content: "result"}
+
+	// one long turn, calling a tool round after round
+	long := []api.Message{{Role: "system"}, {Role: "user", Content: "prompt"}}
+	for range 10 {
+		long = append(long, call, result)
+	}
+
+	// an earlier turn, then a turn still calling tools
+	turns := []api.Message{{Role: "system"}, {Role: "user"}, call, result, {Role: "assistant"}, {Role: "user"}}
+	for range 5 {
+		turns = append(turns, call, result)
+	}
+
+	for _, test := range []struct {
+		name string
+		msgs []api.Message
+		cut  int
+		want int
+	}{
+		{"long turn", long, len(long) - compactKeep, len(long) - compactKeep},
+		{"long turn, cut at a result", long, len(long) - compactKeep + 1, len(long) - compactKeep},
+		{"turn boundary", turns, len(turns) - compactKeep, 5},
+		{"only the prompt", long[:3], 2, 2},
+	} {
+		got := compactCut(test.msgs, test.cut)
+		if got != test.want {
+			t.Errorf("%s: compactCut(%d) = %d, want %d", test.name, test.cut, got, test.want)
+		}
+		if got > 0 && test.msgs[got].Role == "user" && len(test.msgs[got-1].ToolCalls) > 0 {
+			t.Errorf("%s: cut at %d separates a tool result from its call", test.name, got)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/middleware"
+	"github.com/mschoch/dacs/style"
+	"github.com/mschoch/dacs/tools"
+)
+
+const (
+	criticPrompt = "You are a code reviewer checking changes another assistant proposes before they are applied. Given the conversation and the proposed change, approve it unless it has a real problem: it does not do what the user asked, introduces a bug, breaks the surrounding code, removes something it should not, or is clearly incomplete. Do not object to style preferences. When you object, say concretely what is wrong and how to fix it."
+
+	// maxCriticRejections per turn, after which changes are applied anyway
+	// so the critic cannot stall a turn
+	maxCriticRejections = 3
+)
+
+var criticFormat = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "approve": {"type": "boolean"},
+    "objections": {"type": "string"}
+  },
+  "required": ["approve", "objections"]
+}`)
+
+// critique is a tool middleware having a second model review the changes
+// proposed by tools that write or delete, as their dry run reports them,
+// and sending them back to the model with the objections, if any, rather
+// than applying them. It runs outside of the policy, calls the policy
+// denies are passed on without a dry run.
+func (a *Agent) critique(next middleware.ToolFunc) middleware.ToolFunc {
+	return func(ctx context.Context, call middleware.ToolCall) (string, error) {
+		if !a.critic || a.dryRun || (call.Effect != tools.EffectWrite && call.Effect != tools.EffectDelete) ||
+			a.criticRejections >= maxCriticRejections {
+			return next(ctx, call)
+		}
+		var tool tools.Tool
+		for _, t := range a.tools {
+			if t.Definition.Name == call.Name {
+				tool = t
+			}
+		}
+		if tool.Function == nil {
+			return next(ctx, call)
+		}
+		if a.policy != nil && a.policy.Check(a.workspace, call.Name, call.Input) != nil {
+			// the policy, further in, denies the call, even its dry run
+			// must not read the protected paths
+			return next(ctx, call)
+		}
+		proposed, err := func() (res string, err error) {
+			defer recoverTool(&err)
+			return tool.Function(tools.WithDryRun(ctx), call.Input)
+		}()
+		if err != nil {
+			// the call is run all the same, failing again, and its error is
+			// the result the model gets
+			return next(ctx, call)
+		}
+
+		objections, err := a.review(ctx, call.Name, proposed)
+		if err != nil {
+			i18n.Printf("Error: reviewing the change: %s\n", err.Error())
+			return next(ctx, call)
+		}
+		if objections == "" {
+			return next(ctx, call)
+		}
+		a.criticRejections++
+		fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("critic")), objections)
+		return fmt.Sprintf("The change was not applied, a reviewer objected to it:\n%s\nAddress the objections and propose the change again, or explain why it is right.", objections), nil
+	}
+}
+
+// review asks the critic model about the proposed change, it returns the
+// objections, empty when the change is approved.
+func (a *Agent) review(ctx context.Context, tool, proposed string) (string, error) {
+	conversation := []api.Message{{Role: "system", Content: criticPrompt}}
+	for _, m := range a.session.Messages {
+		if m.Role != "system" {
+			conversation = append(conversation, m)
+		}
+	}
+	conversation = append(conversation, api.Message{
+		Role:    "user",
+		Content: fmt.Sprintf("Review this change proposed with %s:\n%s", tool, proposed),
+	})
+	model := a.criticLLM
+	if model == "" {
+		model = a.toolsLLM
+	}
+	res, err := a.chat(ctx, &api.ChatRequest{
+		Model:    model,
+		Messages: conversation,
+		Format:   criticFormat,
+		Options: map[string]interface{}{
+			"temperature": 0.0,
+			"num_ctx":     a.numCtx(ctx, conversation, nil),
+		},
+		Stream: &FALSE,
+	})
+	if err != nil {
+		return "", err
+	}
+	var verdict struct {
+		Approve    bool   `json:"approve"`
+		Objections string `json:"objections"`
+	}
+	_, content := extractThinking(res.Message.Content, res.Message.Thinking)
+	if err := json.Unmarshal([]byte(content), &verdict); err != nil {
+		return "", fmt.Errorf("invalid verdict: %s", content)
+	}
+	if verdict.Approve {
+		return "", nil
+	}
+	if verdict.Objections == "" {
+		verdict.Objections = "no reason given"
+	}
+	return verdict.Objections, nil
+}
+
+func (a *Agent) cmdCritic(context.Context, string) error {
+	a.critic = !a.critic
+	if a.critic {
+		fmt.Println(i18n.T("changes are reviewed before they are applied"))
+	} else {
+		fmt.Println(i18n.T("changes are applied without review"))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mschoch/dacs/middleware"
+	"github.com/mschoch/dacs/tools"
+)
+
+// minDedupSize is the size of a file read below which repeating it costs
+// less than the note replacing it
+const minDedupSize = 200
+
+// readDigest identifies a file read, the path and the content returned.
+type readDigest [sha256.Size]byte
+
+// recordRead remembers the digest of a file read's result, as returned by
+// the tool, before any middleware changes it.
+func (a *Agent) recordRead(name string, input json.RawMessage, res string) {
+	if name != tools.ReadFileDefinition.Definition.Name || len(res) < minDedupSize {
+		return
+	}
+	var readFileInput tools.ReadFileInput
+	_ = json.Unmarshal(input, &readFileInput)
+	a.lastRead = sha256.Sum256([]byte(readFileInput.Path + "\x00" + res))
+}
+
+// dedupReads is a tool middleware replacing the result of a file read that
+// is already in the conversation, the same file unchanged, with a note
+// pointing to it.
+func (a *Agent) dedupReads(next middleware.ToolFunc) middleware.ToolFunc {
+	return func(ctx context.Context, call middleware.ToolCall) (string, error) {
+		// calls stopped by other middleware do not reach recordRead
+		a.lastRead = readDigest{}
+		a.deduped = false
+		res, err := next(ctx, call)
+		digest := a.lastRead
+		if err != nil || digest == (readDigest{}) {
+			return res, err
+		}
+		if a.reads == nil {
+			a.reads = map[readDigest]string{}
+		}
+		// the earlier result is found as it was added, after the other
+		// middleware and labelResults' ID, as long as it was not
+		// compacted or dropped
+		if earlier, ok := a.reads[digest]; ok {
+			for i := len(a.session.Messages) - 1; i >= 0; i-- {
+				if strings.HasSuffix(a.session.Messages[i].Content, earlier) {
+					var input tools.ReadFileInput
+					_ = json.Unmarshal(call.Input, &input)
+					a.deduped = true
+					return fmt.Sprintf("%s has not changed since it was read, its content is already in the conversation at message %d.", input.Path, i), nil
+				}
+			}
+		}
+		a.reads[digest] = res
+		return res, nil
+	}
+}
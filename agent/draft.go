@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/tools"
+)
+
+const (
+	draftPrompt = "You are the fast first responder of a coding assistant. If the user's last message is small talk or a simple question you can answer fully and correctly from the conversation alone, answer it. If answering it needs tools, reading or changing files, running commands or reasoning about code, reply with only the word " + escalate + "."
+	escalate    = "ESCALATE"
+)
+
+// shouldDraft reports whether the turn may be answered by the draft model,
+// only its first inference, and not when a tool or model was asked for or
+// the outcome must be reported with the finish tool.
+func (a *Agent) shouldDraft() bool {
+	if a.draftLLM == "" || a.toolRounds > 0 || a.toolChoice.choice != "" || a.retry.skipDraft {
+		return false
+	}
+	return !slices.ContainsFunc(a.tools, func(t tools.Tool) bool {
+		return t.Definition.Name == tools.FinishDefinition.Definition.Name
+	})
+}
+
+// draft asks the draft model to answer a trivial turn, it returns false
+// when the turn needs the tools model.
+func (a *Agent) draft(ctx context.Context) (api.ChatResponse, bool) {
+	conversation := []api.Message{{Role: "system", Content: draftPrompt}}
+	for _, m := range a.session.Messages {
+		if m.Role != "system" {
+			conversation = append(conversation, m)
+		}
+	}
+	res, err := a.chat(ctx, &api.ChatRequest{
+		Model:    a.draftLLM,
+		Messages: conversation,
+		Options: map[string]interface{}{
+			"temperature": 0.0,
+			"num_ctx":     a.numCtx(ctx, conversation, nil),
+		},
+		Stream: &FALSE,
+	})
+	if err != nil {
+		return res, false
+	}
+	_, answer := extractThinking(res.Message.Content, res.Message.Thinking)
+	if answer == "" || strings.Contains(answer, escalate) || len(res.Message.ToolCalls) > 0 {
+		return res, false
+	}
+	return res, true
+}
@@ -0,0 +1,30 @@
+package agent
+
+import "github.com/ollama/ollama/api"
+
+// withEphemeral returns the conversation with the messages that are
+// rebuilt for every inference, and never stored in the session, inserted
+// after the system prompt.
+func (a *Agent) withEphemeral(conversation []api.Message) []api.Message {
+	var ephemeral []api.Message
+	for _, build := range []func() (api.Message, bool){
+		a.pinnedMessage,
+		a.notesMessage,
+	} {
+		if msg, ok := build(); ok {
+			ephemeral = append(ephemeral, msg)
+		}
+	}
+	if len(ephemeral) == 0 {
+		return conversation
+	}
+
+	at := 0
+	if len(conversation) > 0 && conversation[0].Role == "system" {
+		at = 1
+	}
+	rv := make([]api.Message, 0, len(conversation)+len(ephemeral))
+	rv = append(rv, conversation[:at]...)
+	rv = append(rv, ephemeral...)
+	return append(rv, conversation[at:]...)
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/format"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/provider"
+	"github.com/mschoch/dacs/style"
+)
+
+// maxCPUShare is the share of a model offloaded to the CPU above which
+// responses get painfully slow
+const maxCPUShare = 0.1
+
+// checkFit loads the model and warns when it does not fit in memory, or
+// runs largely on the CPU, suggesting the available models that fit.
+func (a *Agent) checkFit(ctx context.Context, mm provider.ModelManager) {
+	list, err := mm.List(ctx)
+	if err != nil {
+		return
+	}
+	var fileSize int64
+	for _, m := range list.Models {
+		if provider.SameModel(m.Name, a.toolsLLM) {
+			fileSize = m.Size
+		}
+	}
+	if a.localBackend && fileSize > 0 {
+		if mem := systemMemory(); mem > 0 && fileSize > mem {
+			fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("Warning")),
+				i18n.T("%s needs at least %s of memory, the system has %s, it will not fit", a.toolsLLM, format.HumanBytes(fileSize), format.HumanBytes(mem)))
+			printFitting(list.Models, mem*8/10)
+			return
+		}
+	}
+
+	rm, ok := a.client.(provider.RunningModels)
+	if !ok {
+		return
+	}
+	// a request without messages only loads the model, which the first
+	// request would have to wait for anyway
+	err = a.client.Chat(ctx, &api.ChatRequest{Model: a.toolsLLM, Stream: &FALSE}, func(api.ChatResponse) error { return nil })
+	if err != nil {
+		return
+	}
+	running, err := rm.ListRunning(ctx)
+	if err != nil {
+		return
+	}
+	for _, m := range running.Models {
+		if !provider.SameModel(m.Name, a.toolsLLM) || m.Size == 0 {
+			continue
+		}
+		cpuShare := 1 - float64(m.SizeVRAM)/float64(m.Size)
+		if cpuShare < maxCPUShare {
+			return
+		}
+		fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("Warning")),
+			i18n.T("%.0f%% of %s (%s) is on the CPU, only %s fits in VRAM, expect slow responses", cpuShare*100, a.toolsLLM, format.HumanBytes(m.Size), format.HumanBytes(m.SizeVRAM)))
+		if m.SizeVRAM > 0 {
+			// the loaded size includes the context, which a smaller model
+			// needs too
+			printFitting(list.Models, m.SizeVRAM-max(m.Size-fileSize, 0))
+		}
+		return
+	}
+}
+
+// printFitting suggests the models no larger than budget, and what else
+// to try.
+func printFitting(models []api.ListModelResponse, budget int64) {
+	var fitting []api.ListModelResponse
+	for _, m := range models {
+		// embedding models cannot chat
+		if m.Size <= budget && !strings.Contains(m.Name, "embed") {
+			fitting = append(fitting, m)
+		}
+	}
+	sort.Slice(fitting, func(i, j int) bool { return fitting[i].Size > fitting[j].Size })
+	if len(fitting) > 3 {
+		fitting = fitting[:3]
+	}
+	var names []string
+	for _, m := range fitting {
+		names = append(names, fmt.Sprintf("%s (%s)", m.Name, format.HumanBytes(m.Size)))
+	}
+	if len(names) > 0 {
+		fmt.Printf("  models that fit: %s\n", strings.Join(names, ", "))
+	}
+	fmt.Printf("  try a model under %s: a smaller quantization (e.g. q4_K_M rather than q8_0), fewer parameters, or a smaller num_ctx\n",
+		format.HumanBytes(max(budget, 0)))
+}
+
+// isLocalHost reports whether the API URL is on this machine, whose memory
+// then bounds the models it can run.
+func isLocalHost(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// systemMemory returns the total memory of the machine, 0 if unknown.
+func systemMemory() int64 {
+	switch runtime.GOOS {
+	case "linux":
+		f, err := os.Open("/proc/meminfo")
+		if err != nil {
+			return 0
+		}
+		defer f.Close()
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			// MemTotal:       32795416 kB
+			fields := strings.Fields(s.Text())
+			if len(fields) >= 2 && fields[0] == "MemTotal:" {
+				kb, _ := strconv.ParseInt(fields[1], 10, 64)
+				return kb * 1024
+			}
+		}
+	case "darwin":
+		out, err := exec.Command("sysctl", "-n", "hw.memsize").Output()
+		if err != nil {
+			return 0
+		}
+		n, _ := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/mschoch/dacs/i18n"
+)
+
+func (a *Agent) cmdHistory(context.Context, string) error {
+	for i, m := range a.session.Messages {
+		summary, _, _ := strings.Cut(strings.TrimSpace(m.Content), "\n")
+		if len(summary) > 80 {
+			summary = summary[:77] + "..."
+		}
+		for _, tc := range m.ToolCalls {
+			summary = strings.TrimSpace(summary + " " + tc.Function.Name + "(...)")
+		}
+		fmt.Printf("%4d %-9s %s\n", i, m.Role, summary)
+	}
+	return nil
+}
+
+// cmdDrop removes message N, or messages N through M, from the history.
+func (a *Agent) cmdDrop(_ context.Context, args string) error {
+	first, last, err := a.messageRange(args)
+	if err != nil {
+		return err
+	}
+	a.session.Replace(first, last+1)
+	a.turnStart = -1
+	i18n.Printf("dropped %d messages\n", last-first+1)
+	return nil
+}
+
+// cmdEdit replaces the content of message N, with the rest of the
+// arguments or, when there are none, with the result of editing it in
+// $EDITOR.
+func (a *Agent) cmdEdit(_ context.Context, args string) error {
+	arg, content, _ := strings.Cut(args, " ")
+	n, _, err := a.messageRange(arg)
+	if err != nil {
+		return err
+	}
+	if content == "" {
+		content, err = editInEditor(a.session.Messages[n].Content)
+		if err != nil {
+			return err
+		}
+	}
+	a.session.Messages[n].Content = content
+	i18n.Printf("edited message %d\n", n)
+	return nil
+}
+
+func (a *Agent) messageRange(args string) (int, int, error) {
+	if args == "" {
+		return 0, 0, fmt.Errorf("missing message number, see /history")
+	}
+	firstArg, lastArg, isRange := strings.Cut(args, "-")
+	first, err := strconv.Atoi(strings.TrimSpace(firstArg))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid message number %q", firstArg)
+	}
+	last := first
+	if isRange {
+		last, err = strconv.Atoi(strings.TrimSpace(lastArg))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid message number %q", lastArg)
+		}
+	}
+	if first < 0 || last < first || last >= len(a.session.Messages) {
+		return 0, 0, fmt.Errorf("no such messages %q, see /history", args)
+	}
+	return first, last, nil
+}
+
+func editInEditor(content string) (string, error) {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	f, err := os.CreateTemp("", "dacs-message-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(content)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", f.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("error running %s: %w", editor, err)
+	}
+	buf, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(buf), "\n"), nil
+}
@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/style"
+)
+
+// interruptible returns a context that is canceled when the user presses
+// ctrl-c, outside of the line editor which handles it itself, and a func
+// reporting whether that happened. stop must be called when done.
+func interruptible(ctx context.Context) (_ context.Context, interrupted func() bool, stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	var pressed atomic.Bool
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-sigs:
+			pressed.Store(true)
+			fmt.Printf("\n%s: %s\n", style.Label(style.Yellow, i18n.T("Interrupted")), i18n.T("stopping after the running tool"))
+			cancel()
+		case <-done:
+		}
+	}()
+	return ctx, pressed.Load, func() {
+		signal.Stop(sigs)
+		close(done)
+		cancel()
+	}
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// languagePack holds conventions for a language or framework, added to the
+// system prompt when one of its marker files is in the workspace root, and
+// when set, mentions one of contains.
+type languagePack struct {
+	name     string
+	markers  []string
+	contains []string
+	prompt   string
+}
+
+var languagePacks = []languagePack{
+	{
+		name:    "Go",
+		markers: []string{"go.mod"},
+		prompt:  "Format with gofmt and keep imports grouped standard library first. Handle every error, wrapping it with context using fmt.Errorf and %w, rather than panicking. Prefer small interfaces defined where they are used, return concrete types, and pass context.Context as the first parameter. Keep exported identifiers documented with comments starting with their name. Run go build ./..., go vet ./... and go test ./... to check changes.",
+	},
+	{
+		name:    "Rust",
+		markers: []string{"Cargo.toml"},
+		prompt:  "Format with rustfmt and keep cargo clippy clean. Propagate errors with ? and Result rather than unwrap or expect outside of tests. Prefer borrowing to cloning, iterators to index loops, and enums with match to flags. Run cargo build and cargo test to check changes.",
+	},
+	{
+		name:    "TypeScript",
+		markers: []string{"tsconfig.json"},
+		prompt:  "Keep the code strictly typed, avoid any and non-null assertions, prefer type narrowing and unions. Use const and let, never var, async/await over raw promise chains, and the module style already used by the project. Check changes with the project's type check, lint and test scripts from package.json.",
+	},
+	{
+		name:    "JavaScript",
+		markers: []string{"package.json"},
+		prompt:  "Follow the project's existing module style (ESM or CommonJS) and formatter. Use const and let, never var, strict equality, and async/await over callbacks. Do not add dependencies without asking. Check changes with the lint and test scripts from package.json, using the package manager whose lock file is present.",
+	},
+	{
+		name:     "React",
+		markers:  []string{"package.json"},
+		contains: []string{`"react"`},
+		prompt:   "Write function components with hooks, keep them small and pure, and follow the rules of hooks. Derive values during rendering rather than syncing them with effects, and give list items stable keys.",
+	},
+	{
+		name:    "Python",
+		markers: []string{"pyproject.toml", "setup.py", "requirements.txt"},
+		prompt:  "Follow PEP 8 and the project's formatter and linter. Add type hints to new functions, prefer pathlib, f-strings and context managers, and catch specific exceptions only. Check changes with the project's test runner, usually pytest.",
+	},
+}
+
+// languageConventions returns the conventions of the languages detected in
+// the primary root, detecting them on first use.
+func (a *Agent) languageConventions() string {
+	if !a.languagePacks {
+		return ""
+	}
+	if a.conventions != nil {
+		return *a.conventions
+	}
+	root := a.workspace.Primary()
+	var parts []string
+	for _, pack := range languagePacks {
+		for _, marker := range pack.markers {
+			content, err := root.FS.ReadFile(filepath.Join(root.Path, marker))
+			if err != nil {
+				continue
+			}
+			if len(pack.contains) > 0 && !containsAny(content, pack.contains) {
+				continue
+			}
+			parts = append(parts, pack.name+" conventions: "+pack.prompt)
+			break
+		}
+	}
+	conventions := strings.Join(parts, "\n\n")
+	a.conventions = &conventions
+	return conventions
+}
+
+func containsAny(content []byte, subs []string) bool {
+	for _, sub := range subs {
+		if bytes.Contains(content, []byte(sub)) {
+			return true
+		}
+	}
+	return false
+}
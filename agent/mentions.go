@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// files larger than this are mentioned by name only, the model can still
+// read them with read_file
+const maxMentionBytes = 64 * 1024
+
+var mentionRe = regexp.MustCompile(`(?:^|\s)@(\S+)`)
+
+// expandMentions attaches the contents of the files referenced with @path
+// in the user's input to the message. The paths are resolved in the
+// workspace, as the tools' are.
+func (a *Agent) expandMentions(input string) string {
+	var attachments strings.Builder
+	seen := map[string]bool{}
+	for _, m := range mentionRe.FindAllStringSubmatch(input, -1) {
+		path, content := a.mentionedFile(m[1])
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		if len(content) > maxMentionBytes {
+			fmt.Fprintf(&attachments, "\n\n%s is %d bytes, too large to attach, use read_file for the parts you need.", path, len(content))
+			continue
+		}
+		fmt.Fprintf(&attachments, "\n\nContents of %s:\n```\n%s\n```", path, strings.TrimRight(string(content), "\n"))
+	}
+	return input + attachments.String()
+}
+
+// mentionedFile resolves a mention to a file and reads it, ignoring
+// trailing punctuation such as "@main.go," in a sentence.
+func (a *Agent) mentionedFile(mention string) (string, []byte) {
+	for path := mention; path != ""; path = path[:len(path)-1] {
+		if content, err := a.readFile(path); err == nil {
+			return path, content
+		}
+		if !strings.ContainsAny(path[len(path)-1:], ".,;:!?)'\"") {
+			break
+		}
+	}
+	return "", nil
+}
+
+// readFile reads the file at the path, relative to the workspace or
+// prefixed with a root name, through the root's file system.
+func (a *Agent) readFile(path string) ([]byte, error) {
+	root, abs, err := a.workspace.Resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return root.FS.ReadFile(abs)
+}
+
+// Complete is a lineedit.CompleteFunc for slash commands and @path
+// mentions, paths are completed in the workspace.
+func (a *Agent) Complete(line []rune, pos int) ([]string, int) {
+	start := pos
+	for start > 0 && line[start-1] != ' ' {
+		start--
+	}
+	word := string(line[start:pos])
+
+	switch {
+	case start == 0 && strings.HasPrefix(word, "/"):
+		var rv []string
+		for name := range commands {
+			if strings.HasPrefix("/"+name, word) {
+				rv = append(rv, "/"+name)
+			}
+		}
+		sort.Strings(rv)
+		return rv, start
+	case strings.HasPrefix(word, "@"):
+		return a.completePath(word[1:], "@"), start
+	case strings.HasPrefix(string(line), "/"):
+		name, _, _ := strings.Cut(strings.TrimPrefix(string(line), "/"), " ")
+		if commands[name].pathArg {
+			return a.completePath(word, ""), start
+		}
+	}
+	return nil, start
+}
+
+func (a *Agent) completePath(partial, prefix string) []string {
+	dir, base := filepath.Split(partial)
+	root, abs, err := a.workspace.Resolve(dir)
+	if err != nil {
+		return nil
+	}
+	entries, err := root.FS.List(abs, 1)
+	if err != nil {
+		return nil
+	}
+	var rv []string
+	for _, name := range entries {
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		rv = append(rv, prefix+dir+filepath.ToSlash(name))
+	}
+	return rv
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/middleware"
+	"github.com/mschoch/dacs/provider"
+	"github.com/mschoch/dacs/style"
+)
+
+// toolPanic is the error of a tool that panicked, with the stack trace for
+// the log.
+type toolPanic struct {
+	value any
+	stack []byte
+}
+
+func (p *toolPanic) Error() string {
+	return fmt.Sprintf("panic: %v\n\n%s", p.value, p.stack)
+}
+
+// recoverTool turns a panic of the tool into a toolPanic error, so the
+// session continues.
+func recoverTool(err *error) {
+	if r := recover(); r != nil {
+		*err = &toolPanic{value: r, stack: debug.Stack()}
+	}
+}
+
+// UseInferenceMiddleware wraps every inference in the middlewares, inside
+// of those added before.
+func (a *Agent) UseInferenceMiddleware(mws ...middleware.Inference) {
+	a.inferenceMiddleware = append(a.inferenceMiddleware, mws...)
+}
+
+// UseToolMiddleware wraps every tool call in the middlewares, inside of
+// those added before.
+func (a *Agent) UseToolMiddleware(mws ...middleware.Tool) {
+	a.toolMiddleware = append(a.toolMiddleware, mws...)
+}
+
+// chat runs the request through the inference middleware.
+func (a *Agent) chat(ctx context.Context, req *api.ChatRequest) (api.ChatResponse, error) {
+	run := func(ctx context.Context, req *api.ChatRequest) (rv api.ChatResponse, err error) {
+		var spinner *style.Spinner
+		if a.spinner {
+			spinner = style.Spin(req.Model)
+			defer spinner.Stop()
+			ctx = provider.WithQueueStatus(ctx, spinner.Status)
+		}
+		// streamed responses come in parts, the last has the metrics
+		var msg api.Message
+		err = a.client.Chat(ctx, req, func(resp api.ChatResponse) error {
+			msg.Role = resp.Message.Role
+			msg.Content += resp.Message.Content
+			msg.Thinking += resp.Message.Thinking
+			msg.ToolCalls = append(msg.ToolCalls, resp.Message.ToolCalls...)
+			rv = resp
+			if spinner != nil && !resp.Done {
+				spinner.Token()
+			}
+			return nil
+		})
+		rv.Message = msg
+		return rv, err
+	}
+	return middleware.ChainInference(run, a.inferenceMiddleware...)(ctx, req)
+}
+
+// showToolCall prints every tool call, it is the outermost tool middleware
+// so calls stopped by other middleware are shown too.
+func (a *Agent) showToolCall(next middleware.ToolFunc) middleware.ToolFunc {
+	return func(ctx context.Context, call middleware.ToolCall) (string, error) {
+		fmt.Printf("%s: %s(%s)\n", style.Label(style.Green, "tool"), call.Name, call.Input)
+		return next(ctx, call)
+	}
+}
+
+// recordToolStats records the outcome and latency of every tool call.
+func (a *Agent) recordToolStats(next middleware.ToolFunc) middleware.ToolFunc {
+	return func(ctx context.Context, call middleware.ToolCall) (string, error) {
+		start := time.Now()
+		res, err := next(ctx, call)
+		elapsed := time.Since(start)
+		a.session.RecordToolCall(call.Name, elapsed, err)
+		a.timings.tools += elapsed
+		return res, err
+	}
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/format"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/provider"
+	"github.com/mschoch/dacs/style"
+)
+
+func (a *Agent) modelManager() (provider.ModelManager, error) {
+	mm, ok := a.client.(provider.ModelManager)
+	if !ok {
+		return nil, fmt.Errorf("the backend cannot manage models")
+	}
+	return mm, nil
+}
+
+func (a *Agent) cmdModels(ctx context.Context, _ string) error {
+	mm, err := a.modelManager()
+	if err != nil {
+		return err
+	}
+	res, err := mm.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(res.Models) == 0 {
+		fmt.Println("no models, download one with /pull")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tSIZE\tFAMILY\tPARAMETERS\tQUANTIZATION")
+	for _, m := range res.Models {
+		current := " "
+		if m.Name == a.toolsLLM || m.Model == a.toolsLLM {
+			current = "*"
+		}
+		fmt.Fprintf(w, "%s %s\t%s\t%s\t%s\t%s\n", current, m.Name, format.HumanBytes(m.Size),
+			m.Details.Family, m.Details.ParameterSize, m.Details.QuantizationLevel)
+	}
+	return w.Flush()
+}
+
+func (a *Agent) cmdPull(ctx context.Context, model string) error {
+	if model == "" {
+		return fmt.Errorf("usage: /pull MODEL")
+	}
+	mm, err := a.modelManager()
+	if err != nil {
+		return err
+	}
+	return pullModel(ctx, mm, model)
+}
+
+// pullModel downloads the model, showing its progress on a single line.
+func pullModel(ctx context.Context, mm provider.ModelManager, model string) error {
+	status := ""
+	err := mm.Pull(ctx, &api.PullRequest{Model: model}, func(p api.ProgressResponse) error {
+		if p.Status != status && status != "" {
+			style.EndProgress(true)
+		}
+		status = p.Status
+		// plain output has a line per step rather than per percent
+		if p.Total > 0 && !style.Plain {
+			style.Progress(fmt.Sprintf("%s: %d%% (%s/%s)", p.Status, p.Completed*100/p.Total,
+				format.HumanBytes(p.Completed), format.HumanBytes(p.Total)))
+		} else {
+			style.Progress(p.Status)
+		}
+		return nil
+	})
+	style.EndProgress(true)
+	return err
+}
+
+// ensureModel pulls the model when it is not available, so a missing
+// model is downloaded rather than failing the first request, and checks
+// that it fits. The model named by the project's config is not pulled.
+func (a *Agent) ensureModel(ctx context.Context) {
+	if a.modelChecked == a.toolsLLM {
+		return
+	}
+	a.modelChecked = a.toolsLLM
+	mm, ok := a.client.(provider.ModelManager)
+	if !ok {
+		return
+	}
+	found, err := provider.HasModel(ctx, mm, a.toolsLLM)
+	if err != nil {
+		// the backend may not list models, let the request tell
+		return
+	}
+	if !found && a.toolsLLM == a.cfg.ProjectToolsLLM {
+		// cloning a repository must not be enough to download a model
+		fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("Warning")), i18n.T("%s, named by the project config, is not available, download it with /pull %s", a.toolsLLM, a.toolsLLM))
+		return
+	}
+	if !found {
+		fmt.Printf("%s is not available, pulling it\n", a.toolsLLM)
+		if err := pullModel(ctx, mm, a.toolsLLM); err != nil {
+			i18n.Printf("Error: pulling %s: %s\n", a.toolsLLM, err.Error())
+			return
+		}
+	}
+	a.checkFit(ctx, mm)
+}
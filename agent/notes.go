@@ -0,0 +1,25 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// notesMessage lists the scratchpad notes by title and size, their content
+// is only read back with read_notes when needed.
+func (a *Agent) notesMessage() (api.Message, bool) {
+	if len(a.session.Notes) == 0 {
+		return api.Message{}, false
+	}
+	var sb strings.Builder
+	sb.WriteString("Your scratchpad has these notes, use read_notes to see their content:")
+	for _, n := range a.session.Notes {
+		fmt.Fprintf(&sb, "\n- %s (%d bytes)", n.Title, len(n.Content))
+	}
+	return api.Message{
+		Role:    "user",
+		Content: sb.String(),
+	}, true
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/notify"
+)
+
+// maxNotifyLen bounds the part of the answer included in a notification
+const maxNotifyLen = 200
+
+// UseNotifier sends a notification with n when a turn that ran for at
+// least after completes, or when the agent is waiting for the user.
+func (a *Agent) UseNotifier(n *notify.Notifier, after time.Duration) {
+	a.notifier = n
+	a.notifyAfter = after
+}
+
+// notify sends the notification in the background, so a slow webhook does
+// not hold up the prompt.
+func (a *Agent) notify(ctx context.Context, title, message string) {
+	if a.notifier == nil {
+		return
+	}
+	if r := []rune(message); len(r) > maxNotifyLen {
+		message = string(r[:maxNotifyLen]) + "..."
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if err := a.notifier.Send(ctx, title, message); err != nil {
+			i18n.Printf("Error: notifying: %s\n", err.Error())
+		}
+	}()
+}
+
+// notifyDone notifies that the turn completed, if it ran long enough for
+// the user to have switched away.
+func (a *Agent) notifyDone(ctx context.Context, answer string) {
+	if a.turnStarted.IsZero() || time.Since(a.turnStarted) < a.notifyAfter {
+		return
+	}
+	summary, _, _ := strings.Cut(strings.TrimSpace(answer), "\n")
+	if summary == "" {
+		summary = "the agent is done"
+	}
+	a.notify(ctx, fmt.Sprintf("dacs finished after %s", time.Since(a.turnStarted).Round(time.Second)), summary)
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/provider"
+)
+
+const (
+	minNumCtx = 8192
+	// room left for the model's response on top of the prompt
+	responseReserve = 4096
+	charsPerToken   = 4
+)
+
+// estimateTokens is a rough, tokenizer free estimate of the prompt size.
+func estimateTokens(conversation []api.Message, toolsList api.Tools) int {
+	chars := 0
+	for _, m := range conversation {
+		chars += len(m.Role) + len(m.Content)
+		for _, tc := range m.ToolCalls {
+			chars += len(tc.Function.Name) + len(tc.Function.Arguments.String())
+		}
+	}
+	if len(toolsList) > 0 {
+		buf, _ := json.Marshal(toolsList)
+		chars += len(buf)
+	}
+	return chars / charsPerToken
+}
+
+// numCtx returns the context window to request. It only ever grows, in
+// power of two steps, because every change makes Ollama reload the model.
+func (a *Agent) numCtx(ctx context.Context, conversation []api.Message, toolsList api.Tools) int {
+	if a.fixedNumCtx > 0 {
+		return a.fixedNumCtx
+	}
+
+	if a.modelMaxCtx == 0 {
+		a.modelMaxCtx = provider.ContextLength(ctx, a.client, a.toolsLLM)
+		if a.modelMaxCtx == 0 {
+			a.modelMaxCtx = -1
+		}
+	}
+
+	want := estimateTokens(conversation, toolsList) + responseReserve
+	size := max(a.currentNumCtx, minNumCtx)
+	for size < want {
+		size *= 2
+	}
+	if a.maxNumCtx > 0 && size > a.maxNumCtx {
+		size = a.maxNumCtx
+	}
+	if a.modelMaxCtx > 0 && size > a.modelMaxCtx {
+		size = a.modelMaxCtx
+	}
+	a.currentNumCtx = size
+	return size
+}
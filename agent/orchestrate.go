@@ -0,0 +1,305 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/config"
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/provider"
+	"github.com/mschoch/dacs/session"
+	"github.com/mschoch/dacs/style"
+	"github.com/mschoch/dacs/tools"
+	"github.com/mschoch/dacs/workspace"
+)
+
+const (
+	planPrompt = "You are the planner of a team of coding agents. Break the user's task into a few subtasks, each a self-contained piece of work a worker agent can do on its own without seeing the others, such as changing one component or writing the tests for one feature. Give every subtask a short title and complete instructions, including the files involved and how to check the work. Do not split a task that is small enough for one agent, return a single subtask then."
+
+	// subtasks a plan is cut to
+	maxSubtasks = 8
+)
+
+var planFormat = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "subtasks": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "title": {"type": "string"},
+          "instructions": {"type": "string"}
+        },
+        "required": ["title", "instructions"]
+      }
+    }
+  },
+  "required": ["subtasks"]
+}`)
+
+type subtask struct {
+	Title        string `json:"title"`
+	Instructions string `json:"instructions"`
+
+	outcome *session.Outcome
+	err     error
+	// stats of the worker's session
+	stats []session.TaskStats
+	// worktree the subtask was done in, when in parallel
+	worktree string
+}
+
+// cmdOrchestrate has the model plan the task as subtasks, runs a worker
+// agent for each, in parallel in git worktrees when orchestrator_workers
+// allows, and then has the model check the results fit together.
+func (a *Agent) cmdOrchestrate(ctx context.Context, task string) error {
+	if task == "" {
+		return fmt.Errorf("usage: /orchestrate TASK")
+	}
+	a.ensureModel(ctx)
+	plan, err := a.plan(ctx, task)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("plan")), i18n.T("%d subtasks", len(plan)))
+	for n, st := range plan {
+		fmt.Printf("  %d. %s\n", n+1, st.Title)
+	}
+
+	root := a.workspace.Primary()
+	parallel := a.cfg.OrchestratorWorkers > 1 && len(plan) > 1 && !a.dryRun && len(a.workspace.Roots) == 1 &&
+		root.FS == workspace.Local && root.FS.Command(ctx, root.Path, "git rev-parse --git-dir").Run() == nil
+	if !parallel {
+		for n, st := range plan {
+			fmt.Printf("%s: %s\n", subtaskLabel(n, len(plan)), st.Title)
+			st.outcome, st.err = a.worker(a.workspace).runSubtask(ctx, task, plan, n)
+			reportSubtask(n, len(plan), st)
+		}
+	} else {
+		a.runParallel(ctx, task, plan)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n\nThis task was split into subtasks done by worker agents:\n", task)
+	for n, st := range plan {
+		fmt.Fprintf(&sb, "%d. %s: %s\n", n+1, st.Title, subtaskResult(st))
+	}
+	sb.WriteString("\nCheck that their changes are complete and fit together, fix what does not, and report the outcome.")
+	a.addUserInput(ctx, sb.String())
+	for _, st := range plan {
+		a.session.AddTaskStats(st.stats...)
+	}
+	a.resume = true
+	return nil
+}
+
+// plan asks the model to break the task into subtasks.
+func (a *Agent) plan(ctx context.Context, task string) ([]*subtask, error) {
+	conversation := []api.Message{{Role: "system", Content: planPrompt}}
+	for _, m := range a.session.Messages {
+		if m.Role != "system" {
+			conversation = append(conversation, m)
+		}
+	}
+	conversation = append(conversation, api.Message{Role: "user", Content: task})
+	res, err := a.chat(ctx, &api.ChatRequest{
+		Model:    a.toolsLLM,
+		Messages: conversation,
+		Format:   planFormat,
+		Options: map[string]interface{}{
+			"temperature": 0.0,
+			"num_ctx":     a.numCtx(ctx, conversation, nil),
+		},
+		Stream: &FALSE,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var plan struct {
+		Subtasks []*subtask `json:"subtasks"`
+	}
+	_, content := extractThinking(res.Message.Content, res.Message.Thinking)
+	if err := json.Unmarshal([]byte(content), &plan); err != nil || len(plan.Subtasks) == 0 {
+		return nil, fmt.Errorf("the model did not answer with a plan: %s", content)
+	}
+	if len(plan.Subtasks) > maxSubtasks {
+		plan.Subtasks = plan.Subtasks[:maxSubtasks]
+	}
+	return plan.Subtasks, nil
+}
+
+// worker returns an agent for a subtask, with the tools, model and
+// middleware of this one, a new session and nobody to ask.
+func (a *Agent) worker(ws *workspace.Workspace) *Agent {
+	toolset := slices.DeleteFunc(slices.Clone(a.tools), func(t tools.Tool) bool {
+		return t.Definition.Name == tools.FinishDefinition.Definition.Name
+	})
+	toolset = append(toolset, tools.FinishDefinition)
+	w := New(a.client, a.cfg, func(string) (string, bool) { return "", false }, toolset)
+	w.toolsLLM = a.toolsLLM
+	w.modelChecked = a.modelChecked
+	w.critic = a.critic
+	w.dryRun = a.dryRun
+	w.policy = a.policy
+	w.systemTemplate = a.systemTemplate
+	w.workspace = ws
+	// its statistics are added to this agent's task
+	w.saveStats = false
+	// the middleware added after New, such as the policy and logging
+	w.UseInferenceMiddleware(a.inferenceMiddleware[len(w.inferenceMiddleware):]...)
+	w.UseToolMiddleware(a.toolMiddleware[len(w.toolMiddleware):]...)
+	return w
+}
+
+// runSubtask has the agent, a worker, do the nth subtask of the plan, and
+// returns what it reported.
+func (a *Agent) runSubtask(ctx context.Context, task string, plan []*subtask, n int) (*session.Outcome, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "You are one of several agents working on this task:\n%s\n\nIt was planned as these subtasks:\n", task)
+	for i, st := range plan {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, st.Title)
+	}
+	fmt.Fprintf(&sb, "\nYou do subtask %d, only that one, the others are done by other agents:\n%s\n\n%s\n\nWhen you are done, or cannot make further progress, call finish.",
+		n+1, plan[n].Title, plan[n].Instructions)
+	// the user waits for the agent they talk to first
+	err := a.RunPrompt(provider.WithPriority(ctx, provider.Background), sb.String())
+	plan[n].stats = a.session.Tasks
+	if err != nil {
+		return nil, err
+	}
+	if a.session.Outcome != nil {
+		return a.session.Outcome, nil
+	}
+	last := a.session.Messages[len(a.session.Messages)-1]
+	return &session.Outcome{Summary: "no outcome reported, its last answer: " + last.Content}, nil
+}
+
+// runParallel runs up to OrchestratorWorkers workers at a time, each in a
+// git worktree of the primary root started from its current state, then
+// applies their changes to it in the order of the plan.
+func (a *Agent) runParallel(ctx context.Context, task string, plan []*subtask) {
+	root := a.workspace.Primary()
+	base, err := gitOutput(ctx, root, "git diff --binary HEAD", nil)
+	if err != nil {
+		for _, st := range plan {
+			st.err = err
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	// git does not add worktrees concurrently
+	var adding sync.Mutex
+	sem := make(chan struct{}, a.cfg.OrchestratorWorkers)
+	for n, st := range plan {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			fmt.Printf("%s: %s\n", subtaskLabel(n, len(plan)), i18n.T("%s, started", st.Title))
+			adding.Lock()
+			st.worktree, st.err = addWorktree(ctx, root, base)
+			adding.Unlock()
+			if st.err != nil {
+				reportSubtask(n, len(plan), st)
+				return
+			}
+			ws, err := workspace.New([]config.Root{{Name: root.Name, Path: st.worktree}})
+			if err != nil {
+				st.err = err
+			} else {
+				w := a.worker(ws)
+				w.spinner = false
+				st.outcome, st.err = w.runSubtask(ctx, task, plan, n)
+			}
+			reportSubtask(n, len(plan), st)
+		}()
+	}
+	wg.Wait()
+
+	for n, st := range plan {
+		if st.worktree == "" {
+			continue
+		}
+		wt := &workspace.Root{Name: root.Name, Path: st.worktree, FS: root.FS}
+		// relative to the worktree's base commit, the state it started from
+		patch, err := gitOutput(ctx, wt, "git add -A && git diff --cached --binary HEAD", nil)
+		if err == nil && len(bytes.TrimSpace(patch)) > 0 {
+			_, err = gitOutput(ctx, root, "git apply", patch)
+		}
+		if err != nil {
+			st.err = fmt.Errorf("applying its changes: %w, they are left in %s", err, st.worktree)
+			fmt.Printf("%s: %s\n", subtaskLabel(n, len(plan)), st.err)
+			continue
+		}
+		_, _ = gitOutput(ctx, root, "git worktree remove --force "+workspace.ShellQuote(st.worktree), nil)
+	}
+}
+
+// addWorktree creates a detached worktree of the root's repository, with
+// the base changes applied and committed so later changes can be told
+// apart.
+func addWorktree(ctx context.Context, root *workspace.Root, base []byte) (string, error) {
+	dir, err := os.MkdirTemp("", "dacs-worktree-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = gitOutput(ctx, root, "git worktree add --detach "+workspace.ShellQuote(dir)+" HEAD", nil)
+	if err != nil {
+		os.Remove(dir)
+		return "", err
+	}
+	wt := &workspace.Root{Name: root.Name, Path: dir, FS: root.FS}
+	if len(bytes.TrimSpace(base)) > 0 {
+		_, err = gitOutput(ctx, wt, "git apply --binary && git add -A && git -c user.name=dacs -c user.email=dacs@localhost commit -q --no-verify -m base", base)
+		if err != nil {
+			_, _ = gitOutput(ctx, root, "git worktree remove --force "+workspace.ShellQuote(dir), nil)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// gitOutput runs the script in the root with the input on stdin, errors
+// include what it wrote to stderr.
+func gitOutput(ctx context.Context, root *workspace.Root, script string, input []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := root.FS.Command(ctx, root.Path, script)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", script, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func reportSubtask(n, total int, st *subtask) {
+	fmt.Printf("%s: %s, %s\n", subtaskLabel(n, total), st.Title, subtaskResult(st))
+}
+
+func subtaskLabel(n, total int) string {
+	return style.Label(style.Yellow, i18n.T("subtask %d/%d", n+1, total))
+}
+
+func subtaskResult(st *subtask) string {
+	switch {
+	case st.err != nil:
+		return "failed: " + st.err.Error()
+	case st.outcome == nil:
+		return "not run"
+	case st.outcome.Success:
+		return "done: " + st.outcome.Summary
+	}
+	return "not completed: " + st.outcome.Summary
+}
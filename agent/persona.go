@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/tools"
+)
+
+// Persona bundles instructions added to the system prompt, the tools
+// offered (all when empty) and the model (the configured one when empty)
+// for a workflow.
+type Persona struct {
+	Prompt string
+	Tools  []string
+	Model  string
+}
+
+// defaultPersona is the name of the configured behavior, without a persona
+const defaultPersona = "default"
+
+var readOnlyTools = []string{"read_file", "list_files", "semantic_search", "describe_files", "read_notes", "write_note", "todo_read", "todo_write", "recall_result", "get_capabilities"}
+
+// DefaultPersonas are available unless replaced by the configuration.
+var DefaultPersonas = map[string]Persona{
+	"reviewer": {
+		Prompt: "You are reviewing code. Read the code in question and its context, then point out bugs, risky changes, missing tests and unclear code, each with a path:line reference and a concrete suggestion. Do not change any files.",
+		Tools:  readOnlyTools,
+	},
+	"test-writer": {
+		Prompt: "You write tests. Follow the conventions of the existing tests, prefer table-driven tests covering edge cases and errors, run them and iterate until they compile and pass. Do not change the code under test.",
+	},
+	"documenter": {
+		Prompt: "You write Go doc comments. Each starts with the name of the symbol it documents and says what it does, its inputs and results and any caveats, in complete sentences, briefly, in the style of the existing comments in the package. Do not change any files.",
+		Tools:  readOnlyTools,
+	},
+	"architect": {
+		Prompt: "You are a software architect. Explore the codebase to understand its structure, then discuss designs, trade-offs and plans for changes. Do not change any files.",
+		Tools:  readOnlyTools,
+	},
+}
+
+// UsePersonas makes the personas available with /mode, in addition to the
+// default behavior.
+func (a *Agent) UsePersonas(personas map[string]Persona) {
+	a.personas = personas
+}
+
+// SetPersona switches to the named persona, or back to the default
+// behavior.
+func (a *Agent) SetPersona(name string) error {
+	if name == defaultPersona {
+		a.persona = ""
+		a.applyTools()
+		a.setModel(a.baseModel)
+		return nil
+	}
+	p, ok := a.personas[name]
+	if !ok {
+		return fmt.Errorf("unknown persona %q, try /mode", name)
+	}
+	a.persona = name
+	a.applyTools()
+	model := a.baseModel
+	if p.Model != "" {
+		model = p.Model
+	}
+	a.setModel(model)
+	return nil
+}
+
+// setModel switches the model, its context length is looked up again.
+func (a *Agent) setModel(model string) {
+	if model != a.toolsLLM {
+		a.toolsLLM = model
+		a.modelMaxCtx = 0
+	}
+}
+
+// personaTool reports whether the current persona offers the tool.
+func (a *Agent) personaTool(name string) bool {
+	if a.persona == "" {
+		return true
+	}
+	p := a.personas[a.persona]
+	// a headless run must still be able to report its outcome
+	return len(p.Tools) == 0 || slices.Contains(p.Tools, name) || name == tools.FinishDefinition.Definition.Name
+}
+
+// personaPrompt returns the current persona's instructions.
+func (a *Agent) personaPrompt() string {
+	if a.persona == "" {
+		return ""
+	}
+	return a.personas[a.persona].Prompt
+}
+
+func (a *Agent) cmdMode(_ context.Context, name string) error {
+	if name == "" {
+		current := a.persona
+		if current == "" {
+			current = defaultPersona
+		}
+		names := []string{defaultPersona}
+		for n := range a.personas {
+			names = append(names, n)
+		}
+		sort.Strings(names[1:])
+		for _, n := range names {
+			mark := " "
+			if n == current {
+				mark = "*"
+			}
+			fmt.Printf(" %s %s\n", mark, n)
+		}
+		return nil
+	}
+	if err := a.SetPersona(name); err != nil {
+		return err
+	}
+	i18n.Printf("mode %s, model %s, %d tools\n", name, a.toolsLLM, len(a.tools))
+	return nil
+}
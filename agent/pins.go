@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/i18n"
+)
+
+// pinnedMessage shows the current contents of the pinned files. The files
+// are re-read on every call, so the model always sees them as they are now.
+func (a *Agent) pinnedMessage() (api.Message, bool) {
+	if len(a.session.Pinned) == 0 {
+		return api.Message{}, false
+	}
+
+	var sb strings.Builder
+	sb.WriteString("The user pinned these files, their current contents are always shown here:")
+	for _, path := range a.session.Pinned {
+		content, err := a.readFile(path)
+		if err != nil {
+			fmt.Fprintf(&sb, "\n\n%s: %v", path, err)
+			continue
+		}
+		fmt.Fprintf(&sb, "\n\nContents of %s:\n```\n%s\n```", path, strings.TrimRight(string(content), "\n"))
+	}
+	return api.Message{
+		Role:    "user",
+		Content: sb.String(),
+	}, true
+}
+
+func (a *Agent) cmdPin(_ context.Context, path string) error {
+	if path == "" {
+		if len(a.session.Pinned) == 0 {
+			fmt.Println(i18n.T("no pinned files"))
+		}
+		for _, p := range a.session.Pinned {
+			fmt.Printf("  %s\n", p)
+		}
+		return nil
+	}
+	content, err := a.readFile(path)
+	if err != nil {
+		return err
+	}
+	if len(content) > maxMentionBytes {
+		return i18n.Errorf("%s is %d bytes, too large to pin", path, len(content))
+	}
+	if !slices.Contains(a.session.Pinned, path) {
+		a.session.Pinned = append(a.session.Pinned, path)
+	}
+	i18n.Printf("pinned %s\n", path)
+	return nil
+}
+
+func (a *Agent) cmdUnpin(_ context.Context, path string) error {
+	if path == "" {
+		a.session.Pinned = nil
+		fmt.Println(i18n.T("unpinned all files"))
+		return nil
+	}
+	i := slices.Index(a.session.Pinned, path)
+	if i < 0 {
+		return i18n.Errorf("%s is not pinned", path)
+	}
+	a.session.Pinned = slices.Delete(a.session.Pinned, i, i+1)
+	i18n.Printf("unpinned %s\n", path)
+	return nil
+}
@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mschoch/dacs/middleware"
+	"github.com/mschoch/dacs/tools"
+)
+
+// minResultRefSize is the size of a tool result above which it gets an ID
+const minResultRefSize = 2000
+
+// labelResults is a tool middleware keeping large results in the session
+// and prefixing them with their ID, so that summaries can refer to them and
+// recall_result fetch them again.
+func (a *Agent) labelResults(next middleware.ToolFunc) middleware.ToolFunc {
+	return func(ctx context.Context, call middleware.ToolCall) (string, error) {
+		res, err := next(ctx, call)
+		// a repeated read replaced by dedupReads is kept already
+		if err != nil || a.deduped || len(res) < minResultRefSize || call.Name == tools.RecallResultDefinition.Definition.Name {
+			return res, err
+		}
+		id := a.session.AddResult(call.Name, res)
+		return fmt.Sprintf("[result %s]\n%s", id, res), nil
+	}
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/style"
+	"github.com/mschoch/dacs/tools"
+)
+
+// retryOptions override the inference settings until the next user input.
+type retryOptions struct {
+	temperature *float64
+	model       string
+	// skipDraft sends the retry to the tools model, the draft model's
+	// answer may be what is retried
+	skipDraft bool
+}
+
+// cmdRetry drops everything produced since the user's last input and runs
+// inference again, optionally with a different temperature or model. The
+// model is told about the dropped calls that changed the workspace, their
+// effects are not undone.
+func (a *Agent) cmdRetry(_ context.Context, args string) error {
+	if a.turnStart < 0 || a.turnStart > len(a.session.Messages) {
+		return fmt.Errorf("nothing to retry")
+	}
+
+	opts := retryOptions{skipDraft: true}
+	for _, arg := range strings.Fields(args) {
+		k, v, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("invalid argument %q, expected temperature=N or model=NAME", arg)
+		}
+		switch k {
+		case "temperature":
+			t, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("invalid temperature %q", v)
+			}
+			opts.temperature = &t
+		case "model":
+			opts.model = v
+		default:
+			return fmt.Errorf("unknown option %q", k)
+		}
+	}
+
+	dropped := a.session.Messages[a.turnStart:]
+	var applied []string
+	if !a.dryRun {
+		applied = a.appliedCalls(dropped)
+	}
+	a.session.Messages = a.session.Messages[:a.turnStart]
+	if len(applied) > 0 {
+		fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("Warning")), i18n.T("%d tool calls already ran, their effects on the workspace are kept", len(applied)))
+		// kept on further retries, the calls stay applied
+		a.session.Append(api.Message{
+			Role:    "user",
+			Content: "An earlier answer to this was discarded, but these tool calls it made already changed the workspace, their effects are kept:\n" + strings.Join(applied, "\n"),
+		})
+		a.turnStart = len(a.session.Messages)
+	}
+	a.retry = opts
+	a.resume = true
+	i18n.Printf("dropped %d messages, retrying\n", len(dropped))
+	return nil
+}
+
+// appliedCalls lists the calls in the messages of tools that change the
+// workspace, or may, as name(arguments).
+func (a *Agent) appliedCalls(msgs []api.Message) []string {
+	var rv []string
+	for _, m := range msgs {
+		for _, tc := range m.ToolCalls {
+			for _, t := range a.allTools {
+				if t.Definition.Name == tc.Function.Name && (t.Effect == tools.EffectWrite || t.Effect == tools.EffectDelete || t.Effect == tools.EffectExec) {
+					rv = append(rv, fmt.Sprintf("- %s(%s)", tc.Function.Name, tc.Function.Arguments.String()))
+				}
+			}
+		}
+	}
+	return rv
+}
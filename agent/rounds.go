@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/style"
+)
+
+const checkpointPrompt = "Pause here. Briefly summarize what you have done so far for the user's request and what remains to be done. Do not call any tools."
+
+// checkpoint pauses after maxToolRounds consecutive tool rounds, shows the
+// model's summary of its progress and asks the user whether to continue.
+// It reports whether to continue, the user may add a message to steer it.
+func (a *Agent) checkpoint(ctx context.Context) (bool, error) {
+	conversation := slices.Concat(a.withEphemeral(a.session.Messages), []api.Message{{
+		Role:    "user",
+		Content: checkpointPrompt,
+	}})
+	summary, err := a.chat(ctx, &api.ChatRequest{
+		Model:    a.toolsLLM,
+		Messages: conversation,
+		Options: map[string]interface{}{
+			"temperature": 0.0,
+			"num_ctx":     a.numCtx(ctx, conversation, nil),
+		},
+		Stream: &FALSE,
+	})
+	if err != nil {
+		return false, fmt.Errorf("error summarizing progress: %v", err)
+	}
+	_, content := extractThinking(summary.Message.Content, summary.Message.Thinking)
+	fmt.Printf("%s %s: %s\n", style.Label(style.Yellow, i18n.T("Agent")), i18n.T("(paused after %d tool rounds)", a.toolRounds), a.renderer.Render(content))
+
+	a.notify(ctx, "dacs is waiting", fmt.Sprintf("paused after %d tool rounds, continue?", a.toolRounds))
+	answer, ok := a.getUserMessage(style.Label(style.Blue, i18n.T("Continue?")) + " " + i18n.T("[Y/n or a message]") + ": ")
+	if !ok {
+		return false, nil
+	}
+	a.toolRounds = 0
+	switch i18n.Answer(answer) {
+	case "", "y":
+		return true, nil
+	case "n":
+		return false, nil
+	}
+	a.session.Append(api.Message{
+		Role:    "user",
+		Content: a.expandMentions(answer),
+	})
+	return true, nil
+}
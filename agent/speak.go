@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mschoch/dacs/voice"
+)
+
+// UseSpeaker enables /speak, reading final answers aloud with s.
+func (a *Agent) UseSpeaker(s *voice.Speaker) {
+	a.speaker = s
+}
+
+func (a *Agent) cmdSpeak(context.Context, string) error {
+	if a.speaker == nil {
+		return fmt.Errorf("speech output is not available")
+	}
+	a.speak = !a.speak
+	if a.speak {
+		fmt.Println("speaking answers")
+	} else {
+		a.speaker.Stop()
+		fmt.Println("not speaking answers")
+	}
+	return nil
+}
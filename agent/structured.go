@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ollama/ollama/api"
+)
+
+const structuredPrompt = "Now give your final answer to the task as JSON matching the requested schema, based on everything done above. Do not call any tools."
+
+// StructuredAnswer asks the model for its final answer constrained to the
+// JSON schema, using Ollama's structured output, and returns the JSON.
+func (a *Agent) StructuredAnswer(ctx context.Context, schema json.RawMessage) (json.RawMessage, error) {
+	if !json.Valid(schema) {
+		return nil, fmt.Errorf("invalid JSON schema")
+	}
+	a.session.Append(api.Message{
+		Role:    "user",
+		Content: structuredPrompt,
+	})
+	conversation := a.withEphemeral(a.session.Messages)
+
+	res, err := a.chat(ctx, &api.ChatRequest{
+		Model:    a.toolsLLM,
+		Messages: conversation,
+		Format:   schema,
+		Options: map[string]interface{}{
+			"temperature": 0.0,
+			"num_ctx":     a.numCtx(ctx, conversation, nil),
+		},
+		Stream: &FALSE,
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, content := extractThinking(res.Message.Content, res.Message.Thinking)
+	res.Message.Content, res.Message.Thinking = content, ""
+	a.session.Append(res.Message)
+	if !json.Valid([]byte(content)) {
+		return nil, fmt.Errorf("the model did not answer with valid JSON: %s", content)
+	}
+	return json.RawMessage(content), nil
+}
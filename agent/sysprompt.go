@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/i18n"
+)
+
+// DefaultSystemTemplate renders the system prompt before every inference,
+// so its dynamic parts are current. It is a text/template over
+// systemPromptData.
+const DefaultSystemTemplate = SystemPrompt + `{{with .Workspace}}
+
+{{.}}{{end}}{{with .Conventions}}
+
+{{.}}{{end}}
+
+Current time: {{.Time}}{{with .Branch}}
+Git branch: {{.}}{{end}}{{with .Dirty}}
+Uncommitted changes: {{join . ", "}}{{end}}{{with .Pinned}}
+Pinned files: {{join . ", "}}{{end}}`
+
+// files listed as uncommitted changes in the system prompt
+const maxDirtyFiles = 20
+
+type systemPromptData struct {
+	Time      string
+	Workspace string
+	// Conventions are those of the languages detected in the workspace.
+	Conventions string
+	Branch      string
+	Dirty       []string
+	// Pinned lists the pinned files with a digest of their content, so the
+	// model notices when they change.
+	Pinned []string
+}
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// UseSystemTemplate replaces DefaultSystemTemplate.
+func (a *Agent) UseSystemTemplate(text string) error {
+	tmpl, err := template.New("system").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid system prompt template: %v", err)
+	}
+	a.systemTemplate = tmpl
+	return nil
+}
+
+// withSystemPrompt returns the conversation with the system prompt
+// rendered for this inference.
+func (a *Agent) withSystemPrompt(ctx context.Context, conversation []api.Message) []api.Message {
+	if len(conversation) == 0 || conversation[0].Role != "system" {
+		return conversation
+	}
+	var buf bytes.Buffer
+	err := a.systemTemplate.Execute(&buf, a.systemPromptData(ctx))
+	if err != nil {
+		i18n.Printf("Error: rendering system prompt: %s\n", err.Error())
+		return conversation
+	}
+	if a.systemPromptAppend != "" {
+		buf.WriteString("\n\n" + a.systemPromptAppend)
+	}
+	if prompt := a.personaPrompt(); prompt != "" {
+		buf.WriteString("\n\n" + prompt)
+	}
+	rv := append([]api.Message{}, conversation...)
+	rv[0].Content = buf.String()
+	return rv
+}
+
+func (a *Agent) systemPromptData(ctx context.Context) systemPromptData {
+	rv := systemPromptData{
+		Time:        time.Now().Format("Monday, 2006-01-02 15:04 MST"),
+		Workspace:   a.workspace.Describe(),
+		Conventions: a.languageConventions(),
+	}
+
+	root := a.workspace.Primary()
+	if out, err := root.FS.Command(ctx, root.Path, "git rev-parse --abbrev-ref HEAD").Output(); err == nil {
+		rv.Branch = strings.TrimSpace(string(out))
+	}
+	if rv.Branch != "" {
+		if out, err := root.FS.Command(ctx, root.Path, "git status --porcelain").Output(); err == nil {
+			for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+				if len(line) < 4 {
+					continue
+				}
+				if len(rv.Dirty) == maxDirtyFiles {
+					rv.Dirty = append(rv.Dirty, "...")
+					break
+				}
+				rv.Dirty = append(rv.Dirty, line[3:])
+			}
+		}
+	}
+
+	for _, path := range a.session.Pinned {
+		content, err := a.readFile(path)
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(content)
+		rv.Pinned = append(rv.Pinned, fmt.Sprintf("%s (sha256 %x)", path, sum[:4]))
+	}
+	return rv
+}
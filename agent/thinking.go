@@ -0,0 +1,51 @@
+package agent
+
+import "strings"
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// splitThinking separates the <think> blocks some reasoning models emit
+// inline from the rest of the content. Chat templates that open the block
+// in the prompt only emit the closing tag, so everything before a leading
+// </think> is treated as thinking too.
+func splitThinking(content string) (thinking, answer string) {
+	var thoughts []string
+	if closeAt := strings.Index(content, thinkClose); closeAt >= 0 &&
+		!strings.Contains(content[:closeAt], thinkOpen) {
+		thoughts = append(thoughts, content[:closeAt])
+		content = content[closeAt+len(thinkClose):]
+	}
+	for {
+		openAt := strings.Index(content, thinkOpen)
+		if openAt < 0 {
+			break
+		}
+		rest := content[openAt+len(thinkOpen):]
+		closeAt := strings.Index(rest, thinkClose)
+		if closeAt < 0 {
+			// unterminated, the model ran out of tokens while thinking
+			thoughts = append(thoughts, rest)
+			content = content[:openAt]
+			break
+		}
+		thoughts = append(thoughts, rest[:closeAt])
+		content = content[:openAt] + rest[closeAt+len(thinkClose):]
+	}
+	for i := range thoughts {
+		thoughts[i] = strings.TrimSpace(thoughts[i])
+	}
+	return strings.TrimSpace(strings.Join(thoughts, "\n\n")), strings.TrimSpace(content)
+}
+
+// extractThinking moves any reasoning out of the message, so that it is not
+// sent back to the model as part of the context, and returns it.
+func extractThinking(content string, thinkingField string) (thinking, answer string) {
+	thinking, answer = splitThinking(content)
+	if thinkingField != "" {
+		thinking = strings.TrimSpace(strings.TrimSpace(thinkingField) + "\n\n" + thinking)
+	}
+	return thinking, answer
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/middleware"
+	"github.com/mschoch/dacs/style"
+)
+
+// turnTimings add up where the time of a turn went, from the metrics of
+// its responses and the duration of its tool calls.
+type turnTimings struct {
+	inferences               int
+	load                     time.Duration
+	promptEval, eval         time.Duration
+	promptTokens, evalTokens int
+	tools                    time.Duration
+}
+
+// recordTimings is an inference middleware adding the metrics of every
+// response to the turn's timings and the task's statistics.
+func (a *Agent) recordTimings(next middleware.InferenceFunc) middleware.InferenceFunc {
+	return func(ctx context.Context, req *api.ChatRequest) (api.ChatResponse, error) {
+		res, err := next(ctx, req)
+		if err == nil {
+			t := &a.timings
+			t.inferences++
+			t.load += res.LoadDuration
+			t.promptEval += res.PromptEvalDuration
+			t.eval += res.EvalDuration
+			t.promptTokens += res.PromptEvalCount
+			t.evalTokens += res.EvalCount
+			a.session.RecordInference(req.Model, res.PromptEvalCount, res.EvalCount)
+		}
+		return res, err
+	}
+}
+
+// String formats the timings on one line, e.g. load 2.1s, prompt 1830
+// tokens at 950 tok/s, generation 212 tokens at 38 tok/s, tools 1.4s.
+func (t turnTimings) String() string {
+	parts := []string{fmt.Sprintf("load %s", t.load.Round(100*time.Millisecond))}
+	if t.promptEval > 0 {
+		parts = append(parts, fmt.Sprintf("prompt %d tokens at %.0f tok/s", t.promptTokens, float64(t.promptTokens)/t.promptEval.Seconds()))
+	}
+	if t.eval > 0 {
+		parts = append(parts, fmt.Sprintf("generation %d tokens at %.0f tok/s", t.evalTokens, float64(t.evalTokens)/t.eval.Seconds()))
+	}
+	parts = append(parts, fmt.Sprintf("tools %s", t.tools.Round(100*time.Millisecond)))
+	if t.inferences > 1 {
+		parts = append(parts, fmt.Sprintf("%d inferences", t.inferences))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printTimings shows the breakdown of the turn, when enabled.
+func (a *Agent) printTimings() {
+	if a.showTimings && a.timings.inferences > 0 {
+		fmt.Println(style.Color(style.Dim, a.timings.String()))
+	}
+}
+
+func (a *Agent) cmdTimings(context.Context, string) error {
+	a.showTimings = !a.showTimings
+	if a.showTimings {
+		fmt.Println("showing timings")
+	} else {
+		fmt.Println("hiding timings")
+	}
+	return nil
+}
+
+// saveMetadata saves the statistics of the session for dacs stats, when
+// enabled.
+func (a *Agent) saveMetadata() {
+	if !a.saveStats {
+		return
+	}
+	if err := a.session.SaveMetadata(a.workspace.Primary().Path); err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/tools"
+)
+
+// toolChoiceNone disables tool use for a turn.
+const toolChoiceNone = "none"
+
+// toolChoice restricts the tools offered for the next inference, it is
+// either empty (the model chooses), toolChoiceNone or the name of the only
+// tool offered. Ollama has no tool_choice, so a forced tool is the only one
+// offered and the model is told to call it.
+type toolChoice struct {
+	choice string
+	// turn keeps the choice for the whole turn rather than the next
+	// inference only
+	turn bool
+}
+
+// offeredTools applies the tool choice to the tools and returns the
+// instruction to add to the conversation, if any.
+func (a *Agent) offeredTools(toolsList api.Tools) (api.Tools, string) {
+	switch a.toolChoice.choice {
+	case "":
+		return toolsList, ""
+	case toolChoiceNone:
+		return nil, ""
+	}
+	i := slices.IndexFunc(toolsList, func(t api.Tool) bool {
+		return t.Function.Name == a.toolChoice.choice
+	})
+	if i < 0 {
+		return toolsList, ""
+	}
+	return toolsList[i : i+1], fmt.Sprintf("Call the %s tool now.", a.toolChoice.choice)
+}
+
+// cmdAsk answers the question without using any tools.
+func (a *Agent) cmdAsk(ctx context.Context, question string) error {
+	if question == "" {
+		return fmt.Errorf("usage: /ask QUESTION")
+	}
+	a.addUserInput(ctx, question)
+	a.toolChoice = toolChoice{choice: toolChoiceNone, turn: true}
+	a.resume = true
+	return nil
+}
+
+// cmdUse makes the model start the turn by calling the tool.
+func (a *Agent) cmdUse(ctx context.Context, args string) error {
+	name, prompt, _ := strings.Cut(args, " ")
+	prompt = strings.TrimSpace(prompt)
+	if name == "" || prompt == "" {
+		return fmt.Errorf("usage: /use TOOL PROMPT")
+	}
+	if !slices.ContainsFunc(a.tools, func(t tools.Tool) bool { return t.Definition.Name == name }) {
+		return fmt.Errorf("unknown tool %q", name)
+	}
+	a.addUserInput(ctx, prompt)
+	a.toolChoice = toolChoice{choice: name}
+	a.resume = true
+	return nil
+}
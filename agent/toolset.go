@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mschoch/dacs/config"
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/tools"
+)
+
+// applyTools sets the tools offered on the next inference, those of the
+// persona that were not disabled.
+func (a *Agent) applyTools() {
+	a.tools = slices.DeleteFunc(slices.Clone(a.allTools), func(t tools.Tool) bool {
+		return a.disabledTools[t.Definition.Name] || !a.personaTool(t.Definition.Name)
+	})
+}
+
+func (a *Agent) cmdTools(_ context.Context, args string) error {
+	action, name, _ := strings.Cut(strings.TrimSpace(args), " ")
+	name = strings.TrimSpace(name)
+	switch action {
+	case "":
+		for _, t := range a.allTools {
+			mark, note := " ", ""
+			switch {
+			case a.disabledTools[t.Definition.Name]:
+				note = " " + i18n.T("(disabled)")
+			case !a.personaTool(t.Definition.Name):
+				note = " " + i18n.T("(not in mode %s)", a.persona)
+			default:
+				mark = "*"
+			}
+			fmt.Printf(" %s %s%s\n", mark, t.Definition.Name, note)
+		}
+		return nil
+	case "enable", "disable":
+		if !slices.ContainsFunc(a.allTools, func(t tools.Tool) bool { return t.Definition.Name == name }) {
+			return i18n.Errorf("unknown tool %q, try /tools", name)
+		}
+		if a.disabledTools == nil {
+			a.disabledTools = map[string]bool{}
+		}
+		done := "enabled %s, %d tools\n"
+		if action == "disable" {
+			a.disabledTools[name] = true
+			done = "disabled %s, %d tools\n"
+		} else {
+			delete(a.disabledTools, name)
+		}
+		a.applyTools()
+		i18n.Printf(done, name, len(a.tools))
+		return nil
+	case "reload":
+		return a.reloadCustomTools()
+	}
+	return fmt.Errorf("usage: /tools [enable|disable NAME | reload]")
+}
+
+// reloadCustomTools replaces the custom tools with those in the config as
+// it is now, keeping the session.
+func (a *Agent) reloadCustomTools() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	toolset := slices.DeleteFunc(slices.Clone(a.allTools), func(t tools.Tool) bool {
+		return t.Namespace == tools.CustomNamespace
+	})
+	kept := len(toolset)
+	for _, def := range cfg.CustomTools {
+		if len(cfg.Tools) > 0 && !slices.Contains(cfg.Tools, def.Name) {
+			continue
+		}
+		tool, err := tools.NewCustomTool(def)
+		if err != nil {
+			return err
+		}
+		toolset = append(toolset, tool)
+	}
+	toolset, err = tools.Qualify(toolset, cfg.ToolPrecedence)
+	if err != nil {
+		return err
+	}
+	i18n.Printf("reloaded %d custom tools, %d before\n", len(toolset)-kept, len(a.allTools)-kept)
+	a.allTools = toolset
+	a.applyTools()
+	return nil
+}
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mschoch/dacs/tools"
+)
+
+// maxUnknownTools is how many calls of tools that do not exist the model
+// is corrected for in a turn, before the turn fails.
+const maxUnknownTools = 3
+
+// unknownToolError is the error of a call of a tool that does not exist,
+// qualified are the names it is offered as when another tool took its
+// name.
+type unknownToolError struct {
+	name      string
+	qualified []string
+}
+
+func (e *unknownToolError) Error() string {
+	if len(e.qualified) > 0 {
+		return fmt.Sprintf("tool %q not found, it is offered as %s", e.name, strings.Join(e.qualified, " or "))
+	}
+	return fmt.Sprintf("tool %q not found", e.name)
+}
+
+// unknownToolResult tells the model the tool it called does not exist, and
+// which tools do, with their parameters, for it to call one of them.
+func (a *Agent) unknownToolResult(err *unknownToolError) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s. Call one of these tools instead, with arguments matching its parameters:\n", err.Error())
+	for _, t := range a.tools {
+		writeToolSchema(&sb, t)
+	}
+	return sb.String()
+}
+
+// writeToolSchema writes the name, description and parameters of the tool
+// as a JSON line.
+func writeToolSchema(sb *strings.Builder, t tools.Tool) {
+	buf, err := json.Marshal(struct {
+		Name        string           `json:"name"`
+		Description string           `json:"description"`
+		Parameters  tools.Parameters `json:"parameters"`
+	}{t.Definition.Name, t.Definition.Description, t.Definition.Parameters})
+	if err != nil {
+		return
+	}
+	sb.Write(buf)
+	sb.WriteByte('\n')
+}
+
+// invalidArgumentsError is the error of a call of a tool with arguments
+// that do not match its parameters.
+type invalidArgumentsError struct {
+	tool     tools.Tool
+	problems []string
+}
+
+func (e *invalidArgumentsError) Error() string {
+	return fmt.Sprintf("invalid arguments for %s: %s", e.tool.Definition.Name, strings.Join(e.problems, ", "))
+}
+
+// result tells the model what is wrong with the arguments, and the tool's
+// parameters, for it to repeat the call with arguments repaired.
+func (e *invalidArgumentsError) result() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s was not run, its arguments are invalid:\n", e.tool.Definition.Name)
+	for _, p := range e.problems {
+		fmt.Fprintf(&sb, "- %s\n", p)
+	}
+	sb.WriteString("Call it again with arguments matching its parameters:\n")
+	writeToolSchema(&sb, e.tool)
+	return sb.String()
+}
+
+// validateArguments checks the arguments of a tool call against the tool's
+// parameters, returning what does not match.
+func validateArguments(params tools.Parameters, input json.RawMessage) []string {
+	var args map[string]any
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+	if err := dec.Decode(&args); err != nil {
+		return []string{fmt.Sprintf("the arguments are not a JSON object: %v", err)}
+	}
+
+	var problems []string
+	for _, name := range params.Required {
+		if args[name] == nil {
+			problems = append(problems, fmt.Sprintf("%s is required", name))
+		}
+	}
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		v := args[name]
+		prop, ok := params.Properties[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s is not a parameter", name))
+		case v == nil:
+			// as if not passed
+		case len(prop.Type) > 0 && !slices.ContainsFunc(prop.Type, func(t string) bool { return hasType(v, t) }):
+			problems = append(problems, fmt.Sprintf("%s must be of type %s, not %s", name, strings.Join(prop.Type, " or "), typeOf(v)))
+		case len(prop.Enum) > 0 && !slices.ContainsFunc(prop.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(v) }):
+			problems = append(problems, fmt.Sprintf("%s must be one of %v", name, prop.Enum))
+		}
+	}
+	return problems
+}
+
+// hasType reports whether the decoded JSON value is of the JSON schema
+// type.
+func hasType(v any, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	case "number", "string", "boolean", "array", "object":
+		return typeOf(v) == t
+	}
+	// not one that can be checked
+	return true
+}
+
+// typeOf returns the JSON schema type of the decoded JSON value.
+func typeOf(v any) string {
+	switch v.(type) {
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "null"
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/style"
+	"github.com/mschoch/dacs/voice"
+)
+
+// UseVoice enables /voice, transcribing microphone input with t.
+func (a *Agent) UseVoice(t *voice.Transcriber) {
+	a.transcriber = t
+}
+
+// cmdVoice records until the user presses enter and sends the transcription
+// as the user's input.
+func (a *Agent) cmdVoice(ctx context.Context, _ string) error {
+	if a.transcriber == nil {
+		return fmt.Errorf("voice input is not configured, set whisper_url")
+	}
+	rec, err := voice.Record()
+	if err != nil {
+		return err
+	}
+	defer rec.Remove()
+
+	_, ok := a.getUserMessage(style.Label(style.Red, i18n.T("recording")) + ", " + i18n.T("press enter to stop") + " ")
+	if err = rec.Stop(); err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	fmt.Println(i18n.T("transcribing..."))
+	text, err := a.transcriber.Transcribe(ctx, rec.Path)
+	if err != nil {
+		return err
+	}
+	if text == "" {
+		return i18n.Errorf("no speech recognized")
+	}
+	fmt.Printf("%s: %s\n", style.Label(style.Blue, i18n.T("You")), text)
+	a.addUserInput(ctx, text)
+	a.resume = true
+	return nil
+}
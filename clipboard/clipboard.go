@@ -0,0 +1,44 @@
+// Package clipboard copies text to the system clipboard.
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+var commands = [][]string{
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+// Copy puts text on the clipboard using the first available platform
+// utility. When none is found it falls back to the OSC 52 escape sequence
+// written to w, which most terminal emulators honor, even over ssh.
+func Copy(w io.Writer, text string) error {
+	for _, args := range platformCommands() {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("error running %s: %w", args[0], err)
+		}
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "\u001b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+func platformCommands() [][]string {
+	if runtime.GOOS == "darwin" {
+		return [][]string{{"pbcopy"}}
+	}
+	return commands
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mschoch/dacs/agent"
+	"github.com/mschoch/dacs/config"
+	"github.com/mschoch/dacs/provider"
+	"github.com/mschoch/dacs/schedule"
+)
+
+const (
+	completionUsage = `usage:
+  dacs completion bash|zsh|fish
+
+Prints the script completing the subcommands and flags of dacs, exported
+sessions for -import and the models installed in Ollama for -model, for
+the shell. Load it with one of:
+  source <(dacs completion bash)
+  dacs completion zsh > "${fpath[1]}/_dacs"
+  dacs completion fish > ~/.config/fish/completions/dacs.fish
+
+The scripts run dacs completion complete ARGS... WORD, which prints the
+completions of WORD following ARGS.`
+
+	// completeFiles and completeDirs, printed alone, have the shell complete
+	// paths itself
+	completeFiles = ":files"
+	completeDirs  = ":dirs"
+
+	// Ollama not answering by then gets no model names rather than a hung
+	// shell
+	completionTimeout = 2 * time.Second
+)
+
+var scheduleCommands = []string{"add", "list", "remove", "run"}
+
+// commandFlags are the flags of dacs itself, under "", and of each
+// subcommand.
+var commandFlags = map[string][]string{
+	"":             {"-p", "-format", "-persona", "-dry-run", "-no-color", "-import"},
+	"setup":        {"-host", "-yes"},
+	"doctor":       {"-model"},
+	"schedule add": {"-dir"},
+	"schedule run": {"-reports"},
+	"watch":        {"-interval"},
+	"gen-tests":    {"-retries"},
+	"docs":         {"-yes"},
+	"stats":        {"-days"},
+}
+
+// flagValues are the flags taking a value, and what completes it, nothing
+// when empty.
+var flagValues = map[string]string{
+	"-p":        "",
+	"-format":   completeFiles,
+	"-persona":  "personas",
+	"-import":   "sessions",
+	"-host":     "",
+	"-model":    "models",
+	"-dir":      completeDirs,
+	"-reports":  completeDirs,
+	"-interval": "",
+	"-retries":  "",
+	"-days":     "",
+}
+
+func runCompletion(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", completionUsage)
+	}
+	switch args[0] {
+	case "bash":
+		_, err := os.Stdout.WriteString(bashCompletion)
+		return err
+	case "zsh":
+		_, err := os.Stdout.WriteString(zshCompletion)
+		return err
+	case "fish":
+		_, err := os.Stdout.WriteString(fishCompletion)
+		return err
+	case "complete":
+		if len(args) < 2 {
+			return fmt.Errorf("%s", completionUsage)
+		}
+		for _, c := range complete(ctx, args[1:len(args)-1], args[len(args)-1]) {
+			fmt.Println(c)
+		}
+	default:
+		return fmt.Errorf("unknown shell %q\n%s", args[0], completionUsage)
+	}
+	return nil
+}
+
+// complete returns the completions of word following the arguments args,
+// or completeFiles or completeDirs alone.
+func complete(ctx context.Context, args []string, word string) []string {
+	command := ""
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if strings.HasPrefix(a, "-") {
+			if _, ok := flagValues[a]; ok {
+				i++
+			}
+			continue
+		}
+		positional = append(positional, a)
+		switch {
+		case command == "" && subcommands[a] != nil:
+			command = a
+		case command == "schedule" && slices.Contains(scheduleCommands, a):
+			command += " " + a
+		}
+	}
+
+	if n := len(args); n > 0 {
+		if kind, ok := flagValues[args[n-1]]; ok {
+			return values(ctx, kind, word)
+		}
+	}
+	if strings.HasPrefix(word, "-") {
+		return matching(commandFlags[command], word)
+	}
+	switch command {
+	case "":
+		if len(positional) == 0 {
+			return matching(slices.Sorted(maps.Keys(subcommands)), word)
+		}
+	case "schedule":
+		return matching(scheduleCommands, word)
+	case "schedule remove":
+		return values(ctx, "tasks", word)
+	case "completion":
+		if len(positional) == 1 {
+			return matching([]string{"bash", "zsh", "fish"}, word)
+		}
+	case "gen-tests", "decrypt":
+		return []string{completeFiles}
+	case "docs":
+		return []string{completeDirs}
+	}
+	return nil
+}
+
+// values returns the completions of a flag value of the kind.
+func values(ctx context.Context, kind, word string) []string {
+	var rv []string
+	switch kind {
+	case completeFiles, completeDirs:
+		return []string{kind}
+	case "personas":
+		rv = append(slices.Sorted(maps.Keys(agent.DefaultPersonas)), "default")
+		if cfg, err := config.Load(); err == nil {
+			rv = append(rv, slices.Sorted(maps.Keys(cfg.Personas))...)
+		}
+	case "sessions":
+		// exported sessions, the newest first, or any file
+		paths, _ := filepath.Glob(filepath.Join(filepath.Dir(word), "dacs-session-*.json"))
+		for i := len(paths) - 1; i >= 0; i-- {
+			rv = append(rv, paths[i])
+		}
+		if len(matching(rv, word)) == 0 {
+			return []string{completeFiles}
+		}
+	case "models":
+		cfg, err := config.Load()
+		if err != nil {
+			return nil
+		}
+		client, err := provider.NewOllama(cfg.OllamaHost, ollamaOptions(cfg))
+		if err != nil {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+		defer cancel()
+		list, err := client.List(ctx)
+		if err != nil {
+			return nil
+		}
+		for _, m := range list.Models {
+			rv = append(rv, m.Name)
+		}
+	case "tasks":
+		path, err := schedule.Path()
+		if err != nil {
+			return nil
+		}
+		tasks, _ := schedule.Load(path)
+		for _, t := range tasks {
+			rv = append(rv, strconv.Itoa(t.ID))
+		}
+	}
+	return matching(rv, word)
+}
+
+// matching returns the candidates starting with prefix.
+func matching(candidates []string, prefix string) []string {
+	var rv []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) && !slices.Contains(rv, c) {
+			rv = append(rv, c)
+		}
+	}
+	return rv
+}
+
+// bashCompletion splits the line at spaces itself, bash would split model
+// names at their colons too.
+const bashCompletion = `# bash completion for dacs, load with: source <(dacs completion bash)
+_dacs() {
+    local line=${COMP_LINE:0:COMP_POINT}
+    local -a words
+    read -ra words <<<"$line"
+    [[ $line == *[[:space:]] || ${#words[@]} -eq 0 ]] && words+=("")
+    local word=${words[${#words[@]}-1]}
+    local IFS=$'\n'
+    local -a out=($(dacs completion complete "${words[@]:1:${#words[@]}-2}" "$word" 2>/dev/null))
+    case ${out[0]} in
+    :files) COMPREPLY=($(compgen -f -- "${COMP_WORDS[COMP_CWORD]}")); compopt -o filenames ;;
+    :dirs) COMPREPLY=($(compgen -d -- "${COMP_WORDS[COMP_CWORD]}")); compopt -o filenames ;;
+    *)
+        # bash only replaces what follows the last colon
+        local colons=${word%"${word##*:}"}
+        COMPREPLY=("${out[@]#"$colons"}")
+        ;;
+    esac
+}
+complete -F _dacs dacs
+`
+
+const zshCompletion = `#compdef dacs
+# zsh completion for dacs, install with: dacs completion zsh > "${fpath[1]}/_dacs"
+_dacs() {
+    local -a out
+    out=(${(f)"$(dacs completion complete "${(@)words[2,CURRENT-1]}" "${words[CURRENT]}" 2>/dev/null)"})
+    case ${out[1]} in
+    :files) _files ;;
+    :dirs) _files -/ ;;
+    *) compadd -- "${out[@]}" ;;
+    esac
+}
+if [[ $funcstack[1] == _dacs ]]; then
+    _dacs "$@"
+else
+    compdef _dacs dacs
+fi
+`
+
+const fishCompletion = `# fish completion for dacs, install with: dacs completion fish > ~/.config/fish/completions/dacs.fish
+function __dacs_complete
+    set -l args (commandline -opc)[2..-1]
+    set -l word (commandline -ct)
+    set -l out (dacs completion complete $args "$word" 2>/dev/null)
+    switch "$out[1]"
+        case :files
+            __fish_complete_path "$word"
+        case :dirs
+            __fish_complete_directories "$word"
+        case '*'
+            printf '%s\n' $out
+    end
+end
+complete -c dacs -f -a '(__dacs_complete)'
+`
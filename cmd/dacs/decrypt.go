@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mschoch/dacs/crypt"
+)
+
+const decryptUsage = `usage:
+  dacs decrypt FILE
+
+Prints an exported session or report encrypted with encrypt: true, using
+the passphrase from $DACS_PASSPHRASE, the OS keychain or the terminal.`
+
+func runDecrypt(_ context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%s", decryptUsage)
+	}
+	buf, err := crypt.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(buf)
+	return err
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mschoch/dacs/docs"
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/style"
+	"github.com/mschoch/dacs/tools"
+)
+
+const (
+	docsUsage = `usage:
+  dacs docs [-yes] [DIR]
+
+Finds exported symbols without doc comments in the Go files below DIR
+(default the working directory), has the documenter persona draft them and
+shows the changes to each file for approval, or applies them with -yes.`
+
+	docsPrompt = "Write doc comments for these exported symbols in %s, whose content is attached below:\n%s\nRead other files where needed to understand what they do."
+
+	docsSchema = `{
+  "type": "object",
+  "properties": {
+    "comments": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "symbol": {"type": "string"},
+          "comment": {"type": "string"}
+        },
+        "required": ["symbol", "comment"]
+      }
+    }
+  },
+  "required": ["comments"]
+}`
+)
+
+func runDocs(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("docs", flag.ExitOnError)
+	yes := flags.Bool("yes", false, "apply the comments without asking")
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), docsUsage) }
+	_ = flags.Parse(args)
+	if flags.NArg() > 1 {
+		return fmt.Errorf("%s", docsUsage)
+	}
+	root := "."
+	if flags.NArg() == 1 {
+		root = flags.Arg(0)
+	}
+	files, err := docs.Missing(root)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println("all exported symbols are documented")
+		return nil
+	}
+
+	schema, err := os.CreateTemp("", "dacs-docs-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(schema.Name())
+	if _, err = schema.WriteString(docsSchema); err != nil {
+		return err
+	}
+	if err = schema.Close(); err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	answers := bufio.NewReader(os.Stdin)
+	for _, f := range files {
+		src, err := os.ReadFile(f.Path)
+		if err != nil {
+			return err
+		}
+		var list strings.Builder
+		for _, s := range f.Symbols {
+			fmt.Fprintf(&list, "- %s, line %d: %s\n", s.Name, s.Line, s.Decl)
+		}
+		fmt.Printf("%s: %d undocumented\n", style.Color(style.Yellow, f.Path), len(f.Symbols))
+
+		// the transcript goes to stderr, the comments to stdout
+		var out bytes.Buffer
+		cmd := exec.CommandContext(ctx, self, "-persona", "documenter", "-format", schema.Name(), "-p", fmt.Sprintf(docsPrompt, f.Path, list.String()))
+		cmd.Stdin = bytes.NewReader(src)
+		cmd.Stdout = &out
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		var exitErr *exec.ExitError
+		if err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() == exitError) {
+			return fmt.Errorf("drafting comments for %s failed: %v", f.Path, err)
+		}
+		var drafted struct {
+			Comments []struct {
+				Symbol  string `json:"symbol"`
+				Comment string `json:"comment"`
+			} `json:"comments"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &drafted); err != nil {
+			return fmt.Errorf("invalid comments for %s: %v", f.Path, err)
+		}
+		comments := make(map[string]string)
+		for _, c := range drafted.Comments {
+			comments[c.Symbol] = c.Comment
+		}
+
+		updated, err := docs.Insert(src, f.Symbols, comments)
+		if err != nil {
+			return fmt.Errorf("inserting comments in %s: %v", f.Path, err)
+		}
+		if bytes.Equal(src, updated) {
+			fmt.Println("no comments drafted")
+			continue
+		}
+		fmt.Print(tools.UnifiedDiff(f.Path, string(src), string(updated)))
+		if !*yes {
+			fmt.Printf("%s %s: ", style.Label(style.Blue, i18n.T("Apply?")), i18n.T("[y/N/q]"))
+			answer, err := answers.ReadString('\n')
+			if err != nil && answer == "" {
+				return nil
+			}
+			switch i18n.Answer(answer) {
+			case "y":
+			case "q":
+				return nil
+			default:
+				continue
+			}
+		}
+		if err := os.WriteFile(f.Path, updated, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
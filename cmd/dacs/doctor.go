@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/config"
+	"github.com/mschoch/dacs/provider"
+	"github.com/mschoch/dacs/style"
+	"github.com/mschoch/dacs/tools"
+)
+
+const doctorUsage = `usage:
+  dacs doctor [-model MODEL]
+
+Runs scripted conversations with the model (default the configured one) to
+check that it calls tools correctly, and reports each check as passed or
+failed with the reason.`
+
+// doctorCheck is a capability the agent relies on, tried with a scripted
+// conversation.
+type doctorCheck struct {
+	name string
+	run  func(ctx context.Context, p provider.Provider, model string) error
+}
+
+var setAlarmTool = api.ToolFunction{
+	Name:        "set_alarm",
+	Description: "Set an alarm.",
+	Parameters: tools.Parameters{
+		Type:     "object",
+		Required: []string{"hour", "minute", "days", "repeat"},
+		Properties: map[string]tools.Property{
+			"hour":   {Type: api.PropertyType{"integer"}, Description: "Hour, 0-23."},
+			"minute": {Type: api.PropertyType{"integer"}, Description: "Minute, 0-59."},
+			"days":   {Type: api.PropertyType{"array"}, Items: map[string]string{"type": "string"}, Description: "Lowercase days of the week."},
+			"repeat": {Type: api.PropertyType{"boolean"}, Description: "Whether the alarm repeats every week."},
+		},
+	},
+}
+
+var doctorChecks = []doctorCheck{
+	{
+		name: "calls a tool with the right arguments",
+		run: func(ctx context.Context, p provider.Provider, model string) error {
+			_, err := probeToolCall(ctx, p, model)
+			return err
+		},
+	},
+	{
+		name: "passes typed JSON arguments",
+		run: func(ctx context.Context, p provider.Provider, model string) error {
+			msg, _, err := probeChat(ctx, p, model, []api.Message{
+				{Role: "user", Content: "Set an alarm for 7:30 on Mondays and Fridays, every week."},
+			}, setAlarmTool)
+			if err != nil {
+				return err
+			}
+			args, err := onlyCall(msg, setAlarmTool.Name)
+			if err != nil {
+				return err
+			}
+			hour, _ := args["hour"].(float64)
+			minute, _ := args["minute"].(float64)
+			repeat, _ := args["repeat"].(bool)
+			var days []string
+			list, _ := args["days"].([]any)
+			for _, d := range list {
+				s, _ := d.(string)
+				days = append(days, strings.ToLower(s))
+			}
+			slices.Sort(days)
+			if hour != 7 || minute != 30 || !repeat || !slices.Equal(days, []string{"friday", "monday"}) {
+				return fmt.Errorf("wrong or mistyped arguments %v", args)
+			}
+			return nil
+		},
+	},
+	{
+		name: "picks the right tool",
+		run: func(ctx context.Context, p provider.Provider, model string) error {
+			msg, _, err := probeChat(ctx, p, model, []api.Message{
+				{Role: "user", Content: "Which files are in the src directory?"},
+			}, tools.ReadFileDefinition.Definition, tools.ListFilesDefinition.Definition, tools.RunCommandDefinition.Definition)
+			if err != nil {
+				return err
+			}
+			args, err := onlyCall(msg, tools.ListFilesDefinition.Definition.Name)
+			if err != nil {
+				return err
+			}
+			if path, _ := args["path"].(string); strings.Trim(path, "./") != "src" {
+				return fmt.Errorf("wrong arguments %v", args)
+			}
+			return nil
+		},
+	},
+	{
+		name: "answers without tools when none are needed",
+		run: func(ctx context.Context, p provider.Provider, model string) error {
+			msg, _, err := probeChat(ctx, p, model, []api.Message{
+				{Role: "user", Content: "What is 17 + 25? Answer with the number only."},
+			}, tools.ReadFileDefinition.Definition, tools.RunCommandDefinition.Definition)
+			if err != nil {
+				return err
+			}
+			if len(msg.ToolCalls) > 0 {
+				return fmt.Errorf("called %s", msg.ToolCalls[0].Function.Name)
+			}
+			if !strings.Contains(msg.Content, "42") {
+				return fmt.Errorf("wrong answer %q", msg.Content)
+			}
+			return nil
+		},
+	},
+	{
+		name: "uses tool results",
+		run: func(ctx context.Context, p provider.Provider, model string) error {
+			msg, _, err := probeChat(ctx, p, model, []api.Message{
+				{Role: "user", Content: "Which Go version does the module in go.mod require?"},
+				{Role: "assistant", ToolCalls: []api.ToolCall{{Function: api.ToolCallFunction{
+					Name:      tools.ReadFileDefinition.Definition.Name,
+					Arguments: api.ToolCallFunctionArguments{"path": "go.mod"},
+				}}}},
+				{Role: "tool", Content: "module example.com/hello\n\ngo 1.21.4\n"},
+			}, tools.ReadFileDefinition.Definition)
+			if err != nil {
+				return err
+			}
+			if len(msg.ToolCalls) > 0 {
+				return fmt.Errorf("called %s again", msg.ToolCalls[0].Function.Name)
+			}
+			if !strings.Contains(msg.Content, "1.21") {
+				return fmt.Errorf("wrong answer %q", msg.Content)
+			}
+			return nil
+		},
+	},
+	{
+		name: "makes several tool calls at once",
+		run: func(ctx context.Context, p provider.Provider, model string) error {
+			msg, _, err := probeChat(ctx, p, model, []api.Message{
+				{Role: "user", Content: "Read the files a.txt and b.txt."},
+			}, tools.ReadFileDefinition.Definition)
+			if err != nil {
+				return err
+			}
+			var paths []string
+			for _, tc := range msg.ToolCalls {
+				path, _ := tc.Function.Arguments["path"].(string)
+				paths = append(paths, path)
+			}
+			slices.Sort(paths)
+			if !slices.Equal(paths, []string{"a.txt", "b.txt"}) {
+				return fmt.Errorf("read %v", paths)
+			}
+			return nil
+		},
+	},
+}
+
+func runDoctor(ctx context.Context, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	model := flags.String("model", cfg.ToolsLLM, "the model to check")
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), doctorUsage) }
+	_ = flags.Parse(args)
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%s", doctorUsage)
+	}
+
+	client, err := provider.NewOllama(cfg.OllamaHost, ollamaOptions(cfg))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("checking %s at %s\n", *model, provider.Redacted(cfg.OllamaHost))
+	failed := 0
+	for _, c := range doctorChecks {
+		if err := c.run(ctx, client, *model); err != nil {
+			failed++
+			fmt.Printf("%s %s: %s\n", style.Label(style.Red, "FAIL"), c.name, err.Error())
+		} else {
+			fmt.Printf("%s %s\n", style.Label(style.Green, "PASS"), c.name)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed, the model may fumble tool calls, try another with dacs setup", failed, len(doctorChecks))
+	}
+	return nil
+}
+
+// probeChat sends the conversation with the tools to the model, and returns
+// its answer and how long it took.
+func probeChat(ctx context.Context, p provider.Provider, model string, messages []api.Message, fns ...api.ToolFunction) (api.Message, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	var toolsList api.Tools
+	for _, fn := range fns {
+		toolsList = append(toolsList, api.Tool{Type: "function", Function: fn})
+	}
+	start := time.Now()
+	var res api.ChatResponse
+	stream := false
+	err := p.Chat(ctx, &api.ChatRequest{
+		Model:    model,
+		Messages: messages,
+		Tools:    toolsList,
+		Options:  map[string]any{"temperature": 0.0},
+		Stream:   &stream,
+	}, func(cr api.ChatResponse) error {
+		res = cr
+		return nil
+	})
+	return res.Message, time.Since(start), err
+}
+
+// onlyCall returns the arguments of the one call the answer must make.
+func onlyCall(msg api.Message, name string) (map[string]any, error) {
+	switch {
+	case len(msg.ToolCalls) == 0:
+		return nil, errors.New("no tool call")
+	case len(msg.ToolCalls) > 1:
+		return nil, fmt.Errorf("%d tool calls", len(msg.ToolCalls))
+	case msg.ToolCalls[0].Function.Name != name:
+		return nil, fmt.Errorf("called %s", msg.ToolCalls[0].Function.Name)
+	}
+	return msg.ToolCalls[0].Function.Arguments, nil
+}
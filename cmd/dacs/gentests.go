@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mschoch/dacs/style"
+)
+
+const (
+	genTestsUsage = `usage:
+  dacs gen-tests [-retries N] FILE|PACKAGE
+
+Writes table-driven Go tests for FILE or PACKAGE with the test-writer
+persona, then runs them and has the agent fix them until they compile and
+pass, at most N more times.`
+
+	genTestsPrompt = "Write table-driven Go tests for %s. Read the code first and cover the exported behavior, edge cases and error paths, following the conventions of any existing tests in the package. Run them with `go test %s` and fix the tests until they compile and pass."
+
+	genTestsFixPrompt = "The tests for %s do not pass yet, the output of `go test %s` is attached below. Fix the tests, not the code under test unless it is clearly a bug, and run them again until they pass."
+
+	defaultGenTestsRetries = 3
+)
+
+func runGenTests(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("gen-tests", flag.ExitOnError)
+	retries := flags.Int("retries", defaultGenTestsRetries, "how many more times to have the agent fix failing tests")
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), genTestsUsage) }
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%s", genTestsUsage)
+	}
+	target := flags.Arg(0)
+	pkg, err := testPackage(target)
+	if err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	prompt := fmt.Sprintf(genTestsPrompt, target, pkg)
+	var failure []byte
+	for attempt := 0; attempt <= *retries; attempt++ {
+		cmd := exec.CommandContext(ctx, self, "-persona", "test-writer", "-p", prompt)
+		if failure != nil {
+			cmd.Stdin = bytes.NewReader(failure)
+		}
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		var exitErr *exec.ExitError
+		if err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() == exitError) {
+			return fmt.Errorf("generating tests failed: %v", err)
+		}
+
+		// the agent's own verdict is not trusted, the tests are run again
+		out, err := exec.CommandContext(ctx, "go", "test", pkg).CombinedOutput()
+		if err == nil {
+			fmt.Println(style.Color(style.Green, fmt.Sprintf("tests for %s pass", target)))
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		failure = out
+		prompt = fmt.Sprintf(genTestsFixPrompt, target, pkg)
+		fmt.Println(style.Color(style.Yellow, fmt.Sprintf("tests for %s fail", target)))
+	}
+	return fmt.Errorf("tests for %s still fail after %d retries:\n%s", target, *retries, failure)
+}
+
+// testPackage returns the package to run go test on for a Go file or a
+// package directory or pattern.
+func testPackage(target string) (string, error) {
+	if strings.HasSuffix(target, ".go") {
+		if _, err := os.Stat(target); err != nil {
+			return "", err
+		}
+		target = filepath.Dir(target)
+	}
+	if filepath.IsAbs(target) || strings.HasPrefix(target, ".") {
+		return target, nil
+	}
+	if _, err := os.Stat(target); err == nil {
+		// a directory, not an import path
+		return "./" + filepath.ToSlash(target), nil
+	}
+	return target, nil
+}
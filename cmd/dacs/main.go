@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"golang.org/x/term"
+
+	"github.com/mschoch/dacs/agent"
+	"github.com/mschoch/dacs/config"
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/index"
+	"github.com/mschoch/dacs/injection"
+	"github.com/mschoch/dacs/lineedit"
+	"github.com/mschoch/dacs/middleware"
+	"github.com/mschoch/dacs/notify"
+	"github.com/mschoch/dacs/policy"
+	"github.com/mschoch/dacs/provider"
+	"github.com/mschoch/dacs/session"
+	"github.com/mschoch/dacs/style"
+	"github.com/mschoch/dacs/tools"
+	"github.com/mschoch/dacs/voice"
+	"github.com/mschoch/dacs/workspace"
+)
+
+// subcommands are what dacs runs in place of a session, by name, and what
+// completion offers. They are set in init, runCompletion refers to them.
+var subcommands map[string]func(ctx context.Context, args []string) error
+
+func init() {
+	subcommands = map[string]func(ctx context.Context, args []string) error{
+		"setup":      runSetup,
+		"doctor":     runDoctor,
+		"schedule":   runSchedule,
+		"watch":      runWatch,
+		"review":     runReview,
+		"gen-tests":  runGenTests,
+		"docs":       runDocs,
+		"stats":      runStats,
+		"decrypt":    runDecrypt,
+		"completion": runCompletion,
+	}
+}
+
+func main() {
+
+	ctx := context.Background()
+
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(ctx, os.Args[2:]); err != nil {
+				i18n.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	prompt := flag.String("p", "", "run the prompt non-interactively and exit")
+	format := flag.String("format", "", "with -p, a JSON schema file the final answer must match, it is printed to stdout")
+	persona := flag.String("persona", "", "persona to start in, see /mode")
+	dryRun := flag.Bool("dry-run", false, "tools report what they would change without changing anything")
+	noColor := flag.Bool("no-color", false, "plain output, without colors or other escape sequences, lines are labeled AGENT:, TOOL: and so on")
+	importPath := flag.String("import", "", "continue the conversation in the file, a dacs export or a Claude Code, Aider or markdown transcript")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if cfg.Locale != "" {
+		i18n.SetLocale(cfg.Locale)
+	}
+	if *dryRun {
+		cfg.DryRun = true
+	}
+	if *noColor || cfg.NoColor {
+		style.Plain = true
+	}
+	if *persona != "" {
+		cfg.Persona = *persona
+	}
+
+	ws, err := workspace.New(cfg.Roots)
+	if err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	client, err := provider.NewOllama(cfg.OllamaHost, ollamaOptions(cfg))
+	if err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if err := provider.Ping(ctx, client, cfg.OllamaHost); err != nil {
+		if len(cfg.Failover) == 0 {
+			i18n.Printf("Error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		// the failover backends may answer
+		fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("Warning")), err.Error())
+	}
+
+	editor := lineedit.New(os.Stdin, os.Stdout)
+	editor.Plain = style.Plain
+	if err := editor.SetMode(cfg.EditMode, cfg.Keybindings); err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	getUserMessage := func(prompt string) (string, bool) {
+		line, err := editor.ReadLine(prompt)
+		if err != nil {
+			return "", false
+		}
+		return line, true
+	}
+	if *prompt != "" {
+		// headless, there is nobody to ask
+		getUserMessage = func(string) (string, bool) {
+			return "", false
+		}
+	}
+
+	idx := index.New(client, cfg.EmbedLLM, ".")
+	if cfg.EmbedWorkers > 0 {
+		idx.Workers = cfg.EmbedWorkers
+	}
+	if cfg.EmbedBatch > 0 {
+		idx.BatchSize = cfg.EmbedBatch
+	}
+	if cfg.RerankLLM != "" {
+		idx.Reranker = index.NewReranker(client, cfg.RerankLLM)
+	}
+	// refresh a previously persisted index, re-embedding what changed
+	if _, err := os.Stat(idx.Path()); err == nil {
+		if err := idx.Build(ctx); err != nil {
+			i18n.Printf("Error: refreshing index: %s\n", err.Error())
+		}
+	}
+	summaryLLM := cfg.SummaryLLM
+	if summaryLLM == "" {
+		summaryLLM = cfg.ToolsLLM
+	}
+	toolset := append(tools.Default(), tools.NewSemanticSearch(idx), tools.NewDescribeFiles(index.NewSummaries(client, summaryLLM)))
+	if cfg.KubeContext != "" {
+		toolset = append(toolset, tools.NewKubernetes(tools.Kubernetes{Context: cfg.KubeContext, Namespace: cfg.KubeNamespace})...)
+	}
+	for _, def := range cfg.CustomTools {
+		tool, err := tools.NewCustomTool(def)
+		if err != nil {
+			i18n.Printf("Error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		toolset = append(toolset, tool)
+	}
+	toolset, err = tools.Qualify(toolset, cfg.ToolPrecedence)
+	if err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if len(cfg.Tools) > 0 {
+		toolset, err = tools.Select(toolset, cfg.Tools)
+		if err != nil {
+			i18n.Printf("Error: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+	if *prompt != "" {
+		toolset = append(toolset, tools.FinishDefinition)
+	}
+
+	chat, err := failover(cfg, client)
+	if err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	chat = provider.NewQueue(chat)
+
+	a := agent.New(chat, cfg, getUserMessage, toolset)
+	editor.Complete = a.Complete
+	a.UseIndex(idx)
+	a.UseWorkspace(ws)
+	if cfg.LogFile != "" {
+		f, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			i18n.Printf("Error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		defer f.Close()
+		logger := middleware.NewLogger(f)
+		a.UseInferenceMiddleware(logger.Inference)
+		a.UseToolMiddleware(logger.Tool)
+	}
+	rules := policy.DefaultRules
+	if len(cfg.Policy) > 0 {
+		rules = nil
+		for _, r := range cfg.Policy {
+			rules = append(rules, policy.Rule{Paths: r.Paths, Tools: r.Tools, Reason: r.Reason})
+		}
+	}
+	pol, err := policy.New(rules)
+	if err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	a.UsePolicy(pol)
+	if cfg.AuditLog != "" {
+		// inside of the policy, so only the calls performed are recorded
+		f, err := os.OpenFile(cfg.AuditLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			i18n.Printf("Error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		defer f.Close()
+		a.UseToolMiddleware(middleware.NewAuditor(f).Tool)
+	}
+	scanner, err := injection.NewScanner(cfg.InjectionMode, cfg.InjectionPatterns)
+	if err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	untrusted := injection.DefaultTools
+	if len(cfg.UntrustedTools) > 0 {
+		untrusted = cfg.UntrustedTools
+	}
+	a.UseToolMiddleware(scanner.Middleware(untrusted))
+	personas := maps.Clone(agent.DefaultPersonas)
+	for name, p := range cfg.Personas {
+		personas[name] = agent.Persona{Prompt: p.Prompt, Tools: p.Tools, Model: p.Model}
+	}
+	a.UsePersonas(personas)
+	if cfg.Persona != "" {
+		if err := a.SetPersona(cfg.Persona); err != nil {
+			i18n.Printf("Error: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+	if cfg.SystemPrompt != "" {
+		if err := a.UseSystemTemplate(cfg.SystemPrompt); err != nil {
+			i18n.Printf("Error: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+	if cfg.WhisperURL != "" {
+		transcriber := voice.NewTranscriber(cfg.WhisperURL, cfg.WhisperModel)
+		transcriber.APIKey = cfg.WhisperAPIKey
+		a.UseVoice(transcriber)
+	}
+	speaker := voice.NewSpeaker(cfg.TTSURL, cfg.TTSModel, cfg.TTSVoice)
+	speaker.APIKey = cfg.TTSAPIKey
+	a.UseSpeaker(speaker)
+	if cfg.Notify || cfg.NotifyWebhook != "" {
+		a.UseNotifier(notify.New(cfg.Notify, cfg.NotifyWebhook), time.Duration(cfg.NotifyAfter)*time.Second)
+	}
+	if *importPath != "" {
+		if err := a.Import(*importPath); err != nil {
+			i18n.Printf("Error: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+	if *prompt == "" {
+		err = a.Run(ctx)
+		if err != nil {
+			i18n.Printf("Error: %s\n", err.Error())
+		}
+		return
+	}
+
+	var schema []byte
+	stdout := os.Stdout
+	if *format != "" {
+		schema, err = os.ReadFile(*format)
+		if err != nil {
+			i18n.Printf("Error: %s\n", err.Error())
+			os.Exit(exitError)
+		}
+		// keep stdout for the answer alone, the transcript goes to stderr
+		os.Stdout = os.Stderr
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		input, err := readStdin(maxStdinBytes)
+		if err != nil {
+			i18n.Printf("Error: reading stdin: %s\n", err.Error())
+			os.Exit(exitError)
+		}
+		if strings.TrimSpace(input) != "" {
+			a.Attach("this input on stdin", input)
+		}
+	}
+	err = a.RunPrompt(ctx, *prompt)
+	if err != nil {
+		i18n.Printf("Error: %s\n", err.Error())
+		os.Exit(exitError)
+	}
+	if schema != nil {
+		answer, err := a.StructuredAnswer(ctx, schema)
+		if err != nil {
+			i18n.Printf("Error: %s\n", err.Error())
+			os.Exit(exitError)
+		}
+		fmt.Fprintln(stdout, string(answer))
+	}
+	os.Exit(exitCode(a.Session().Outcome))
+}
+
+// failover chains the configured failover backends after the Ollama host,
+// or the pool of it and the other Ollama hosts sharing the requests.
+// Without either it reconnects to the host when the connection drops.
+func failover(cfg *config.Config, client *api.Client) (provider.Provider, error) {
+	if len(cfg.Failover) == 0 && len(cfg.OllamaHosts) == 0 {
+		return provider.NewReconnecting(client, cfg.OllamaHost), nil
+	}
+	var primary provider.Provider = client
+	if len(cfg.OllamaHosts) > 0 {
+		backends := []provider.Backend{{Name: provider.Redacted(cfg.OllamaHost), Provider: client}}
+		for _, host := range cfg.OllamaHosts {
+			c, err := provider.NewOllama(host, ollamaOptions(cfg))
+			if err != nil {
+				return nil, err
+			}
+			backends = append(backends, provider.Backend{Name: provider.Redacted(host), Provider: c})
+		}
+		pool, err := provider.NewBalancer(cfg.Balance, backends...)
+		if err != nil {
+			return nil, err
+		}
+		if len(cfg.Failover) == 0 {
+			return pool, nil
+		}
+		primary = pool
+	}
+	f := provider.NewFailover(provider.Backend{Name: provider.Redacted(cfg.OllamaHost), Provider: primary})
+	for _, b := range cfg.Failover {
+		p, err := provider.New(b.Type, b.URL, b.APIKey)
+		if err != nil {
+			return nil, err
+		}
+		name := b.Name
+		if name == "" {
+			name = b.URL
+		}
+		f.Backends = append(f.Backends, provider.Backend{Name: name, Provider: p, Model: b.Model})
+	}
+	if cfg.FailoverAttempts > 0 {
+		f.Attempts = cfg.FailoverAttempts
+	}
+	f.Timeout = time.Duration(cfg.FailoverTimeout) * time.Second
+	return f, nil
+}
+
+// ollamaOptions are how to connect to the Ollama host.
+func ollamaOptions(cfg *config.Config) provider.HTTPOptions {
+	return provider.HTTPOptions{
+		CA:      cfg.OllamaCA,
+		Cert:    cfg.OllamaCert,
+		Key:     cfg.OllamaCertKey,
+		Proxy:   cfg.OllamaProxy,
+		Headers: cfg.OllamaHeaders,
+		APIKey:  cfg.OllamaAPIKey,
+	}
+}
+
+// maxStdinBytes bounds what is attached from stdin, the end is kept as it
+// is usually the relevant part, e.g. of a log
+const maxStdinBytes = 128 * 1024
+
+func readStdin(limit int) (string, error) {
+	buf, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	if len(buf) > limit {
+		return fmt.Sprintf("[first %d bytes omitted]\n%s", len(buf)-limit, buf[len(buf)-limit:]), nil
+	}
+	return string(buf), nil
+}
+
+// exit codes of headless runs
+const (
+	exitSuccess   = 0
+	exitFailure   = 1
+	exitNoOutcome = 2
+	exitError     = 3
+)
+
+// exitCode maps the outcome the model reported to the exit code of a
+// headless run.
+func exitCode(outcome *session.Outcome) int {
+	switch {
+	case outcome == nil:
+		fmt.Println(i18n.T("the agent did not report an outcome"))
+		return exitNoOutcome
+	case outcome.Success:
+		i18n.Printf("succeeded: %s\n", outcome.Summary)
+		return exitSuccess
+	default:
+		i18n.Printf("failed: %s\n", outcome.Summary)
+		return exitFailure
+	}
+}
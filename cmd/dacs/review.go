@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	reviewUsage = `usage:
+  dacs review [REF]
+
+Reviews the changes between REF and HEAD, or the uncommitted changes when
+REF is omitted, with the reviewer persona and prints the comments as
+path:line: severity: comment.`
+
+	reviewPrompt = "Review the changes in the diff attached below. Read the changed files where more context is needed. Report bugs, risky changes, missing tests and unclear code, each tied to the file and the line in the new version it concerns. When done, finish with success if the changes look good and failure if any comment is an error."
+
+	reviewSchema = `{
+  "type": "object",
+  "properties": {
+    "comments": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "path": {"type": "string"},
+          "line": {"type": "integer"},
+          "severity": {"type": "string", "enum": ["error", "warning", "suggestion"]},
+          "comment": {"type": "string"}
+        },
+        "required": ["path", "line", "severity", "comment"]
+      }
+    }
+  },
+  "required": ["comments"]
+}`
+)
+
+type reviewComment struct {
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Comment  string `json:"comment"`
+}
+
+func runReview(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("review", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), reviewUsage) }
+	_ = flags.Parse(args)
+	if flags.NArg() > 1 {
+		return fmt.Errorf("%s", reviewUsage)
+	}
+	diffArgs := []string{"diff", "HEAD"}
+	if flags.NArg() == 1 {
+		// like a pull request, changes since the branches diverged
+		diffArgs = []string{"diff", flags.Arg(0) + "...HEAD"}
+	}
+	diff, err := exec.CommandContext(ctx, "git", diffArgs...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("git diff: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return err
+	}
+	if len(bytes.TrimSpace(diff)) == 0 {
+		fmt.Println("no changes to review")
+		return nil
+	}
+	if len(diff) > maxStdinBytes {
+		// the child keeps only the end of its stdin, the review would
+		// silently miss the start of the diff
+		return fmt.Errorf("the diff is %d bytes, more than the %d a review can take, review fewer changes at a time", len(diff), maxStdinBytes)
+	}
+
+	schema, err := os.CreateTemp("", "dacs-review-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(schema.Name())
+	if _, err = schema.WriteString(reviewSchema); err != nil {
+		return err
+	}
+	if err = schema.Close(); err != nil {
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// the transcript goes to stderr, the comments to stdout
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, self, "-persona", "reviewer", "-format", schema.Name(), "-p", reviewPrompt)
+	cmd.Stdin = bytes.NewReader(diff)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() == exitError) {
+		return fmt.Errorf("review failed: %v", err)
+	}
+
+	var review struct {
+		Comments []reviewComment `json:"comments"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &review); err != nil {
+		return fmt.Errorf("invalid review: %v", err)
+	}
+	if len(review.Comments) == 0 {
+		fmt.Println("no comments")
+	}
+	for _, c := range review.Comments {
+		fmt.Printf("%s:%d: %s: %s\n", c.Path, c.Line, c.Severity, c.Comment)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+
+	"github.com/mschoch/dacs/config"
+	"github.com/mschoch/dacs/crypt"
+	"github.com/mschoch/dacs/schedule"
+)
+
+const scheduleUsage = `usage:
+  dacs schedule add [-dir DIR] CRON PROMPT   run PROMPT in DIR (default the working directory) on the CRON schedule
+  dacs schedule list                         list the scheduled tasks
+  dacs schedule remove ID                    remove a scheduled task
+  dacs schedule run [-reports DIR]           run the tasks as they are due, writing reports to DIR`
+
+func runSchedule(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", scheduleUsage)
+	}
+	path, err := schedule.Path()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "add":
+		flags := flag.NewFlagSet("schedule add", flag.ExitOnError)
+		dir := flags.String("dir", ".", "directory to run the prompt in")
+		_ = flags.Parse(args[1:])
+		if flags.NArg() != 2 {
+			return fmt.Errorf("%s", scheduleUsage)
+		}
+		absDir, err := filepath.Abs(*dir)
+		if err != nil {
+			return err
+		}
+		task, err := schedule.Add(path, schedule.Task{
+			Cron:   flags.Arg(0),
+			Prompt: flags.Arg(1),
+			Dir:    absDir,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("added task %d\n", task.ID)
+	case "list":
+		tasks, err := schedule.Load(path)
+		if err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			fmt.Println("no scheduled tasks")
+		}
+		for _, t := range tasks {
+			fmt.Printf("%3d  %-15s %s\n     %s\n", t.ID, t.Cron, t.Dir, t.Prompt)
+		}
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("%s", scheduleUsage)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid task id %q", args[1])
+		}
+		if err = schedule.Remove(path, id); err != nil {
+			return err
+		}
+		fmt.Printf("removed task %d\n", id)
+	case "run":
+		flags := flag.NewFlagSet("schedule run", flag.ExitOnError)
+		reports := flags.String("reports", defaultReports(), "directory the reports are written to")
+		_ = flags.Parse(args[1:])
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		var env []string
+		if cfg.Encrypt {
+			// there may be nobody to ask once tasks run, so the runs get
+			// the passphrase from the environment
+			passphrase, err := crypt.Passphrase()
+			if err != nil {
+				return err
+			}
+			env = append(os.Environ(), "DACS_PASSPHRASE="+passphrase)
+		}
+		self, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+		fmt.Printf("running scheduled tasks from %s, reports in %s\n", path, *reports)
+		return schedule.Run(ctx, path, *reports, cfg.Encrypt, func(ctx context.Context, task schedule.Task, w io.Writer) error {
+			cmd := exec.CommandContext(ctx, self, "-p", task.Prompt)
+			cmd.Dir = task.Dir
+			cmd.Env = env
+			cmd.Stdout = w
+			cmd.Stderr = w
+			return cmd.Run()
+		})
+	default:
+		return fmt.Errorf("unknown schedule command %q\n%s", args[0], scheduleUsage)
+	}
+	return nil
+}
+
+func defaultReports() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "dacs-reports"
+	}
+	return filepath.Join(dir, "dacs", "reports")
+}
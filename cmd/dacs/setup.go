@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/format"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mschoch/dacs/config"
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/provider"
+	"github.com/mschoch/dacs/style"
+	"github.com/mschoch/dacs/tools"
+)
+
+const (
+	setupUsage = `usage:
+  dacs setup [-host URL] [-yes]
+
+Checks that Ollama answers at URL (default the configured host), tries a
+tool call round trip with every installed model, and writes the host and
+the recommended models to the config file, after asking unless -yes.`
+
+	// a round trip slower than this makes for a sluggish agent, the largest
+	// model within it is recommended
+	setupMaxRoundTrip = 20 * time.Second
+	// a model not answering by then is given up on
+	probeTimeout = 2 * time.Minute
+)
+
+func runSetup(ctx context.Context, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("setup", flag.ExitOnError)
+	host := flags.String("host", cfg.OllamaHost, "the Ollama API endpoint")
+	yes := flags.Bool("yes", false, "write the config without asking")
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), setupUsage) }
+	_ = flags.Parse(args)
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%s", setupUsage)
+	}
+
+	client, err := provider.NewOllama(*host, ollamaOptions(cfg))
+	if err != nil {
+		return err
+	}
+	version, err := client.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("no answer from Ollama at %s, is it running? %v", provider.Redacted(*host), err)
+	}
+	fmt.Printf("Ollama %s at %s\n", version, provider.Redacted(*host))
+	list, err := client.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(list.Models) == 0 {
+		return fmt.Errorf("no models installed, pull one first, e.g. ollama pull %s", config.DefaultToolsLLM)
+	}
+
+	type result struct {
+		model     api.ListModelResponse
+		roundTrip time.Duration
+		err       error
+	}
+	var results []result
+	embedLLM := ""
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tSIZE\tTOOL CALL\tROUND TRIP")
+	for _, m := range list.Models {
+		if strings.Contains(m.Name, "embed") {
+			if embedLLM == "" || provider.SameModel(m.Name, config.DefaultEmbedLLM) {
+				embedLLM = m.Name
+			}
+			continue
+		}
+		style.Progress(fmt.Sprintf("trying %s...", m.Name))
+		roundTrip, err := probeToolCall(ctx, client, m.Name)
+		results = append(results, result{m, roundTrip, err})
+		status := "ok"
+		if err != nil {
+			status = "fails: " + err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.Name, format.HumanBytes(m.Size), status, roundTrip.Round(100*time.Millisecond))
+	}
+	style.EndProgress(false)
+	w.Flush()
+
+	// the largest model answering fast enough, or else the fastest
+	sort.Slice(results, func(i, j int) bool { return results[i].model.Size > results[j].model.Size })
+	var best *result
+	for i, r := range results {
+		if r.err != nil {
+			continue
+		}
+		if r.roundTrip <= setupMaxRoundTrip {
+			best = &results[i]
+			break
+		}
+		if best == nil || r.roundTrip < best.roundTrip {
+			best = &results[i]
+		}
+	}
+	if best == nil {
+		return fmt.Errorf("none of the models called the tool correctly, pull one that supports tools, e.g. ollama pull %s", config.DefaultToolsLLM)
+	}
+
+	settings := [][2]string{{"ollama_host", *host}, {"tools_llm", best.model.Name}}
+	if embedLLM != "" {
+		settings = append(settings, [2]string{"embed_llm", embedLLM})
+	} else {
+		fmt.Printf("no embedding model for the semantic index, pull one with: ollama pull %s\n", config.DefaultEmbedLLM)
+	}
+	path := config.Path()
+	if path == "" {
+		return fmt.Errorf("no config directory")
+	}
+	fmt.Printf("recommended settings for %s:\n", path)
+	for _, s := range settings {
+		fmt.Printf("  %s: %s\n", s[0], s[1])
+	}
+	if !*yes {
+		fmt.Printf("%s %s: ", style.Label(style.Blue, i18n.T("Write?")), i18n.T("[y/N]"))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if i18n.Answer(answer) != "y" {
+			return nil
+		}
+	}
+	return writeSettings(path, settings)
+}
+
+// probeToolCall asks the model to read a file with read_file, and returns
+// how long it took, or why the call was not right.
+func probeToolCall(ctx context.Context, p provider.Provider, model string) (time.Duration, error) {
+	msg, elapsed, err := probeChat(ctx, p, model, []api.Message{
+		{Role: "user", Content: "Read the file go.mod, using the read_file tool."},
+	}, tools.ReadFileDefinition.Definition)
+	if err != nil {
+		return elapsed, err
+	}
+	args, err := onlyCall(msg, tools.ReadFileDefinition.Definition.Name)
+	if err != nil {
+		return elapsed, err
+	}
+	if path, _ := args["path"].(string); path != "go.mod" {
+		return elapsed, fmt.Errorf("wrong arguments %v", args)
+	}
+	return elapsed, nil
+}
+
+// writeSettings sets the top level keys in the config file, keeping the
+// rest of it, comments included.
+func writeSettings(path string, settings [][2]string) error {
+	var doc yaml.Node
+	buf, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	m := doc.Content[0]
+	if m.Kind != yaml.MappingNode {
+		return fmt.Errorf("error parsing %s: not a mapping", path)
+	}
+	for _, s := range settings {
+		set := false
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			if m.Content[i].Value == s[0] {
+				m.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Value: s[1]}
+				set = true
+			}
+		}
+		if !set {
+			m.Content = append(m.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: s[0]},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: s[1]})
+		}
+	}
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, out, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", path)
+	return nil
+}
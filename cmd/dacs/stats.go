@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mschoch/dacs/session"
+)
+
+const statsUsage = `usage:
+  dacs stats [-days N]
+
+Reports on the sessions of the last N days (default 30) from their saved
+metadata: tasks per day, tokens per model, tool failure rates and the
+average turns per task. Nothing leaves this machine.`
+
+func runStats(_ context.Context, args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	days := flags.Int("days", 30, "days to report on")
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), statsUsage) }
+	_ = flags.Parse(args)
+	if flags.NArg() > 0 || *days < 1 {
+		return fmt.Errorf("%s", statsUsage)
+	}
+
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day()-*days+1, 0, 0, 0, 0, time.Local)
+	sessions, err := session.LoadMetadata(since)
+	if err != nil {
+		return err
+	}
+	tasksPerDay := map[string]int{}
+	models := map[string]*session.ModelUsage{}
+	tools := map[string]*session.ToolStat{}
+	tasks, inferences := 0, 0
+	var duration time.Duration
+	for _, s := range sessions {
+		for _, t := range s.Tasks {
+			tasks++
+			inferences += t.Inferences
+			duration += t.Duration
+			tasksPerDay[t.Started.Local().Format(time.DateOnly)]++
+			for name, u := range t.Models {
+				if models[name] == nil {
+					models[name] = &session.ModelUsage{}
+				}
+				models[name].Inferences += u.Inferences
+				models[name].PromptTokens += u.PromptTokens
+				models[name].EvalTokens += u.EvalTokens
+			}
+			for name, stat := range t.Tools {
+				if tools[name] == nil {
+					tools[name] = &session.ToolStat{}
+				}
+				tools[name].Calls += stat.Calls
+				tools[name].Failures += stat.Failures
+			}
+		}
+	}
+	if tasks == 0 {
+		fmt.Printf("no tasks in the last %d days\n", *days)
+		return nil
+	}
+
+	fmt.Printf("%d sessions, %d tasks in the last %d days, %.1f turns and %s per task on average\n\n",
+		len(sessions), tasks, *days, float64(inferences)/float64(tasks), (duration / time.Duration(tasks)).Round(time.Second))
+
+	most := 0
+	for _, n := range tasksPerDay {
+		most = max(most, n)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DAY\tTASKS\t")
+	for d := since; !d.After(now); d = d.AddDate(0, 0, 1) {
+		day := d.Format(time.DateOnly)
+		if n := tasksPerDay[day]; n > 0 {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", day, n, bar(n, most))
+		}
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "MODEL\tTURNS\tPROMPT TOKENS\tGENERATED TOKENS")
+	for _, name := range sortedKeys(models, func(u *session.ModelUsage) int { return u.PromptTokens + u.EvalTokens }) {
+		u := models[name]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", name, u.Inferences, u.PromptTokens, u.EvalTokens)
+	}
+	if len(tools) == 0 {
+		return w.Flush()
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "TOOL\tCALLS\tFAILED\tFAILURE RATE")
+	for _, name := range sortedKeys(tools, func(s *session.ToolStat) int { return s.Calls }) {
+		s := tools[name]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.0f%%\n", name, s.Calls, s.Failures, 100*float64(s.Failures)/float64(s.Calls))
+	}
+	return w.Flush()
+}
+
+// bar draws n as a bar of up to 40 characters for most.
+func bar(n, most int) string {
+	return strings.Repeat("#", max(1, 40*n/most))
+}
+
+// sortedKeys returns the keys of the map, by decreasing value of by.
+func sortedKeys[T any](m map[string]T, by func(T) int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if by(m[keys[i]]) != by(m[keys[j]]) {
+			return by(m[keys[i]]) > by(m[keys[j]])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/style"
+	"github.com/mschoch/dacs/watch"
+)
+
+const (
+	watchUsage = `usage:
+  dacs watch [-interval D] PATTERN PROMPT
+
+Runs PROMPT whenever a file matching PATTERN is created or modified. PROMPT
+is a text/template with {{.Path}}, {{.Op}} and {{.Content}}, the end of the
+changed file.`
+
+	// the end of the changed file available to the prompt template
+	watchTailBytes = 16 * 1024
+)
+
+func runWatch(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	interval := flags.Duration("interval", watch.DefaultInterval, "how often to check for changes")
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), watchUsage) }
+	_ = flags.Parse(args)
+	if flags.NArg() != 2 {
+		return fmt.Errorf("%s", watchUsage)
+	}
+	tmpl, err := template.New("prompt").Parse(flags.Arg(1))
+	if err != nil {
+		return fmt.Errorf("invalid prompt template: %v", err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	w := watch.New(".", flags.Arg(0))
+	w.Interval = *interval
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	fmt.Printf("watching %s, ctrl-c to stop\n", w.Pattern)
+	return w.Run(ctx, func(ctx context.Context, ev watch.Event) error {
+		content, err := watch.Tail(filepath.Join(w.Root, ev.Path), watchTailBytes)
+		if err != nil {
+			i18n.Printf("Error: %s\n", err.Error())
+			return nil
+		}
+		var prompt strings.Builder
+		err = tmpl.Execute(&prompt, struct {
+			watch.Event
+			Content string
+		}{ev, content})
+		if err != nil {
+			return fmt.Errorf("error rendering prompt: %v", err)
+		}
+
+		fmt.Printf("%s at %s\n", style.Color(style.Yellow, ev.Path+" "+ev.Op), time.Now().Format(time.TimeOnly))
+		cmd := exec.CommandContext(ctx, self, "-p", prompt.String())
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err = cmd.Run(); err != nil && ctx.Err() == nil {
+			i18n.Printf("Error: %s\n", err.Error())
+		}
+		return nil
+	})
+}
@@ -0,0 +1,216 @@
+// Package crypt encrypts the files dacs persists, such as exported sessions
+// and scheduled run reports, which can contain proprietary code and secrets.
+// Files are encrypted with AES-256-GCM, with a key derived from a
+// passphrase with PBKDF2.
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/mschoch/dacs/i18n"
+)
+
+const (
+	// magic starts every encrypted file, so they can be told apart from
+	// plain ones
+	magic = "dacs-encrypted-v1\n"
+
+	saltSize   = 16
+	iterations = 600000
+
+	// KeychainService is the name the passphrase is stored under in the OS
+	// keychain.
+	KeychainService = "dacs"
+)
+
+// IsEncrypted reports whether the data was written by Encrypt.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(magic))
+}
+
+// sessionKey is the key derived for Encrypt, with its salt, so a session
+// autosaving after every call runs PBKDF2 once rather than on every save.
+var sessionKey struct {
+	sync.Mutex
+	passphrase string
+	salt       []byte
+	aead       cipher.AEAD
+}
+
+// Encrypt encrypts the data with a key derived from the passphrase and a
+// random salt, once per process, and a fresh random nonce.
+func Encrypt(data []byte, passphrase string) ([]byte, error) {
+	salt, aead, err := sessionAEAD(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	rv := append([]byte(magic), salt...)
+	rv = append(rv, nonce...)
+	return aead.Seal(rv, nonce, data, []byte(magic)), nil
+}
+
+func sessionAEAD(passphrase string) ([]byte, cipher.AEAD, error) {
+	sessionKey.Lock()
+	defer sessionKey.Unlock()
+	if sessionKey.aead == nil || sessionKey.passphrase != passphrase {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, nil, err
+		}
+		aead, err := newAEAD(passphrase, salt)
+		if err != nil {
+			return nil, nil, err
+		}
+		sessionKey.passphrase, sessionKey.salt, sessionKey.aead = passphrase, salt, aead
+	}
+	return sessionKey.salt, sessionKey.aead, nil
+}
+
+// Decrypt decrypts data written by Encrypt.
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, errors.New("not encrypted by dacs")
+	}
+	data = data[len(magic):]
+	if len(data) < saltSize {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	aead, err := newAEAD(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	rv, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(magic))
+	if err != nil {
+		return nil, errors.New("wrong passphrase, or the data was modified")
+	}
+	return rv, nil
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadFile reads the file, decrypting it when it is encrypted.
+func ReadFile(path string) ([]byte, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil || !IsEncrypted(buf) {
+		return buf, err
+	}
+	passphrase, err := Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	buf, err = Decrypt(buf, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s: %w", path, err)
+	}
+	return buf, nil
+}
+
+// WriteFile writes the data to the file, encrypted when encrypt is set.
+func WriteFile(path string, data []byte, perm os.FileMode, encrypt bool) error {
+	if encrypt {
+		passphrase, err := Passphrase()
+		if err != nil {
+			return err
+		}
+		data, err = Encrypt(data, passphrase)
+		if err != nil {
+			return err
+		}
+		perm = 0600
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+var passphrase struct {
+	once  sync.Once
+	value string
+	err   error
+}
+
+// Passphrase returns the passphrase files are encrypted with, from
+// $DACS_PASSPHRASE, the OS keychain (the generic password of the dacs
+// service with the macOS security tool, or libsecret's secret-tool
+// elsewhere), or else asked for on the terminal. It is looked up once.
+func Passphrase() (string, error) {
+	passphrase.once.Do(func() {
+		if v := os.Getenv("DACS_PASSPHRASE"); v != "" {
+			passphrase.value = v
+			return
+		}
+		if v := keychain(); v != "" {
+			passphrase.value = v
+			return
+		}
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			passphrase.err = errors.New("no passphrase to encrypt with, set DACS_PASSPHRASE or store one in the keychain under the service dacs")
+			return
+		}
+		fmt.Fprint(os.Stderr, i18n.T("Passphrase: "))
+		buf, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err == nil && len(buf) == 0 {
+			err = errors.New("empty passphrase")
+		}
+		passphrase.value, passphrase.err = string(buf), err
+	})
+	return passphrase.value, passphrase.err
+}
+
+// keychain returns the passphrase stored in the OS keychain, if any.
+func keychain() string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var cmd *exec.Cmd
+	switch {
+	case runtime.GOOS == "darwin":
+		cmd = exec.CommandContext(ctx, "security", "find-generic-password", "-s", KeychainService, "-w")
+	default:
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return ""
+		}
+		cmd = exec.CommandContext(ctx, "secret-tool", "lookup", "service", KeychainService)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(string(out), "\r\n")
+}
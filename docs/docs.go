@@ -0,0 +1,176 @@
+// Package docs finds exported Go declarations without doc comments and
+// inserts drafted ones.
+package docs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Symbol is an exported declaration without a doc comment.
+type Symbol struct {
+	Name string
+	// Line is the 1-based line the declaration starts on
+	Line int
+	// Decl is the declaration's first line, e.g. its signature
+	Decl string
+}
+
+// File is a Go source file with undocumented symbols.
+type File struct {
+	Path    string
+	Symbols []Symbol
+}
+
+// Missing walks the Go files below root, skipping tests, vendored code,
+// testdata and hidden directories, and returns those with exported symbols
+// missing doc comments.
+func Missing(root string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		symbols, err := missing(path, src)
+		if err != nil {
+			return err
+		}
+		if len(symbols) > 0 {
+			files = append(files, File{Path: path, Symbols: symbols})
+		}
+		return nil
+	})
+	return files, err
+}
+
+func missing(path string, src []byte) ([]Symbol, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(src), "\n")
+	symbol := func(name string, pos token.Pos) Symbol {
+		line := fset.Position(pos).Line
+		return Symbol{Name: name, Line: line, Decl: strings.TrimSpace(lines[line-1])}
+	}
+
+	var symbols []Symbol
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil || !d.Name.IsExported() {
+				continue
+			}
+			name := d.Name.Name
+			if d.Recv != nil {
+				recv := receiverType(d.Recv.List[0].Type)
+				if !ast.IsExported(recv) {
+					continue
+				}
+				name = recv + "." + name
+			}
+			symbols = append(symbols, symbol(name, d.Pos()))
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			grouped := d.Lparen.IsValid()
+			// a group's comment documents its constants and variables
+			if d.Doc != nil && (!grouped || d.Tok != token.TYPE) {
+				continue
+			}
+			for _, spec := range d.Specs {
+				var names []*ast.Ident
+				var doc *ast.CommentGroup
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names, doc = []*ast.Ident{s.Name}, s.Doc
+				case *ast.ValueSpec:
+					names, doc = s.Names, s.Doc
+				}
+				i := slices.IndexFunc(names, (*ast.Ident).IsExported)
+				if doc != nil || i < 0 {
+					continue
+				}
+				pos := d.Pos()
+				if grouped {
+					pos = spec.Pos()
+				}
+				symbols = append(symbols, symbol(names[i].Name, pos))
+				if !grouped {
+					break
+				}
+			}
+		}
+	}
+	return symbols, nil
+}
+
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// Insert returns src with the comments, keyed by symbol name, added above
+// the symbols' declarations, indented like them. Comments for unknown
+// symbols are ignored.
+func Insert(src []byte, symbols []Symbol, comments map[string]string) ([]byte, error) {
+	lines := strings.Split(string(src), "\n")
+	// from the bottom, so the line numbers stay valid
+	sorted := slices.Clone(symbols)
+	slices.SortFunc(sorted, func(a, b Symbol) int { return b.Line - a.Line })
+	for _, s := range sorted {
+		comment := strings.TrimSpace(comments[s.Name])
+		if comment == "" {
+			continue
+		}
+		if s.Line < 1 || s.Line > len(lines) {
+			return nil, fmt.Errorf("line %d of %s out of range", s.Line, s.Name)
+		}
+		target := lines[s.Line-1]
+		indent := target[:len(target)-len(strings.TrimLeft(target, " \t"))]
+		var block []string
+		for _, l := range strings.Split(comment, "\n") {
+			l = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "//"))
+			if l == "" {
+				block = append(block, indent+"//")
+				continue
+			}
+			block = append(block, indent+"// "+l)
+		}
+		lines = slices.Insert(lines, s.Line-1, block...)
+	}
+	return format.Source([]byte(strings.Join(lines, "\n")))
+}
@@ -2,4 +2,15 @@ module github.com/mschoch/dacs
 
 go 1.24.0
 
-require github.com/ollama/ollama v0.5.11
+require (
+	github.com/alecthomas/chroma/v2 v2.20.0
+	github.com/ollama/ollama v0.9.6
+	golang.org/x/term v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/dlclark/regexp2 v1.11.5 // indirect
+	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+)
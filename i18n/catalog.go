@@ -0,0 +1,362 @@
+package i18n
+
+// catalogs are the translations by language, keyed by the English message.
+var catalogs = map[string]map[string]string{
+	"de": {
+		"You":                                   "Sie",
+		"Agent":                                 "Agent",
+		"Redirect":                              "Umlenken",
+		"(empty to stop)":                       "(leer zum Anhalten)",
+		"Continue?":                             "Fortfahren?",
+		"[Y/n or a message]":                    "[J/n oder eine Nachricht]",
+		"(paused after %d tool rounds)":         "(angehalten nach %d Werkzeugrunden)",
+		"recording":                             "Aufnahme",
+		"press enter to stop":                   "Enter beendet sie",
+		"transcribing...":                       "Transkribiere...",
+		"no speech recognized":                  "keine Sprache erkannt",
+		"Write?":                                "Schreiben?",
+		"Apply?":                                "Übernehmen?",
+		"[y/N]":                                 "[j/N]",
+		"[y/N/q]":                               "[j/N/b]",
+		"Passphrase: ":                          "Passphrase: ",
+		"Chat with %s (use 'ctrl-c' to quit)\n": "Chat mit %s ('Strg-C' beendet)\n",
+		"dry run":                               "Probelauf",
+		"tools report what they would change without changing anything": "Werkzeuge melden, was sie ändern würden, ohne etwas zu ändern",
+		"unknown command /%s, try /help":                                "unbekannter Befehl /%s, siehe /help",
+		"the agent did not report an outcome":                           "der Agent hat kein Ergebnis gemeldet",
+		"Error: %s\n":                                                   "Fehler: %s\n",
+		"Error: gathering context: %s\n":                                "Fehler beim Sammeln des Kontexts: %s\n",
+		"Error: notifying: %s\n":                                        "Fehler beim Benachrichtigen: %s\n",
+		"Error: pulling %s: %s\n":                                       "Fehler beim Herunterladen von %s: %s\n",
+		"Restore?":                                                      "Wiederherstellen?",
+		"found an unsaved session from %s with %d messages\n":           "ungesicherte Sitzung vom %s mit %d Nachrichten gefunden\n",
+		"restored %d messages\n":                                        "%d Nachrichten wiederhergestellt\n",
+		"Error: autosaving: %s\n":                                       "Fehler beim automatischen Speichern: %s\n",
+		"Error: reading stdin: %s\n":                                    "Fehler beim Lesen der Standardeingabe: %s\n",
+		"Error: refreshing index: %s\n":                                 "Fehler beim Aktualisieren des Index: %s\n",
+		"Error: rendering system prompt: %s\n":                          "Fehler beim Erstellen des Systemprompts: %s\n",
+		"Error: reviewing the change: %s\n":                             "Fehler beim Prüfen der Änderung: %s\n",
+		"Error: speaking: %s\n":                                         "Fehler bei der Sprachausgabe: %s\n",
+		"Error: task %d: %s\n":                                          "Fehler in Aufgabe %d: %s\n",
+
+		"Warning":     "Warnung",
+		"Error":       "Fehler",
+		"Interrupted": "Unterbrochen",
+		"Waiting":     "Warten",
+		"%s failed %d times (%v), failing over to %s":                                   "%s ist %d Mal fehlgeschlagen (%v), wechsle zu %s",
+		"%s does not answer (%v), trying %s":                                            "%s antwortet nicht (%v), versuche %s",
+		"lost the connection to Ollama at %s (%v), reconnecting":                        "Verbindung zu Ollama unter %s verloren (%v), verbinde neu",
+		"%s needs at least %s of memory, the system has %s, it will not fit":            "%s braucht mindestens %s Speicher, das System hat %s, es passt nicht",
+		"%.0f%% of %s (%s) is on the CPU, only %s fits in VRAM, expect slow responses":  "%.0f%% von %s (%s) laufen auf der CPU, nur %s passen in den VRAM, Antworten werden langsam sein",
+		"%d tool calls already ran, their effects on the workspace are kept":            "%d Werkzeugaufrufe liefen bereits, ihre Änderungen am Arbeitsbereich bleiben erhalten",
+		"the conversation no longer fits in the context window (%d tokens), compacting": "die Unterhaltung passt nicht mehr in das Kontextfenster (%d Token), sie wird verdichtet",
+		"%s panicked: %v":                      "%s ist abgestürzt: %v",
+		"stopping after the running tool":      "halte nach dem laufenden Werkzeug an",
+		"suspected prompt injection in %s: %q": "vermutete Prompt-Injection in %s: %q",
+
+		// /help
+		"list the available commands":                                                    "die verfügbaren Befehle auflisten",
+		"show per-tool call counts, failures and latency":                                "Aufrufe, Fehler und Latenz je Werkzeug anzeigen",
+		"toggle display of the model's reasoning, or show the last reasoning":            "die Überlegungen des Modells ein- oder ausblenden, oder die letzten anzeigen",
+		"toggle showing where the time of each turn went":                                "anzeigen, wofür die Zeit jeder Runde gebraucht wurde, ein- oder ausschalten",
+		"copy code block N (default the last) to the clipboard":                          "Codeblock N (standardmäßig den letzten) in die Zwischenablage kopieren",
+		"keep a file's current contents in the context, or list pinned files":            "den aktuellen Inhalt einer Datei im Kontext halten, oder angeheftete Dateien auflisten",
+		"stop pinning a file, or all files":                                              "eine Datei oder alle Dateien nicht mehr anheften",
+		"answer without using any tools":                                                 "ohne Werkzeuge antworten",
+		"make the model start by calling the tool":                                       "das Modell mit einem Aufruf des Werkzeugs beginnen lassen",
+		"list the tools, turn one on or off, or reload the custom tools from the config": "die Werkzeuge auflisten, eines ein- oder ausschalten, oder die eigenen Werkzeuge neu aus der Konfiguration laden",
+		"show the model, workspace, tools and policy in effect":                          "Modell, Arbeitsbereich, Werkzeuge und Richtlinie anzeigen",
+		"toggle having a reviewer check changes before they are applied":                 "Änderungen vor dem Übernehmen von einem Prüfer kontrollieren lassen, ein oder aus",
+		"plan the task as subtasks, have worker agents do them, then check the results":  "die Aufgabe in Teilaufgaben planen, von Arbeitsagenten erledigen lassen und die Ergebnisse prüfen",
+		"switch to a persona, or default, or list them":                                  "zu einer Persona oder default wechseln, oder sie auflisten",
+		"list the models available, with their size and family":                          "die verfügbaren Modelle mit Größe und Familie auflisten",
+		"download a model": "ein Modell herunterladen",
+		"drop the last response and run inference again":                                               "die letzte Antwort verwerfen und neu generieren",
+		"show the model's plan for the current task":                                                   "den Plan des Modells für die aktuelle Aufgabe anzeigen",
+		"list the messages in the conversation":                                                        "die Nachrichten der Unterhaltung auflisten",
+		"replace the content of message N, in $EDITOR when no text is given":                           "den Inhalt von Nachricht N ersetzen, in $EDITOR wenn kein Text angegeben ist",
+		"delete message N, or messages N through M":                                                    "Nachricht N oder die Nachrichten N bis M löschen",
+		"replace older messages with a summary to free up context":                                     "ältere Nachrichten durch eine Zusammenfassung ersetzen, um Kontext freizugeben",
+		"speak your input, transcribed with whisper":                                                   "die Eingabe sprechen, transkribiert mit whisper",
+		"toggle reading final answers aloud":                                                           "das Vorlesen der endgültigen Antworten ein- oder ausschalten",
+		"write the session, including tool statistics, as JSON":                                        "die Sitzung samt Werkzeugstatistik als JSON schreiben",
+		"continue a conversation exported by dacs or from a Claude Code, Aider or markdown transcript": "eine von dacs exportierte Unterhaltung oder ein Claude-Code-, Aider- oder Markdown-Protokoll fortsetzen",
+
+		"Thinking":                 "Überlegungen",
+		"session exported to %s\n": "Sitzung nach %s exportiert\n",
+		"imported %d messages from the %s transcript %s\n": "%d Nachrichten aus dem %s-Protokoll %s importiert\n",
+		"no thoughts yet":                              "noch keine Überlegungen",
+		"showing thoughts":                             "Überlegungen werden angezeigt",
+		"hiding thoughts":                              "Überlegungen werden ausgeblendet",
+		"copied code block %d\n":                       "Codeblock %d kopiert\n",
+		"no pinned files":                              "keine angehefteten Dateien",
+		"pinned %s\n":                                  "%s angeheftet\n",
+		"unpinned all files":                           "keine Dateien mehr angeheftet",
+		"unpinned %s\n":                                "%s nicht mehr angeheftet\n",
+		"%s is %d bytes, too large to pin":             "%s hat %d Bytes, zu groß zum Anheften",
+		"%s is not pinned":                             "%s ist nicht angeheftet",
+		"(disabled)":                                   "(deaktiviert)",
+		"(not in mode %s)":                             "(nicht im Modus %s)",
+		"enabled %s, %d tools\n":                       "%s aktiviert, %d Werkzeuge\n",
+		"disabled %s, %d tools\n":                      "%s deaktiviert, %d Werkzeuge\n",
+		"unknown tool %q, try /tools":                  "unbekanntes Werkzeug %q, siehe /tools",
+		"reloaded %d custom tools, %d before\n":        "%d eigene Werkzeuge neu geladen, vorher %d\n",
+		"succeeded: %s\n":                              "erfolgreich: %s\n",
+		"failed: %s\n":                                 "fehlgeschlagen: %s\n",
+		"reconnected":                                  "wieder verbunden",
+		"lost the connection to Ollama at %s: %w":      "Verbindung zu Ollama unter %s verloren: %w",
+		"dropped %d messages\n":                        "%d Nachrichten verworfen\n",
+		"edited message %d\n":                          "Nachricht %d bearbeitet\n",
+		"dropped %d messages, retrying\n":              "%d Nachrichten verworfen, neuer Versuch\n",
+		"critic":                                       "Prüfer",
+		"changes are reviewed before they are applied": "Änderungen werden vor dem Übernehmen geprüft",
+		"changes are applied without review":           "Änderungen werden ohne Prüfung übernommen",
+		"mode %s, model %s, %d tools\n":                "Modus %s, Modell %s, %d Werkzeuge\n",
+		"plan":                                         "Plan",
+		"%d subtasks":                                  "%d Teilaufgaben",
+		"subtask %d/%d":                                "Teilaufgabe %d/%d",
+		"%s, started":                                  "%s, gestartet",
+		"%s, named by the project config, is not available, download it with /pull %s": "%s, vom Projekt konfiguriert, ist nicht verfügbar, mit /pull %s herunterladen",
+	},
+	"es": {
+		"You":                                   "Tú",
+		"Agent":                                 "Agente",
+		"Redirect":                              "Redirigir",
+		"(empty to stop)":                       "(vacío para detener)",
+		"Continue?":                             "¿Continuar?",
+		"[Y/n or a message]":                    "[S/n o un mensaje]",
+		"(paused after %d tool rounds)":         "(en pausa tras %d rondas de herramientas)",
+		"recording":                             "grabando",
+		"press enter to stop":                   "pulsa intro para detener",
+		"transcribing...":                       "transcribiendo...",
+		"no speech recognized":                  "no se reconoció voz",
+		"Write?":                                "¿Escribir?",
+		"Apply?":                                "¿Aplicar?",
+		"[y/N]":                                 "[s/N]",
+		"[y/N/q]":                               "[s/N/q]",
+		"Passphrase: ":                          "Frase de contraseña: ",
+		"Chat with %s (use 'ctrl-c' to quit)\n": "Chat con %s ('ctrl-c' para salir)\n",
+		"dry run":                               "simulación",
+		"tools report what they would change without changing anything": "las herramientas informan de lo que cambiarían sin cambiar nada",
+		"unknown command /%s, try /help":                                "comando desconocido /%s, prueba /help",
+		"the agent did not report an outcome":                           "el agente no informó de un resultado",
+		"Error: %s\n":                                                   "Error: %s\n",
+		"Error: gathering context: %s\n":                                "Error al reunir el contexto: %s\n",
+		"Error: notifying: %s\n":                                        "Error al notificar: %s\n",
+		"Error: pulling %s: %s\n":                                       "Error al descargar %s: %s\n",
+		"Restore?":                                                      "¿Restaurar?",
+		"found an unsaved session from %s with %d messages\n":           "se encontró una sesión sin guardar del %s con %d mensajes\n",
+		"restored %d messages\n":                                        "%d mensajes restaurados\n",
+		"Error: autosaving: %s\n":                                       "Error al guardar automáticamente: %s\n",
+		"Error: reading stdin: %s\n":                                    "Error al leer la entrada estándar: %s\n",
+		"Error: refreshing index: %s\n":                                 "Error al actualizar el índice: %s\n",
+		"Error: rendering system prompt: %s\n":                          "Error al generar el prompt del sistema: %s\n",
+		"Error: reviewing the change: %s\n":                             "Error al revisar el cambio: %s\n",
+		"Error: speaking: %s\n":                                         "Error al hablar: %s\n",
+		"Error: task %d: %s\n":                                          "Error en la tarea %d: %s\n",
+
+		"Warning":     "Aviso",
+		"Error":       "Error",
+		"Interrupted": "Interrumpido",
+		"Waiting":     "Esperando",
+		"%s failed %d times (%v), failing over to %s":                                   "%s falló %d veces (%v), se pasa a %s",
+		"%s does not answer (%v), trying %s":                                            "%s no responde (%v), se prueba %s",
+		"lost the connection to Ollama at %s (%v), reconnecting":                        "se perdió la conexión con Ollama en %s (%v), reconectando",
+		"%s needs at least %s of memory, the system has %s, it will not fit":            "%s necesita al menos %s de memoria, el sistema tiene %s, no cabe",
+		"%.0f%% of %s (%s) is on the CPU, only %s fits in VRAM, expect slow responses":  "el %.0f%% de %s (%s) está en la CPU, solo %s cabe en la VRAM, las respuestas serán lentas",
+		"%d tool calls already ran, their effects on the workspace are kept":            "ya se ejecutaron %d llamadas a herramientas, sus efectos en el espacio de trabajo se mantienen",
+		"the conversation no longer fits in the context window (%d tokens), compacting": "la conversación ya no cabe en la ventana de contexto (%d tokens), compactando",
+		"%s panicked: %v":                      "%s entró en pánico: %v",
+		"stopping after the running tool":      "deteniendo tras la herramienta en curso",
+		"suspected prompt injection in %s: %q": "posible inyección de prompt en %s: %q",
+
+		// /help
+		"list the available commands":                                                    "listar los comandos disponibles",
+		"show per-tool call counts, failures and latency":                                "mostrar llamadas, fallos y latencia por herramienta",
+		"toggle display of the model's reasoning, or show the last reasoning":            "mostrar u ocultar el razonamiento del modelo, o mostrar el último",
+		"toggle showing where the time of each turn went":                                "mostrar u ocultar en qué se fue el tiempo de cada turno",
+		"copy code block N (default the last) to the clipboard":                          "copiar el bloque de código N (por defecto el último) al portapapeles",
+		"keep a file's current contents in the context, or list pinned files":            "mantener el contenido actual de un archivo en el contexto, o listar los archivos fijados",
+		"stop pinning a file, or all files":                                              "dejar de fijar un archivo, o todos",
+		"answer without using any tools":                                                 "responder sin usar herramientas",
+		"make the model start by calling the tool":                                       "hacer que el modelo empiece llamando a la herramienta",
+		"list the tools, turn one on or off, or reload the custom tools from the config": "listar las herramientas, activar o desactivar una, o recargar las herramientas propias de la configuración",
+		"show the model, workspace, tools and policy in effect":                          "mostrar el modelo, el espacio de trabajo, las herramientas y la política en vigor",
+		"toggle having a reviewer check changes before they are applied":                 "activar o desactivar que un revisor compruebe los cambios antes de aplicarlos",
+		"plan the task as subtasks, have worker agents do them, then check the results":  "planificar la tarea en subtareas, hacer que agentes trabajadores las realicen y comprobar los resultados",
+		"switch to a persona, or default, or list them":                                  "cambiar a una persona, o a default, o listarlas",
+		"list the models available, with their size and family":                          "listar los modelos disponibles, con su tamaño y familia",
+		"download a model": "descargar un modelo",
+		"drop the last response and run inference again":                                               "descartar la última respuesta y volver a generar",
+		"show the model's plan for the current task":                                                   "mostrar el plan del modelo para la tarea actual",
+		"list the messages in the conversation":                                                        "listar los mensajes de la conversación",
+		"replace the content of message N, in $EDITOR when no text is given":                           "reemplazar el contenido del mensaje N, en $EDITOR si no se da texto",
+		"delete message N, or messages N through M":                                                    "borrar el mensaje N, o los mensajes N a M",
+		"replace older messages with a summary to free up context":                                     "reemplazar los mensajes antiguos por un resumen para liberar contexto",
+		"speak your input, transcribed with whisper":                                                   "dictar la entrada, transcrita con whisper",
+		"toggle reading final answers aloud":                                                           "activar o desactivar la lectura en voz alta de las respuestas finales",
+		"write the session, including tool statistics, as JSON":                                        "escribir la sesión, con las estadísticas de herramientas, como JSON",
+		"continue a conversation exported by dacs or from a Claude Code, Aider or markdown transcript": "continuar una conversación exportada por dacs o una transcripción de Claude Code, Aider o markdown",
+
+		"Thinking":                 "Razonamiento",
+		"session exported to %s\n": "sesión exportada a %s\n",
+		"imported %d messages from the %s transcript %s\n": "%d mensajes importados de la transcripción %s %s\n",
+		"no thoughts yet":                              "aún no hay razonamientos",
+		"showing thoughts":                             "se muestran los razonamientos",
+		"hiding thoughts":                              "se ocultan los razonamientos",
+		"copied code block %d\n":                       "bloque de código %d copiado\n",
+		"no pinned files":                              "no hay archivos fijados",
+		"pinned %s\n":                                  "%s fijado\n",
+		"unpinned all files":                           "ya no hay archivos fijados",
+		"unpinned %s\n":                                "%s ya no está fijado\n",
+		"%s is %d bytes, too large to pin":             "%s tiene %d bytes, demasiado grande para fijarlo",
+		"%s is not pinned":                             "%s no está fijado",
+		"(disabled)":                                   "(desactivada)",
+		"(not in mode %s)":                             "(no está en el modo %s)",
+		"enabled %s, %d tools\n":                       "%s activada, %d herramientas\n",
+		"disabled %s, %d tools\n":                      "%s desactivada, %d herramientas\n",
+		"unknown tool %q, try /tools":                  "herramienta desconocida %q, prueba /tools",
+		"reloaded %d custom tools, %d before\n":        "%d herramientas propias recargadas, antes %d\n",
+		"succeeded: %s\n":                              "con éxito: %s\n",
+		"failed: %s\n":                                 "fallido: %s\n",
+		"reconnected":                                  "reconectado",
+		"lost the connection to Ollama at %s: %w":      "se perdió la conexión con Ollama en %s: %w",
+		"dropped %d messages\n":                        "%d mensajes descartados\n",
+		"edited message %d\n":                          "mensaje %d editado\n",
+		"dropped %d messages, retrying\n":              "%d mensajes descartados, reintentando\n",
+		"critic":                                       "revisor",
+		"changes are reviewed before they are applied": "los cambios se revisan antes de aplicarlos",
+		"changes are applied without review":           "los cambios se aplican sin revisión",
+		"mode %s, model %s, %d tools\n":                "modo %s, modelo %s, %d herramientas\n",
+		"plan":                                         "plan",
+		"%d subtasks":                                  "%d subtareas",
+		"subtask %d/%d":                                "subtarea %d/%d",
+		"%s, started":                                  "%s, iniciada",
+		"%s, named by the project config, is not available, download it with /pull %s": "%s, indicado por la configuración del proyecto, no está disponible, descárgalo con /pull %s",
+	},
+	"fr": {
+		"You":                                   "Vous",
+		"Agent":                                 "Agent",
+		"Redirect":                              "Réorienter",
+		"(empty to stop)":                       "(vide pour arrêter)",
+		"Continue?":                             "Continuer ?",
+		"[Y/n or a message]":                    "[O/n ou un message]",
+		"(paused after %d tool rounds)":         "(en pause après %d tours d'outils)",
+		"recording":                             "enregistrement",
+		"press enter to stop":                   "appuyez sur entrée pour arrêter",
+		"transcribing...":                       "transcription...",
+		"no speech recognized":                  "aucune parole reconnue",
+		"Write?":                                "Écrire ?",
+		"Apply?":                                "Appliquer ?",
+		"[y/N]":                                 "[o/N]",
+		"[y/N/q]":                               "[o/N/q]",
+		"Passphrase: ":                          "Phrase secrète : ",
+		"Chat with %s (use 'ctrl-c' to quit)\n": "Discussion avec %s ('ctrl-c' pour quitter)\n",
+		"dry run":                               "simulation",
+		"tools report what they would change without changing anything": "les outils indiquent ce qu'ils changeraient sans rien changer",
+		"unknown command /%s, try /help":                                "commande inconnue /%s, essayez /help",
+		"the agent did not report an outcome":                           "l'agent n'a pas indiqué de résultat",
+		"Error: %s\n":                                                   "Erreur : %s\n",
+		"Error: gathering context: %s\n":                                "Erreur lors de la collecte du contexte : %s\n",
+		"Error: notifying: %s\n":                                        "Erreur lors de la notification : %s\n",
+		"Error: pulling %s: %s\n":                                       "Erreur lors du téléchargement de %s : %s\n",
+		"Restore?":                                                      "Restaurer ?",
+		"found an unsaved session from %s with %d messages\n":           "session non enregistrée du %s avec %d messages trouvée\n",
+		"restored %d messages\n":                                        "%d messages restaurés\n",
+		"Error: autosaving: %s\n":                                       "Erreur lors de l'enregistrement automatique : %s\n",
+		"Error: reading stdin: %s\n":                                    "Erreur lors de la lecture de l'entrée standard : %s\n",
+		"Error: refreshing index: %s\n":                                 "Erreur lors de la mise à jour de l'index : %s\n",
+		"Error: rendering system prompt: %s\n":                          "Erreur lors de la génération du prompt système : %s\n",
+		"Error: reviewing the change: %s\n":                             "Erreur lors de la relecture de la modification : %s\n",
+		"Error: speaking: %s\n":                                         "Erreur de synthèse vocale : %s\n",
+		"Error: task %d: %s\n":                                          "Erreur dans la tâche %d : %s\n",
+
+		"Warning":     "Avertissement",
+		"Error":       "Erreur",
+		"Interrupted": "Interrompu",
+		"Waiting":     "En attente",
+		"%s failed %d times (%v), failing over to %s":                                   "%s a échoué %d fois (%v), bascule sur %s",
+		"%s does not answer (%v), trying %s":                                            "%s ne répond pas (%v), essai de %s",
+		"lost the connection to Ollama at %s (%v), reconnecting":                        "connexion à Ollama sur %s perdue (%v), reconnexion",
+		"%s needs at least %s of memory, the system has %s, it will not fit":            "%s a besoin d'au moins %s de mémoire, le système en a %s, il ne tiendra pas",
+		"%.0f%% of %s (%s) is on the CPU, only %s fits in VRAM, expect slow responses":  "%.0f%% de %s (%s) est sur le CPU, seuls %s tiennent en VRAM, les réponses seront lentes",
+		"%d tool calls already ran, their effects on the workspace are kept":            "%d appels d'outils ont déjà été exécutés, leurs effets sur l'espace de travail sont conservés",
+		"the conversation no longer fits in the context window (%d tokens), compacting": "la conversation ne tient plus dans la fenêtre de contexte (%d jetons), compactage",
+		"%s panicked: %v":                      "%s a paniqué : %v",
+		"stopping after the running tool":      "arrêt après l'outil en cours",
+		"suspected prompt injection in %s: %q": "injection de prompt suspectée dans %s : %q",
+
+		// /help
+		"list the available commands":                                                    "lister les commandes disponibles",
+		"show per-tool call counts, failures and latency":                                "afficher les appels, échecs et latences par outil",
+		"toggle display of the model's reasoning, or show the last reasoning":            "afficher ou masquer le raisonnement du modèle, ou afficher le dernier",
+		"toggle showing where the time of each turn went":                                "afficher ou masquer où est passé le temps de chaque tour",
+		"copy code block N (default the last) to the clipboard":                          "copier le bloc de code N (par défaut le dernier) dans le presse-papiers",
+		"keep a file's current contents in the context, or list pinned files":            "garder le contenu actuel d'un fichier dans le contexte, ou lister les fichiers épinglés",
+		"stop pinning a file, or all files":                                              "ne plus épingler un fichier, ou tous",
+		"answer without using any tools":                                                 "répondre sans utiliser d'outils",
+		"make the model start by calling the tool":                                       "faire commencer le modèle par un appel à l'outil",
+		"list the tools, turn one on or off, or reload the custom tools from the config": "lister les outils, en activer ou désactiver un, ou recharger les outils personnalisés de la configuration",
+		"show the model, workspace, tools and policy in effect":                          "afficher le modèle, l'espace de travail, les outils et la politique en vigueur",
+		"toggle having a reviewer check changes before they are applied":                 "activer ou désactiver la relecture des modifications avant leur application",
+		"plan the task as subtasks, have worker agents do them, then check the results":  "planifier la tâche en sous-tâches, les faire réaliser par des agents, puis vérifier les résultats",
+		"switch to a persona, or default, or list them":                                  "passer à une persona, ou à default, ou les lister",
+		"list the models available, with their size and family":                          "lister les modèles disponibles, avec leur taille et leur famille",
+		"download a model": "télécharger un modèle",
+		"drop the last response and run inference again":                                               "abandonner la dernière réponse et relancer l'inférence",
+		"show the model's plan for the current task":                                                   "afficher le plan du modèle pour la tâche en cours",
+		"list the messages in the conversation":                                                        "lister les messages de la conversation",
+		"replace the content of message N, in $EDITOR when no text is given":                           "remplacer le contenu du message N, dans $EDITOR sans texte donné",
+		"delete message N, or messages N through M":                                                    "supprimer le message N, ou les messages N à M",
+		"replace older messages with a summary to free up context":                                     "remplacer les anciens messages par un résumé pour libérer du contexte",
+		"speak your input, transcribed with whisper":                                                   "dicter votre saisie, transcrite avec whisper",
+		"toggle reading final answers aloud":                                                           "activer ou désactiver la lecture à voix haute des réponses finales",
+		"write the session, including tool statistics, as JSON":                                        "écrire la session, avec les statistiques des outils, en JSON",
+		"continue a conversation exported by dacs or from a Claude Code, Aider or markdown transcript": "reprendre une conversation exportée par dacs ou une transcription Claude Code, Aider ou markdown",
+
+		"Thinking":                 "Réflexion",
+		"session exported to %s\n": "session exportée vers %s\n",
+		"imported %d messages from the %s transcript %s\n": "%d messages importés de la transcription %s %s\n",
+		"no thoughts yet":                              "pas encore de réflexions",
+		"showing thoughts":                             "affichage des réflexions",
+		"hiding thoughts":                              "masquage des réflexions",
+		"copied code block %d\n":                       "bloc de code %d copié\n",
+		"no pinned files":                              "aucun fichier épinglé",
+		"pinned %s\n":                                  "%s épinglé\n",
+		"unpinned all files":                           "plus aucun fichier épinglé",
+		"unpinned %s\n":                                "%s n'est plus épinglé\n",
+		"%s is %d bytes, too large to pin":             "%s fait %d octets, trop gros pour être épinglé",
+		"%s is not pinned":                             "%s n'est pas épinglé",
+		"(disabled)":                                   "(désactivé)",
+		"(not in mode %s)":                             "(pas dans le mode %s)",
+		"enabled %s, %d tools\n":                       "%s activé, %d outils\n",
+		"disabled %s, %d tools\n":                      "%s désactivé, %d outils\n",
+		"unknown tool %q, try /tools":                  "outil inconnu %q, essayez /tools",
+		"reloaded %d custom tools, %d before\n":        "%d outils personnalisés rechargés, %d auparavant\n",
+		"succeeded: %s\n":                              "réussi : %s\n",
+		"failed: %s\n":                                 "échoué : %s\n",
+		"reconnected":                                  "reconnecté",
+		"lost the connection to Ollama at %s: %w":      "connexion à Ollama sur %s perdue : %w",
+		"dropped %d messages\n":                        "%d messages supprimés\n",
+		"edited message %d\n":                          "message %d modifié\n",
+		"dropped %d messages, retrying\n":              "%d messages supprimés, nouvel essai\n",
+		"critic":                                       "relecteur",
+		"changes are reviewed before they are applied": "les modifications sont relues avant d'être appliquées",
+		"changes are applied without review":           "les modifications sont appliquées sans relecture",
+		"mode %s, model %s, %d tools\n":                "mode %s, modèle %s, %d outils\n",
+		"plan":                                         "plan",
+		"%d subtasks":                                  "%d sous-tâches",
+		"subtask %d/%d":                                "sous-tâche %d/%d",
+		"%s, started":                                  "%s, démarrée",
+		"%s, named by the project config, is not available, download it with /pull %s": "%s, indiqué par la configuration du projet, n'est pas disponible, téléchargez-le avec /pull %s",
+	},
+}
+
+// answerWords are the comma separated answers to dialogs meaning yes (y),
+// no (n) and quit (q), by language.
+var answerWords = map[string]map[string]string{
+	"en": {"y": "yes", "n": "no", "q": "quit"},
+	"de": {"y": "j,ja", "n": "nein", "q": "b,beenden"},
+	"es": {"y": "s,si,sí", "n": "no", "q": "salir"},
+	"fr": {"y": "o,oui", "n": "non", "q": "quitter"},
+}
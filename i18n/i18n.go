@@ -0,0 +1,102 @@
+// Package i18n translates what dacs itself shows the user, the prompts,
+// dialogs and error messages, into the user's language. The conversation
+// with the model is in whatever language the user types.
+//
+// Messages are looked up by their English text, which is used as is when
+// the locale has no translation for it.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var current struct {
+	once   sync.Once
+	locale string
+}
+
+// SetLocale selects the language, e.g. de or de_DE.UTF-8, by default it is
+// that of $LC_ALL, $LC_MESSAGES or $LANG.
+func SetLocale(locale string) {
+	current.once.Do(func() {})
+	current.locale = language(locale)
+}
+
+// Locale returns the language in use, en when nothing else is supported.
+func Locale() string {
+	current.once.Do(func() {
+		for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+			if v := os.Getenv(name); v != "" {
+				current.locale = language(v)
+				break
+			}
+		}
+	})
+	if _, ok := catalogs[current.locale]; !ok {
+		return "en"
+	}
+	return current.locale
+}
+
+// Locales returns the languages there are translations for, besides
+// English.
+func Locales() []string {
+	rv := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		rv = append(rv, l)
+	}
+	sort.Strings(rv)
+	return rv
+}
+
+// language returns the language of a locale such as de_DE.UTF-8.
+func language(locale string) string {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	locale, _, _ = strings.Cut(strings.ReplaceAll(locale, "-", "_"), "_")
+	return strings.ToLower(locale)
+}
+
+// T returns the translation of the message, which can be a format for
+// args.
+func T(msg string, args ...any) string {
+	if translated, ok := catalogs[Locale()][msg]; ok {
+		msg = translated
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Printf prints the translation of the format.
+func Printf(format string, args ...any) {
+	fmt.Print(T(format, args...))
+}
+
+// Errorf returns an error with the translation of the format.
+func Errorf(format string, args ...any) error {
+	return fmt.Errorf(T(format), args...)
+}
+
+// Answer maps an answer to a dialog to y, n or q when it is yes, no or
+// quit in English or the user's language, and returns it lowercased
+// otherwise.
+func Answer(answer string) string {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	for _, a := range []string{"y", "n", "q"} {
+		if answer == a || answer == answerWords["en"][a] {
+			return a
+		}
+		for _, w := range strings.Split(answerWords[Locale()][a], ",") {
+			if answer == w {
+				return a
+			}
+		}
+	}
+	return answer
+}
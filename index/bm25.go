@@ -0,0 +1,99 @@
+package index
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// rrfK dampens the weight of the top ranks in reciprocal rank fusion
+	rrfK = 60
+)
+
+// tokenize splits text into lower case terms. Identifiers are kept whole
+// and also split into their camelCase and snake_case parts, so both
+// "ReadFile" and "file" match ReadFile.
+func tokenize(text string) []string {
+	var rv []string
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for _, word := range words {
+		parts := splitIdentifier(word)
+		rv = append(rv, strings.ToLower(word))
+		if len(parts) > 1 {
+			for _, part := range parts {
+				rv = append(rv, strings.ToLower(part))
+			}
+		}
+	}
+	return rv
+}
+
+func splitIdentifier(word string) []string {
+	var parts []string
+	for _, piece := range strings.Split(word, "_") {
+		runes := []rune(piece)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+			acronymEnd := i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i+1])
+			if lowerToUpper || acronymEnd {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, string(runes[start:]))
+		}
+	}
+	return parts
+}
+
+func (c *Chunk) termFreqs() map[string]int {
+	if c.terms == nil {
+		c.terms = map[string]int{}
+		for _, t := range tokenize(c.Text) {
+			c.terms[t]++
+		}
+		for _, n := range c.terms {
+			c.length += n
+		}
+	}
+	return c.terms
+}
+
+// bm25 scores every chunk against the query terms.
+func bm25(chunks []*Chunk, query string) []float64 {
+	terms := tokenize(query)
+	docFreq := map[string]int{}
+	totalLength := 0
+	for _, c := range chunks {
+		tf := c.termFreqs()
+		totalLength += c.length
+		for _, t := range terms {
+			if tf[t] > 0 {
+				docFreq[t]++
+			}
+		}
+	}
+	n := float64(len(chunks))
+	avgLength := float64(totalLength) / math.Max(n, 1)
+
+	scores := make([]float64, len(chunks))
+	for i, c := range chunks {
+		tf := c.termFreqs()
+		for _, t := range terms {
+			f := float64(tf[t])
+			if f == 0 {
+				continue
+			}
+			idf := math.Log(1 + (n-float64(docFreq[t])+0.5)/(float64(docFreq[t])+0.5))
+			scores[i] += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(c.length)/avgLength))
+		}
+	}
+	return scores
+}
@@ -0,0 +1,379 @@
+// Package index is a semantic index of the workspace, built from embeddings
+// of fixed size chunks of each text file.
+package index
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/provider"
+)
+
+const (
+	chunkLines   = 60
+	maxFileBytes = 256 * 1024
+
+	DefaultWorkers   = 4
+	DefaultBatchSize = 16
+)
+
+type Chunk struct {
+	Path      string    `json:"path"`
+	StartLine int       `json:"start_line"`
+	EndLine   int       `json:"end_line"`
+	Text      string    `json:"text"`
+	Vector    []float32 `json:"vector"`
+
+	// term frequencies for keyword search, computed on first use
+	terms  map[string]int
+	length int
+}
+
+type Result struct {
+	*Chunk
+	Score float64
+}
+
+type Index struct {
+	embedder provider.Embedder
+	model    string
+	root     string
+
+	// Workers is the number of concurrent embed requests, each embedding
+	// up to BatchSize chunks.
+	Workers   int
+	BatchSize int
+	// Reranker, when set, picks the results from a larger set of
+	// candidates.
+	Reranker *Reranker
+
+	m      sync.Mutex
+	loaded bool
+	files  map[string]fileEntry
+	chunks map[string][]*Chunk
+}
+
+// fileEntry records the state of a file when it was indexed.
+type fileEntry struct {
+	Hash    string
+	ModTime time.Time
+	Size    int64
+}
+
+func New(embedder provider.Embedder, model, root string) *Index {
+	return &Index{
+		embedder:  embedder,
+		model:     model,
+		root:      root,
+		Workers:   DefaultWorkers,
+		BatchSize: DefaultBatchSize,
+	}
+}
+
+// Build brings the index up to date with the workspace. The index is
+// loaded from disk the first time, after that only files whose size or
+// modification time changed are read, and only those whose content
+// changed are embedded again.
+func (i *Index) Build(ctx context.Context) error {
+	i.m.Lock()
+	defer i.m.Unlock()
+	if !i.loaded {
+		i.load()
+		i.loaded = true
+	}
+
+	type update struct {
+		entry  fileEntry
+		chunks []*Chunk
+	}
+	updates := map[string]update{}
+	var pending []*Chunk
+	seen := map[string]bool{}
+	dirty := false
+	err := walkFiles(i.root, func(path, rel string, info fs.FileInfo) error {
+		entry, ok := i.files[rel]
+		if ok && entry.ModTime.Equal(info.ModTime()) && entry.Size == info.Size() {
+			seen[rel] = true
+			return nil
+		}
+		content := readText(path)
+		if content == nil {
+			return nil
+		}
+		seen[rel] = true
+		sum := sha256.Sum256(content)
+		newEntry := fileEntry{
+			Hash:    hex.EncodeToString(sum[:]),
+			ModTime: info.ModTime(),
+			Size:    info.Size(),
+		}
+		if ok && entry.Hash == newEntry.Hash {
+			i.files[rel] = newEntry
+			dirty = true
+			return nil
+		}
+		chunks := split(rel, string(content))
+		updates[rel] = update{entry: newEntry, chunks: chunks}
+		pending = append(pending, chunks...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = i.embedChunks(ctx, pending)
+	if err != nil {
+		return err
+	}
+
+	for rel, u := range updates {
+		i.files[rel] = u.entry
+		i.chunks[rel] = u.chunks
+		dirty = true
+	}
+	for rel := range i.files {
+		if !seen[rel] {
+			delete(i.files, rel)
+			delete(i.chunks, rel)
+			dirty = true
+		}
+	}
+	if dirty {
+		return i.save()
+	}
+	return nil
+}
+
+// embedChunks fills in the vectors of the chunks, in batches embedded by a
+// pool of workers, showing a progress bar.
+func (i *Index) embedChunks(ctx context.Context, chunks []*Chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan []*Chunk)
+	go func() {
+		defer close(batches)
+		for start := 0; start < len(chunks); start += max(i.BatchSize, 1) {
+			select {
+			case batches <- chunks[start:min(start+max(i.BatchSize, 1), len(chunks))]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	progress := newProgress(len(chunks))
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	for range max(i.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				texts := make([]string, len(batch))
+				for n, c := range batch {
+					texts[n] = c.Text
+				}
+				vectors, err := i.embed(ctx, texts)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+				for n, c := range batch {
+					c.Vector = vectors[n]
+				}
+				progress.add(len(batch))
+			}
+		}()
+	}
+	wg.Wait()
+	progress.done()
+	return firstErr
+}
+
+// Search returns the k chunks most relevant to the query, fusing the
+// rankings by vector similarity and by BM25 keyword score, since
+// embeddings alone often miss exact identifiers.
+func (i *Index) Search(ctx context.Context, query string, k int) ([]Result, error) {
+	err := i.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := i.embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := k
+	if i.Reranker != nil {
+		candidates = k + rerankExtra
+	}
+
+	i.m.Lock()
+	var chunks []*Chunk
+	for _, fileChunks := range i.chunks {
+		chunks = append(chunks, fileChunks...)
+	}
+
+	similarity := make([]float64, len(chunks))
+	for n, c := range chunks {
+		similarity[n] = cosine(vectors[0], c.Vector)
+	}
+	keyword := bm25(chunks, query)
+
+	fused := make([]float64, len(chunks))
+	for _, scores := range [][]float64{similarity, keyword} {
+		for rank, n := range ranked(scores) {
+			if scores[n] > 0 {
+				fused[n] += 1 / float64(rrfK+rank+1)
+			}
+		}
+	}
+
+	var results []Result
+	for _, n := range ranked(fused) {
+		if len(results) == candidates || fused[n] == 0 {
+			break
+		}
+		results = append(results, Result{Chunk: chunks[n], Score: fused[n]})
+	}
+	i.m.Unlock()
+
+	if i.Reranker != nil {
+		return i.Reranker.Rerank(ctx, query, results, k)
+	}
+	return results, nil
+}
+
+// ranked returns the indexes of scores, highest score first.
+func ranked(scores []float64) []int {
+	rv := make([]int, len(scores))
+	for n := range rv {
+		rv[n] = n
+	}
+	sort.SliceStable(rv, func(a, b int) bool {
+		return scores[rv[a]] > scores[rv[b]]
+	})
+	return rv
+}
+
+func (i *Index) embed(ctx context.Context, input []string) ([][]float32, error) {
+	res, err := i.embedder.Embed(ctx, &api.EmbedRequest{
+		Model:    i.model,
+		Input:    input,
+		Truncate: &truncate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding with %s: %w", i.model, err)
+	}
+	if len(res.Embeddings) != len(input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(input), len(res.Embeddings))
+	}
+	return res.Embeddings, nil
+}
+
+var truncate = true
+
+// Walk calls fn for every text file in the workspace, skipping hidden and
+// vendored directories, large files and binaries.
+func Walk(root string, fn func(path string, content []byte) error) error {
+	return walkFiles(root, func(path, rel string, _ fs.FileInfo) error {
+		if content := readText(path); content != nil {
+			return fn(rel, content)
+		}
+		return nil
+	})
+}
+
+// walkFiles calls fn for every candidate file, without reading it.
+func walkFiles(root string, fn func(path, rel string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(name, ".") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileBytes || info.Size() == 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, rel, info)
+	})
+}
+
+// readText returns the content of the file, or nil if it cannot be read or
+// looks binary.
+func readText(path string) []byte {
+	content, err := os.ReadFile(path)
+	if err != nil || len(content) == 0 {
+		return nil
+	}
+	if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		return nil
+	}
+	return content
+}
+
+func split(path, content string) []*Chunk {
+	lines := strings.Split(content, "\n")
+	var rv []*Chunk
+	for start := 0; start < len(lines); start += chunkLines {
+		end := min(start+chunkLines, len(lines))
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		rv = append(rv, &Chunk{
+			Path:      path,
+			StartLine: start + 1,
+			EndLine:   end,
+			Text:      text,
+		})
+	}
+	return rv
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,86 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// persisted is the on disk form of the index.
+type persisted struct {
+	Model  string
+	Files  map[string]fileEntry
+	Chunks map[string][]*Chunk
+}
+
+// Path returns where the index is persisted, in the user's cache directory,
+// keyed by the workspace root and the embedding model.
+func (i *Index) Path() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	root, err := filepath.Abs(i.root)
+	if err != nil {
+		root = i.root
+	}
+	sum := sha256.Sum256([]byte(root + "\x00" + i.model))
+	return filepath.Join(dir, "dacs", "index", hex.EncodeToString(sum[:8])+".gob")
+}
+
+// load restores the persisted index, starting empty if there is none or
+// it cannot be read.
+func (i *Index) load() {
+	i.files = map[string]fileEntry{}
+	i.chunks = map[string][]*Chunk{}
+
+	path := i.Path()
+	if path == "" {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var p persisted
+	if err := gob.NewDecoder(f).Decode(&p); err != nil || p.Model != i.model {
+		return
+	}
+	if p.Files != nil && p.Chunks != nil {
+		i.files, i.chunks = p.Files, p.Chunks
+	}
+}
+
+func (i *Index) save() error {
+	path := i.Path()
+	if path == "" {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "index-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = gob.NewEncoder(f).Encode(persisted{
+		Model:  i.model,
+		Files:  i.files,
+		Chunks: i.chunks,
+	})
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return fmt.Errorf("error saving index: %w", err)
+	}
+	return os.Rename(f.Name(), path)
+}
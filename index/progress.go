@@ -0,0 +1,38 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const progressWidth = 30
+
+type progress struct {
+	m     sync.Mutex
+	total int
+	count int
+}
+
+func newProgress(total int) *progress {
+	p := &progress{total: total}
+	p.draw()
+	return p
+}
+
+func (p *progress) add(n int) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.count += n
+	p.draw()
+}
+
+func (p *progress) done() {
+	fmt.Println()
+}
+
+func (p *progress) draw() {
+	filled := progressWidth * p.count / max(p.total, 1)
+	fmt.Printf("\rindexing [%s%s] %d/%d chunks", strings.Repeat("=", filled),
+		strings.Repeat(" ", progressWidth-filled), p.count, p.total)
+}
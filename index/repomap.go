@@ -0,0 +1,66 @@
+package index
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// RepoMap returns a compact outline of the workspace, one line per file,
+// listing the top-level declarations of Go files.
+func RepoMap(root string) (string, error) {
+	var sb strings.Builder
+	err := Walk(root, func(path string, content []byte) error {
+		sb.WriteString(path)
+		if strings.HasSuffix(path, ".go") {
+			if decls := goDecls(path, content); len(decls) > 0 {
+				fmt.Fprintf(&sb, ": %s", strings.Join(decls, ", "))
+			}
+		}
+		sb.WriteString("\n")
+		return nil
+	})
+	return sb.String(), err
+}
+
+func goDecls(path string, content []byte) []string {
+	f, err := parser.ParseFile(token.NewFileSet(), path, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	var rv []string
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name := d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name = recvType(d.Recv.List[0].Type) + "." + name
+			}
+			rv = append(rv, name)
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				rv = append(rv, "type "+spec.(*ast.TypeSpec).Name.Name)
+			}
+		}
+	}
+	return rv
+}
+
+func recvType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return recvType(t.X)
+	case *ast.IndexExpr:
+		return recvType(t.X)
+	case *ast.IndexListExpr:
+		return recvType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return "?"
+}
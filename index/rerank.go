@@ -0,0 +1,125 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/provider"
+)
+
+const (
+	// candidates retrieved beyond k for the reranker to choose from
+	rerankExtra = 10
+	// longer chunks are cut when shown to the reranker
+	rerankChunkChars = 1200
+
+	rerankPrompt = "You rate how relevant code and text snippets are to a search query. Score each snippet from 0 (unrelated) to 10 (exactly what the query is looking for)."
+)
+
+var rerankFormat = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "scores": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer"},
+          "score": {"type": "integer"}
+        },
+        "required": ["id", "score"]
+      }
+    }
+  },
+  "required": ["scores"]
+}`)
+
+// Reranker uses a (small) model to score retrieved chunks for relevance to
+// the query, which is more precise than similarity alone.
+type Reranker struct {
+	client provider.Provider
+	model  string
+}
+
+func NewReranker(client provider.Provider, model string) *Reranker {
+	return &Reranker{
+		client: client,
+		model:  model,
+	}
+}
+
+// Rerank orders the results by the model's scores, keeping the original
+// order for ties, and returns the best k.
+func (r *Reranker) Rerank(ctx context.Context, query string, results []Result, k int) ([]Result, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Query: %s\n", query)
+	for n, res := range results {
+		text := res.Text
+		if len(text) > rerankChunkChars {
+			text = text[:rerankChunkChars] + "..."
+		}
+		fmt.Fprintf(&sb, "\nSnippet %d (%s:%d-%d):\n%s\n", n+1, res.Path, res.StartLine, res.EndLine, text)
+	}
+
+	var resp api.ChatResponse
+	stream := false
+	err := r.client.Chat(ctx, &api.ChatRequest{
+		Model: r.model,
+		Messages: []api.Message{
+			{Role: "system", Content: rerankPrompt},
+			{Role: "user", Content: sb.String()},
+		},
+		Format: rerankFormat,
+		Options: map[string]any{
+			"temperature": 0.0,
+		},
+		Stream: &stream,
+	}, func(cr api.ChatResponse) error {
+		resp = cr
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reranking with %s: %w", r.model, err)
+	}
+
+	var scored struct {
+		Scores []struct {
+			ID    int `json:"id"`
+			Score int `json:"score"`
+		} `json:"scores"`
+	}
+	err = json.Unmarshal([]byte(resp.Message.Content), &scored)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing rerank scores: %w", err)
+	}
+	scores := make([]int, len(results))
+	for _, s := range scored.Scores {
+		if s.ID >= 1 && s.ID <= len(results) {
+			scores[s.ID-1] = s.Score
+		}
+	}
+
+	order := make([]int, len(results))
+	for n := range order {
+		order[n] = n
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+	rv := make([]Result, 0, min(k, len(results)))
+	for _, n := range order[:min(k, len(order))] {
+		res := results[n]
+		res.Score = float64(scores[n])
+		rv = append(rv, res)
+	}
+	return rv, nil
+}
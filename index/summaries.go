@@ -0,0 +1,153 @@
+package index
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/provider"
+)
+
+const (
+	// longer files are cut when shown to the summarizer, their beginning
+	// usually says enough about what they are
+	summaryFileChars = 6000
+
+	summaryPrompt = "You describe files of a software project. Reply with a single line of at most 25 words saying what the file contains or does, naming its main types, functions or sections. No preamble, no markdown."
+)
+
+// Summaries generates one line summaries of files with a (small) model,
+// cached by content, so a file is only summarized again once it changed.
+type Summaries struct {
+	client provider.Provider
+	model  string
+
+	mu      sync.Mutex
+	cache   map[string]string
+	changed bool
+}
+
+func NewSummaries(client provider.Provider, model string) *Summaries {
+	s := &Summaries{
+		client: client,
+		model:  model,
+	}
+	s.load()
+	return s
+}
+
+// Summarize returns the summary of the file, generating it unless the same
+// content was summarized before.
+func (s *Summaries) Summarize(ctx context.Context, path string, content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	key := hex.EncodeToString(sum[:])
+	s.mu.Lock()
+	summary, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok {
+		return summary, nil
+	}
+
+	text := string(content)
+	if len(text) > summaryFileChars {
+		text = text[:summaryFileChars] + "\n..."
+	}
+	var resp api.ChatResponse
+	stream := false
+	err := s.client.Chat(ctx, &api.ChatRequest{
+		Model: s.model,
+		Messages: []api.Message{
+			{Role: "system", Content: summaryPrompt},
+			{Role: "user", Content: fmt.Sprintf("File %s:\n%s", path, text)},
+		},
+		Options: map[string]any{
+			"temperature": 0.0,
+		},
+		Stream: &stream,
+	}, func(cr api.ChatResponse) error {
+		resp = cr
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error summarizing %s: %w", path, err)
+	}
+	summary = strings.TrimSpace(resp.Message.Content)
+	if i := strings.IndexByte(summary, '\n'); i >= 0 {
+		summary = summary[:i]
+	}
+
+	s.mu.Lock()
+	s.cache[key] = summary
+	s.changed = true
+	s.mu.Unlock()
+	return summary, nil
+}
+
+// path returns where the summaries are persisted, in the user's cache
+// directory, keyed by the model as the same content has the same summary
+// in every workspace.
+func (s *Summaries) path() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(s.model))
+	return filepath.Join(dir, "dacs", "summaries", hex.EncodeToString(sum[:8])+".gob")
+}
+
+// load restores the persisted summaries, starting empty if there are none
+// or they cannot be read.
+func (s *Summaries) load() {
+	s.cache = map[string]string{}
+	path := s.path()
+	if path == "" {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var cache map[string]string
+	if err := gob.NewDecoder(f).Decode(&cache); err == nil && cache != nil {
+		s.cache = cache
+	}
+}
+
+// Save persists the summaries generated since they were loaded.
+func (s *Summaries) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.path()
+	if path == "" || !s.changed {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "summaries-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = gob.NewEncoder(f).Encode(s.cache)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return fmt.Errorf("error saving summaries: %w", err)
+	}
+	s.changed = false
+	return os.Rename(f.Name(), path)
+}
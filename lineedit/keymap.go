@@ -0,0 +1,296 @@
+package lineedit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Action is what the editor does when a key is pressed.
+type Action string
+
+const (
+	AcceptLine         Action = "accept-line"
+	Interrupt          Action = "interrupt"
+	DeleteCharOrEOF    Action = "delete-char-or-eof"
+	DeleteChar         Action = "delete-char"
+	BackwardDeleteChar Action = "backward-delete-char"
+	BeginningOfLine    Action = "beginning-of-line"
+	EndOfLine          Action = "end-of-line"
+	BackwardChar       Action = "backward-char"
+	ForwardChar        Action = "forward-char"
+	BackwardWord       Action = "backward-word"
+	ForwardWord        Action = "forward-word"
+	KillLine           Action = "kill-line"
+	UnixLineDiscard    Action = "unix-line-discard"
+	BackwardKillWord   Action = "backward-kill-word"
+	KillWord           Action = "kill-word"
+	Yank               Action = "yank"
+	ClearScreen        Action = "clear-screen"
+	Complete           Action = "complete"
+	PreviousHistory    Action = "previous-history"
+	NextHistory        Action = "next-history"
+
+	// vi modes, and its commands that differ from emacs
+	ViCommandMode     Action = "vi-command-mode"
+	ViInsert          Action = "vi-insert"
+	ViAppend          Action = "vi-append"
+	ViInsertBeginning Action = "vi-insert-beginning"
+	ViAppendEnd       Action = "vi-append-end"
+	ViNextWord        Action = "vi-next-word"
+	ViEndWord         Action = "vi-end-word"
+	ViDelete          Action = "vi-delete"
+	ViChange          Action = "vi-change"
+	ViChangeLine      Action = "vi-change-line"
+	ViPut             Action = "vi-put"
+)
+
+// Keymap maps keys to actions. Keys are named as typed, such as "a" or
+// "$", or ctrl-a through ctrl-z, alt- followed by a key, and up, down,
+// left, right, home, end, delete, backspace, tab, enter and escape.
+type Keymap map[string]Action
+
+// EmacsKeymap is the default keymap, readline's emacs mode.
+var EmacsKeymap = Keymap{
+	"enter":     AcceptLine,
+	"ctrl-c":    Interrupt,
+	"ctrl-d":    DeleteCharOrEOF,
+	"delete":    DeleteChar,
+	"backspace": BackwardDeleteChar,
+	"ctrl-h":    BackwardDeleteChar,
+	"ctrl-a":    BeginningOfLine,
+	"home":      BeginningOfLine,
+	"ctrl-e":    EndOfLine,
+	"end":       EndOfLine,
+	"ctrl-b":    BackwardChar,
+	"left":      BackwardChar,
+	"ctrl-f":    ForwardChar,
+	"right":     ForwardChar,
+	"alt-b":     BackwardWord,
+	"alt-f":     ForwardWord,
+	"ctrl-k":    KillLine,
+	"ctrl-u":    UnixLineDiscard,
+	"ctrl-w":    BackwardKillWord,
+	"alt-d":     KillWord,
+	"ctrl-y":    Yank,
+	"ctrl-l":    ClearScreen,
+	"tab":       Complete,
+	"ctrl-p":    PreviousHistory,
+	"up":        PreviousHistory,
+	"ctrl-n":    NextHistory,
+	"down":      NextHistory,
+}
+
+// ViInsertKeymap is the keymap of vi's insert mode, escape switches to
+// ViCommandKeymap.
+var ViInsertKeymap = Keymap{
+	"enter":     AcceptLine,
+	"ctrl-c":    Interrupt,
+	"ctrl-d":    DeleteCharOrEOF,
+	"delete":    DeleteChar,
+	"backspace": BackwardDeleteChar,
+	"ctrl-h":    BackwardDeleteChar,
+	"home":      BeginningOfLine,
+	"end":       EndOfLine,
+	"left":      BackwardChar,
+	"right":     ForwardChar,
+	"ctrl-u":    UnixLineDiscard,
+	"ctrl-w":    BackwardKillWord,
+	"ctrl-l":    ClearScreen,
+	"tab":       Complete,
+	"ctrl-p":    PreviousHistory,
+	"up":        PreviousHistory,
+	"ctrl-n":    NextHistory,
+	"down":      NextHistory,
+	"escape":    ViCommandMode,
+}
+
+// ViCommandKeymap is the keymap of vi's command mode. Keys without an
+// action are ignored rather than inserted.
+var ViCommandKeymap = Keymap{
+	"enter":     AcceptLine,
+	"ctrl-c":    Interrupt,
+	"ctrl-d":    DeleteCharOrEOF,
+	"ctrl-l":    ClearScreen,
+	"h":         BackwardChar,
+	"left":      BackwardChar,
+	"backspace": BackwardChar,
+	"l":         ForwardChar,
+	"right":     ForwardChar,
+	" ":         ForwardChar,
+	"0":         BeginningOfLine,
+	"^":         BeginningOfLine,
+	"home":      BeginningOfLine,
+	"$":         EndOfLine,
+	"end":       EndOfLine,
+	"b":         BackwardWord,
+	"w":         ViNextWord,
+	"e":         ViEndWord,
+	"x":         DeleteChar,
+	"delete":    DeleteChar,
+	"X":         BackwardDeleteChar,
+	"D":         KillLine,
+	"p":         ViPut,
+	"k":         PreviousHistory,
+	"up":        PreviousHistory,
+	"j":         NextHistory,
+	"down":      NextHistory,
+	"i":         ViInsert,
+	"a":         ViAppend,
+	"I":         ViInsertBeginning,
+	"A":         ViAppendEnd,
+	"d":         ViDelete,
+	"c":         ViChange,
+	"C":         ViChangeLine,
+	"S":         ViChangeLine,
+}
+
+// motions are the actions d and c can be followed by in vi's command mode.
+var motions = map[Action]bool{
+	BackwardChar:    true,
+	ForwardChar:     true,
+	BeginningOfLine: true,
+	EndOfLine:       true,
+	BackwardWord:    true,
+	ViNextWord:      true,
+	ViEndWord:       true,
+}
+
+var actions = func() map[Action]bool {
+	rv := map[Action]bool{}
+	for _, km := range []Keymap{EmacsKeymap, ViInsertKeymap, ViCommandKeymap} {
+		for _, a := range km {
+			rv[a] = true
+		}
+	}
+	return rv
+}()
+
+// SetMode selects emacs (the default) or vi keybindings, with the bindings
+// replacing or adding to those of emacs mode, or vi's insert mode. In vi
+// mode, keys prefixed with "command " are bound in its command mode.
+func (e *Editor) SetMode(mode string, bindings map[string]string) error {
+	switch mode {
+	case "", "emacs":
+		e.keymap, e.viCommand = clone(EmacsKeymap), nil
+	case "vi":
+		e.keymap, e.viCommand = clone(ViInsertKeymap), clone(ViCommandKeymap)
+	default:
+		return fmt.Errorf("unknown edit mode %q, expected emacs or vi", mode)
+	}
+	keys := make([]string, 0, len(bindings))
+	for k := range bindings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		a := Action(bindings[k])
+		if !actions[a] {
+			return fmt.Errorf("unknown action %q bound to %q", a, k)
+		}
+		km := e.keymap
+		if name, ok := strings.CutPrefix(k, "command "); ok && e.viCommand != nil {
+			km, k = e.viCommand, name
+		}
+		if !validKey(k) {
+			return fmt.Errorf("unknown key %q bound to %q", k, a)
+		}
+		km[k] = a
+	}
+	return nil
+}
+
+func clone(km Keymap) Keymap {
+	rv := make(Keymap, len(km))
+	for k, a := range km {
+		rv[k] = a
+	}
+	return rv
+}
+
+var namedKeys = map[string]bool{
+	"up": true, "down": true, "left": true, "right": true, "home": true, "end": true,
+	"delete": true, "backspace": true, "tab": true, "enter": true, "escape": true,
+}
+
+func validKey(k string) bool {
+	if name, ok := strings.CutPrefix(k, "alt-"); ok {
+		return len([]rune(name)) == 1 || namedKeys[name]
+	}
+	if name, ok := strings.CutPrefix(k, "ctrl-"); ok {
+		return len(name) == 1 && name[0] >= 'a' && name[0] <= 'z'
+	}
+	return namedKeys[k] || len([]rune(k)) == 1
+}
+
+// readKey reads a key press and returns its name, see Keymap.
+func (e *Editor) readKey() (string, error) {
+	r, _, err := e.reader.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case r == '\r' || r == '\n':
+		return "enter", nil
+	case r == '\t':
+		return "tab", nil
+	case r == 127:
+		return "backspace", nil
+	case r == 27:
+		return e.readEscape(), nil
+	case r > 0 && r <= 26:
+		return "ctrl-" + string(rune('a'+r-1)), nil
+	}
+	return string(r), nil
+}
+
+// readEscape reads the rest of a key starting with escape: an escape
+// sequence for a special key, alt and a key, or escape itself when nothing
+// follows at once, as when it was typed.
+func (e *Editor) readEscape() string {
+	if e.reader.Buffered() == 0 {
+		return "escape"
+	}
+	r, _, err := e.reader.ReadRune()
+	if err != nil {
+		return "escape"
+	}
+	if r != '[' && r != 'O' {
+		switch {
+		case r == 127:
+			return "alt-backspace"
+		case unicode.IsPrint(r):
+			return "alt-" + string(r)
+		}
+		return "escape"
+	}
+	seq := []rune{r}
+	for len(seq) <= 8 {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			break
+		}
+		seq = append(seq, r)
+		if unicode.IsLetter(r) || r == '~' {
+			break
+		}
+	}
+	switch string(seq) {
+	case "[A", "OA":
+		return "up"
+	case "[B", "OB":
+		return "down"
+	case "[C", "OC":
+		return "right"
+	case "[D", "OD":
+		return "left"
+	case "[H", "OH", "[1~", "[7~":
+		return "home"
+	case "[F", "OF", "[4~", "[8~":
+		return "end"
+	case "[3~":
+		return "delete"
+	}
+	return "escape " + string(seq)
+}
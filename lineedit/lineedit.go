@@ -0,0 +1,370 @@
+// Package lineedit is a minimal line editor for the REPL, with history and
+// tab completion.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// ErrInterrupt is returned by ReadLine when the user presses ctrl-c.
+var ErrInterrupt = errors.New("interrupt")
+
+// CompleteFunc returns the candidates for the word ending at pos, and the
+// position that word starts at.
+type CompleteFunc func(line []rune, pos int) (candidates []string, start int)
+
+type Editor struct {
+	Complete CompleteFunc
+	// Plain reads lines without any editing, as when the input is not a
+	// terminal, so nothing is redrawn for screen readers.
+	Plain bool
+
+	in      *os.File
+	out     io.Writer
+	reader  *bufio.Reader
+	history []string
+
+	prompt string
+	buf    []rune
+	pos    int
+	// cursor column relative to the end of the prompt, as last drawn
+	drawn int
+	// killed is the text last deleted with a kill command
+	killed string
+
+	keymap Keymap
+	// viCommand is the keymap of vi's command mode, nil in emacs mode,
+	// command is set while in it
+	viCommand Keymap
+	command   bool
+}
+
+func New(in *os.File, out io.Writer) *Editor {
+	return &Editor{
+		in:     in,
+		out:    out,
+		reader: bufio.NewReader(in),
+	}
+}
+
+// ReadLine prints the prompt and reads a line of input. When the input is
+// not a terminal, or Plain is set, it reads lines without any editing.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	fd := int(e.in.Fd())
+	if e.Plain || !term.IsTerminal(fd) {
+		fmt.Fprint(e.out, prompt)
+		line, err := e.reader.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(fd, state)
+
+	e.prompt, e.buf, e.pos, e.drawn = prompt, nil, 0, 0
+	fmt.Fprint(e.out, prompt)
+	line, err := e.edit()
+	fmt.Fprint(e.out, "\r\n")
+	if err == nil && strings.TrimSpace(line) != "" {
+		e.history = append(e.history, line)
+	}
+	return line, err
+}
+
+func (e *Editor) edit() (string, error) {
+	if e.keymap == nil {
+		e.keymap = EmacsKeymap
+	}
+	// vi starts in insert mode
+	e.command = false
+	historyPos := len(e.history)
+	var pending string
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+		keymap := e.keymap
+		if e.command {
+			keymap = e.viCommand
+		}
+		action, ok := keymap[key]
+		if !ok {
+			if r := []rune(key); !e.command && len(r) == 1 && unicode.IsPrint(r[0]) {
+				e.insert(key)
+				e.redraw()
+			}
+			continue
+		}
+		switch action {
+		case AcceptLine:
+			return string(e.buf), nil
+		case Interrupt:
+			return "", ErrInterrupt
+		case DeleteCharOrEOF:
+			if len(e.buf) == 0 {
+				return "", io.EOF
+			}
+			e.delete(e.pos)
+		case PreviousHistory, NextHistory:
+			historyPos, pending = e.recall(historyPos, pending, action == PreviousHistory)
+		case ViDelete, ViChange:
+			e.operate(action)
+		default:
+			e.do(action)
+		}
+		if e.command {
+			// the cursor is on a character in vi's command mode
+			e.pos = max(min(e.pos, len(e.buf)-1), 0)
+		}
+		e.redraw()
+	}
+}
+
+// do performs the action, other than those ending the line or recalling
+// history.
+func (e *Editor) do(action Action) {
+	switch action {
+	case DeleteChar:
+		if e.command {
+			// kept for p, as vi does
+			e.kill(e.pos, min(e.pos+1, len(e.buf)))
+		} else {
+			e.delete(e.pos)
+		}
+	case BackwardDeleteChar:
+		if e.pos > 0 {
+			e.delete(e.pos - 1)
+			e.pos--
+		}
+	case BeginningOfLine:
+		e.pos = 0
+	case EndOfLine:
+		e.pos = len(e.buf)
+	case BackwardChar:
+		e.pos = max(e.pos-1, 0)
+	case ForwardChar:
+		e.pos = min(e.pos+1, len(e.buf))
+	case BackwardWord:
+		e.pos = wordStart(e.buf, e.pos)
+	case ForwardWord:
+		e.pos = wordEnd(e.buf, e.pos)
+	case ViNextWord:
+		e.pos = nextWord(e.buf, e.pos)
+	case ViEndWord:
+		e.pos = max(wordEnd(e.buf, e.pos+1)-1, 0)
+	case KillLine:
+		e.kill(e.pos, len(e.buf))
+	case UnixLineDiscard:
+		e.kill(0, e.pos)
+	case BackwardKillWord:
+		e.kill(wordStart(e.buf, e.pos), e.pos)
+	case KillWord:
+		e.kill(e.pos, wordEnd(e.buf, e.pos))
+	case Yank:
+		e.insert(e.killed)
+	case ViPut:
+		e.pos = min(e.pos+1, len(e.buf))
+		e.insert(e.killed)
+		e.pos--
+	case ClearScreen:
+		fmt.Fprint(e.out, "\u001b[H\u001b[2J"+e.prompt)
+		e.drawn = 0
+	case Complete:
+		e.complete()
+	case ViCommandMode:
+		if e.viCommand != nil && !e.command {
+			e.command = true
+			e.pos = max(e.pos-1, 0)
+		}
+	case ViInsert:
+		e.command = false
+	case ViAppend:
+		e.command = false
+		e.pos = min(e.pos+1, len(e.buf))
+	case ViInsertBeginning:
+		e.command = false
+		e.pos = 0
+	case ViAppendEnd:
+		e.command = false
+		e.pos = len(e.buf)
+	case ViChangeLine:
+		e.kill(0, len(e.buf))
+		e.command = false
+	}
+}
+
+// operate reads the motion following vi's d or c, and deletes the text it
+// moves over, the line when the operator is repeated, switching to insert
+// mode for c.
+func (e *Editor) operate(op Action) {
+	key, err := e.readKey()
+	if err != nil {
+		return
+	}
+	motion := e.viCommand[key]
+	switch {
+	case motion == op:
+		e.kill(0, len(e.buf))
+	case motions[motion]:
+		from := e.pos
+		e.do(motion)
+		to := e.pos
+		if motion == ViEndWord {
+			// inclusive, the end is on the word's last character
+			to = min(to+1, len(e.buf))
+		}
+		if op == ViChange && motion == ViNextWord {
+			// cw changes to the end of the word, as ce
+			to = wordEnd(e.buf, from)
+		}
+		e.kill(min(from, to), max(from, to))
+	default:
+		return
+	}
+	if op == ViChange {
+		e.command = false
+	}
+}
+
+// kill deletes buf[from:to], keeping it to be yanked.
+func (e *Editor) kill(from, to int) {
+	if from >= to {
+		return
+	}
+	e.killed = string(e.buf[from:to])
+	e.buf = append(e.buf[:from], e.buf[to:]...)
+	e.pos = from
+}
+
+func (e *Editor) insert(s string) {
+	rs := []rune(s)
+	e.buf = append(e.buf[:e.pos], append(rs, e.buf[e.pos:]...)...)
+	e.pos += len(rs)
+}
+
+func (e *Editor) delete(at int) {
+	if at < len(e.buf) {
+		e.buf = append(e.buf[:at], e.buf[at+1:]...)
+	}
+}
+
+// recall moves through the history, remembering the line being edited
+// so that moving past the newest entry restores it.
+func (e *Editor) recall(historyPos int, pending string, older bool) (int, string) {
+	if historyPos == len(e.history) {
+		pending = string(e.buf)
+	}
+	if older && historyPos > 0 {
+		historyPos--
+	} else if !older && historyPos < len(e.history) {
+		historyPos++
+	} else {
+		return historyPos, pending
+	}
+	if historyPos == len(e.history) {
+		e.buf = []rune(pending)
+	} else {
+		e.buf = []rune(e.history[historyPos])
+	}
+	e.pos = len(e.buf)
+	return historyPos, pending
+}
+
+func (e *Editor) complete() {
+	if e.Complete == nil {
+		return
+	}
+	candidates, start := e.Complete(e.buf, e.pos)
+	if len(candidates) == 0 {
+		return
+	}
+	word := string(e.buf[start:e.pos])
+	prefix := commonPrefix(candidates)
+	if len(candidates) == 1 && !strings.HasSuffix(prefix, "/") {
+		prefix += " "
+	}
+	if len(prefix) > len(word) {
+		e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+		e.pos = start
+		e.insert(prefix)
+		return
+	}
+	if len(candidates) > 1 {
+		fmt.Fprint(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n"+e.prompt)
+		e.drawn = 0
+	}
+}
+
+// redraw rewrites the line after the prompt and places the cursor.
+func (e *Editor) redraw() {
+	var sb strings.Builder
+	if e.drawn > 0 {
+		fmt.Fprintf(&sb, "\u001b[%dD", e.drawn)
+	}
+	sb.WriteString(string(e.buf))
+	sb.WriteString("\u001b[K")
+	if back := len(e.buf) - e.pos; back > 0 {
+		fmt.Fprintf(&sb, "\u001b[%dD", back)
+	}
+	e.drawn = e.pos
+	fmt.Fprint(e.out, sb.String())
+}
+
+// wordEnd returns the end of the word at or after pos.
+func wordEnd(buf []rune, pos int) int {
+	end := pos
+	for end < len(buf) && buf[end] == ' ' {
+		end++
+	}
+	for end < len(buf) && buf[end] != ' ' {
+		end++
+	}
+	return end
+}
+
+// nextWord returns the start of the word after the one at pos.
+func nextWord(buf []rune, pos int) int {
+	next := pos
+	for next < len(buf) && buf[next] != ' ' {
+		next++
+	}
+	for next < len(buf) && buf[next] == ' ' {
+		next++
+	}
+	return next
+}
+
+func wordStart(buf []rune, pos int) int {
+	start := pos
+	for start > 0 && buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && buf[start-1] != ' ' {
+		start--
+	}
+	return start
+}
+
+func commonPrefix(ss []string) string {
+	prefix := ss[0]
+	for _, s := range ss[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,38 @@
+package lineedit
+
+import "testing"
+
+func TestWordMotions(t *testing.T) {
+	buf := []rune("foo  bar baz")
+	for _, test := range []struct {
+		pos                          int
+		wordEnd, nextWord, wordStart int
+	}{
+		{0, 3, 5, 0},
+		{1, 3, 5, 0},
+		{3, 8, 5, 0},
+		{5, 8, 9, 0},
+		{6, 8, 9, 5},
+		{9, 12, 12, 5},
+		{12, 12, 12, 9},
+	} {
+		if got := wordEnd(buf, test.pos); got != test.wordEnd {
+			t.Errorf("wordEnd(%d) = %d, want %d", test.pos, got, test.wordEnd)
+		}
+		if got := nextWord(buf, test.pos); got != test.nextWord {
+			t.Errorf("nextWord(%d) = %d, want %d", test.pos, got, test.nextWord)
+		}
+		if got := wordStart(buf, test.pos); got != test.wordStart {
+			t.Errorf("wordStart(%d) = %d, want %d", test.pos, got, test.wordStart)
+		}
+	}
+
+	for _, empty := range [][]rune{nil, []rune("   ")} {
+		if got := wordStart(empty, len(empty)); got != 0 {
+			t.Errorf("wordStart(%q) = %d, want 0", string(empty), got)
+		}
+		if got := wordEnd(empty, 0); got != len(empty) {
+			t.Errorf("wordEnd(%q) = %d, want %d", string(empty), got, len(empty))
+		}
+	}
+}
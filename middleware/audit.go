@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mschoch/dacs/tools"
+)
+
+// Auditor appends a JSON line to a writer for every tool call with an
+// effect outside of the session, such as a file write or a shell command,
+// for review after autonomous runs, with its outcome: performed, failed,
+// dry run, or declined when the user did not approve its changes. Calls
+// denied by middleware before it, such as the policy, do not reach it and
+// are not recorded.
+type Auditor struct {
+	m sync.Mutex
+	w io.Writer
+}
+
+func NewAuditor(w io.Writer) *Auditor {
+	return &Auditor{w: w}
+}
+
+type auditEntry struct {
+	Time     time.Time       `json:"time"`
+	Duration time.Duration   `json:"duration"`
+	Message  int             `json:"message"`
+	Tool     string          `json:"tool"`
+	Effect   tools.Effect    `json:"effect"`
+	Input    json.RawMessage `json:"input"`
+	Outcome  string          `json:"outcome"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// outcomes of the audited calls
+const (
+	outcomePerformed = "performed"
+	outcomeFailed    = "failed"
+	outcomeDryRun    = "dry_run"
+	outcomeDeclined  = "declined"
+)
+
+func (a *Auditor) Tool(next ToolFunc) ToolFunc {
+	return func(ctx context.Context, call ToolCall) (string, error) {
+		if call.Effect == tools.EffectNone {
+			return next(ctx, call)
+		}
+		var declined error
+		parent := ctx
+		ctx = tools.WithApprove(ctx, func(paths []string, diff string) error {
+			declined = tools.Approve(parent, paths, diff)
+			return declined
+		})
+		start := time.Now()
+		res, err := next(ctx, call)
+		e := auditEntry{
+			Time:     start,
+			Duration: time.Since(start),
+			Message:  call.Message,
+			Tool:     call.Name,
+			Effect:   call.Effect,
+			Input:    call.Input,
+			Outcome:  outcomePerformed,
+		}
+		switch {
+		case err != nil:
+			e.Outcome, e.Error = outcomeFailed, err.Error()
+		case tools.DryRun(ctx):
+			e.Outcome = outcomeDryRun
+		case declined != nil:
+			e.Outcome, e.Error = outcomeDeclined, declined.Error()
+		}
+		if buf, merr := json.Marshal(e); merr == nil {
+			a.m.Lock()
+			_, _ = a.w.Write(append(buf, '\n'))
+			a.m.Unlock()
+		}
+		return res, err
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Logger writes every inference and tool call, with its result, to a
+// writer as JSON lines.
+type Logger struct {
+	m sync.Mutex
+	w io.Writer
+}
+
+func NewLogger(w io.Writer) *Logger {
+	return &Logger{w: w}
+}
+
+type logEntry struct {
+	Time     time.Time         `json:"time"`
+	Duration time.Duration     `json:"duration"`
+	Request  *api.ChatRequest  `json:"request,omitempty"`
+	Response *api.ChatResponse `json:"response,omitempty"`
+	Tool     string            `json:"tool,omitempty"`
+	Input    json.RawMessage   `json:"input,omitempty"`
+	Result   string            `json:"result,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+func (l *Logger) write(e logEntry) {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	_, _ = l.w.Write(append(buf, '\n'))
+}
+
+func (l *Logger) Inference(next InferenceFunc) InferenceFunc {
+	return func(ctx context.Context, req *api.ChatRequest) (api.ChatResponse, error) {
+		start := time.Now()
+		res, err := next(ctx, req)
+		e := logEntry{Time: start, Duration: time.Since(start), Request: req, Response: &res}
+		if err != nil {
+			e.Response, e.Error = nil, err.Error()
+		}
+		l.write(e)
+		return res, err
+	}
+}
+
+func (l *Logger) Tool(next ToolFunc) ToolFunc {
+	return func(ctx context.Context, call ToolCall) (string, error) {
+		start := time.Now()
+		res, err := next(ctx, call)
+		e := logEntry{Time: start, Duration: time.Since(start), Tool: call.Name, Input: call.Input, Result: res}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		l.write(e)
+		return res, err
+	}
+}
@@ -0,0 +1,54 @@
+// Package middleware wraps the agent's inference and tool execution, so
+// concerns that apply to every call, such as logging and guardrails, stay
+// out of the agent's loop.
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/tools"
+)
+
+// InferenceFunc runs a (non-streaming) chat request.
+type InferenceFunc func(ctx context.Context, req *api.ChatRequest) (api.ChatResponse, error)
+
+// Inference wraps an InferenceFunc, it may change the request or response,
+// or not call next at all.
+type Inference func(next InferenceFunc) InferenceFunc
+
+type ToolCall struct {
+	Name  string
+	Input json.RawMessage
+	// Effect is the tool's, see tools.Effect.
+	Effect tools.Effect
+	// Message is the index in the session of the message making the call.
+	Message int
+}
+
+// ToolFunc executes a tool call.
+type ToolFunc func(ctx context.Context, call ToolCall) (string, error)
+
+// Tool wraps a ToolFunc, it may change the call or result, or not call
+// next at all.
+type Tool func(next ToolFunc) ToolFunc
+
+// ChainInference returns final wrapped in the middlewares, the first one
+// being the outermost.
+func ChainInference(final InferenceFunc, mws ...Inference) InferenceFunc {
+	for i := len(mws) - 1; i >= 0; i-- {
+		final = mws[i](final)
+	}
+	return final
+}
+
+// ChainTool returns final wrapped in the middlewares, the first one being
+// the outermost.
+func ChainTool(final ToolFunc, mws ...Tool) ToolFunc {
+	for i := len(mws) - 1; i >= 0; i-- {
+		final = mws[i](final)
+	}
+	return final
+}
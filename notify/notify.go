@@ -0,0 +1,96 @@
+// Package notify tells the user, who may have switched away during a long
+// run, that the agent finished or is waiting for them.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+type Notifier struct {
+	// Desktop shows a desktop notification, with osascript on macOS and
+	// notify-send elsewhere, ringing the terminal bell when neither works.
+	Desktop bool
+	// Webhook, when set, is sent a JSON {"text": ...} POST, the format of
+	// Slack's incoming webhooks.
+	Webhook string
+	Client  *http.Client
+}
+
+func New(desktop bool, webhook string) *Notifier {
+	return &Notifier{
+		Desktop: desktop,
+		Webhook: webhook,
+		Client:  http.DefaultClient,
+	}
+}
+
+// Send delivers the notification to every configured destination.
+func (n *Notifier) Send(ctx context.Context, title, message string) error {
+	var errs []error
+	if n.Desktop {
+		errs = append(errs, desktop(ctx, title, message))
+	}
+	if n.Webhook != "" {
+		errs = append(errs, n.post(ctx, title, message))
+	}
+	return errors.Join(errs...)
+}
+
+func desktop(ctx context.Context, title, message string) error {
+	var cmd *exec.Cmd
+	switch {
+	case runtime.GOOS == "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptQuote(message), appleScriptQuote(title))
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	default:
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			fmt.Fprint(os.Stdout, "\a")
+			return nil
+		}
+		cmd = exec.CommandContext(ctx, "notify-send", title, message)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("desktop notification failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (n *Notifier) post(ctx context.Context, title, message string) error {
+	body, err := json.Marshal(map[string]string{
+		"text": title + ": " + message,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		buf, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook failed: %s: %s", resp.Status, strings.TrimSpace(string(buf)))
+	}
+	return nil
+}
+
+func appleScriptQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
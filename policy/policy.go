@@ -0,0 +1,204 @@
+// Package policy denies tool calls on protected paths, such as the git
+// metadata, CI configuration and secrets.
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mschoch/dacs/middleware"
+	"github.com/mschoch/dacs/workspace"
+)
+
+// Rule denies the tools (all tools when empty) access to the paths matching
+// any of the globs. Globs are matched against paths relative to their
+// workspace root, * and ? do not match /, ** matches any number of
+// directories, and globs without a / match the file name in any directory.
+type Rule struct {
+	Paths  []string
+	Tools  []string
+	Reason string
+}
+
+// DefaultRules apply when no rules are configured.
+var DefaultRules = []Rule{
+	{
+		Paths:  []string{".git/**", ".github/workflows/**", ".gitlab-ci.yml", ".circleci/**", "Jenkinsfile"},
+		Tools:  []string{"edit_file", "replace_in_files", "generate_from_template", "extract_archive", "create_archive", "edit_notebook"},
+		Reason: "version control metadata and CI configuration are protected",
+	},
+	{
+		Paths:  []string{"*.pem", "*.key", "*.p12", "id_rsa*", "id_ed25519*", ".env", ".env.*", "secrets/**"},
+		Reason: "secrets are protected",
+	},
+}
+
+// pathKeys are the tool input fields holding paths, their values may be a
+// string or a list of strings. Paths inside of shell commands are not
+// checked.
+var pathKeys = []string{"path", "paths", "file", "files", "dir", "directory", "source", "destination"}
+
+type Policy struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	res []*regexp.Regexp
+}
+
+func New(rules []Rule) (*Policy, error) {
+	p := &Policy{}
+	for _, r := range rules {
+		cr := compiledRule{Rule: r}
+		for _, glob := range r.Paths {
+			re, err := globRegexp(glob)
+			if err != nil {
+				return nil, fmt.Errorf("invalid policy path %q: %v", glob, err)
+			}
+			cr.res = append(cr.res, re)
+		}
+		p.rules = append(p.rules, cr)
+	}
+	return p, nil
+}
+
+// Check returns an error describing the violation if the tool call is
+// denied.
+func (p *Policy) Check(ws *workspace.Workspace, tool string, input json.RawMessage) error {
+	var fields map[string]any
+	if json.Unmarshal(input, &fields) != nil {
+		return nil
+	}
+	for _, path := range inputPaths(fields) {
+		if err := p.CheckPath(ws, tool, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckPath returns an error describing the violation if the tool is
+// denied access to the path, for tools finding the paths they access
+// themselves, such as in a directory or an archive.
+func (p *Policy) CheckPath(ws *workspace.Workspace, tool, path string) error {
+	rels := relativePaths(ws, path)
+	for _, r := range p.rules {
+		if len(r.Tools) > 0 && !slices.Contains(r.Tools, tool) {
+			continue
+		}
+		for n, re := range r.res {
+			if slices.ContainsFunc(rels, re.MatchString) {
+				reason := r.Reason
+				if reason == "" {
+					reason = "the path is protected"
+				}
+				return fmt.Errorf("%s on %s is denied by the policy rule %q: %s", tool, path, r.Paths[n], reason)
+			}
+		}
+	}
+	return nil
+}
+
+// Describe returns the rules, one per line.
+func (p *Policy) Describe() []string {
+	var rv []string
+	for _, r := range p.rules {
+		tools := "all tools"
+		if len(r.Tools) > 0 {
+			tools = strings.Join(r.Tools, ", ")
+		}
+		line := fmt.Sprintf("%s denied on %s", tools, strings.Join(r.Paths, ", "))
+		if r.Reason != "" {
+			line += ": " + r.Reason
+		}
+		rv = append(rv, line)
+	}
+	return rv
+}
+
+// Middleware reports a denied tool call to the model as its result, without
+// running it.
+func (p *Policy) Middleware(next middleware.ToolFunc) middleware.ToolFunc {
+	return func(ctx context.Context, call middleware.ToolCall) (string, error) {
+		if err := p.Check(workspace.FromContext(ctx), call.Name, call.Input); err != nil {
+			return fmt.Sprintf("policy violation: %v. Do not try to work around this policy, ask the user if the change is needed.", err), nil
+		}
+		return next(ctx, call)
+	}
+}
+
+func inputPaths(fields map[string]any) []string {
+	var rv []string
+	for _, key := range pathKeys {
+		switch v := fields[key].(type) {
+		case string:
+			rv = append(rv, v)
+		case []any:
+			for _, e := range v {
+				if s, ok := e.(string); ok {
+					rv = append(rv, s)
+				}
+			}
+		}
+	}
+	return rv
+}
+
+// relativePaths returns the path relative to its workspace root, with /
+// separators, and the path it is a symlink to, if so, relative likewise,
+// so that links to protected paths are protected too. It returns the
+// cleaned path if it does not resolve.
+func relativePaths(ws *workspace.Workspace, path string) []string {
+	root, abs, err := ws.Resolve(path)
+	if err != nil {
+		return []string{filepath.ToSlash(filepath.Clean(path))}
+	}
+	var rv []string
+	for _, p := range []string{abs, realPath(root, abs)} {
+		if rel, err := filepath.Rel(root.Path, p); err == nil && !slices.Contains(rv, filepath.ToSlash(rel)) {
+			rv = append(rv, filepath.ToSlash(rel))
+		}
+	}
+	return rv
+}
+
+// realPath returns abs with symlinks resolved, or abs if that fails.
+func realPath(root *workspace.Root, abs string) string {
+	if real, err := root.FS.RealPath(abs); err == nil {
+		return real
+	}
+	return abs
+}
+
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	glob = strings.TrimPrefix(filepath.ToSlash(glob), "/")
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	}
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
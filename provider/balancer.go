@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/style"
+)
+
+// load balancing strategies of a Balancer
+const (
+	RoundRobin  = "round-robin"
+	LeastLoaded = "least-loaded"
+)
+
+// a host not telling what it runs by then is taken as the most loaded
+const psTimeout = 2 * time.Second
+
+// Balancer is a Provider spreading requests over a pool of Ollama hosts,
+// taking turns (RoundRobin) or picking the one with the fewest of its
+// requests in flight, then preferably with the model loaded already and
+// then with the fewest models loaded, by /api/ps (LeastLoaded). A request
+// to a host that cannot be reached is sent to the next one.
+type Balancer struct {
+	Backends []Backend
+	Strategy string
+
+	m        sync.Mutex
+	next     int
+	inFlight []int
+}
+
+func NewBalancer(strategy string, backends ...Backend) (*Balancer, error) {
+	switch strategy {
+	case "":
+		strategy = RoundRobin
+	case RoundRobin, LeastLoaded:
+	default:
+		return nil, fmt.Errorf("unknown balance strategy %q, expected %s or %s", strategy, RoundRobin, LeastLoaded)
+	}
+	return &Balancer{
+		Backends: backends,
+		Strategy: strategy,
+		inFlight: make([]int, len(backends)),
+	}, nil
+}
+
+func (b *Balancer) Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	delivered := false
+	deliver := func(resp api.ChatResponse) error {
+		delivered = true
+		return fn(resp)
+	}
+	order := b.order(ctx, req.Model)
+	var err error
+	for n, i := range order {
+		b.track(i, 1)
+		err = b.Backends[i].Provider.Chat(ctx, req, deliver)
+		b.track(i, -1)
+		if err == nil || delivered || ctx.Err() != nil || !connectionLost(err) {
+			return err
+		}
+		if n+1 < len(order) {
+			fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("Warning")), i18n.T("%s does not answer (%v), trying %s", b.Backends[i].Name, err, b.Backends[order[n+1]].Name))
+		}
+	}
+	return err
+}
+
+func (b *Balancer) track(i, delta int) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.inFlight[i] += delta
+}
+
+// order returns the backends to try for a request for the model, the
+// picked one first and then the others in turn.
+func (b *Balancer) order(ctx context.Context, model string) []int {
+	n := len(b.Backends)
+	b.m.Lock()
+	first := b.next
+	b.next = (b.next + 1) % n
+	inFlight := slices.Clone(b.inFlight)
+	b.m.Unlock()
+	rv := make([]int, n)
+	for i := range rv {
+		rv[i] = (first + i) % n
+	}
+	if b.Strategy != LeastLoaded {
+		return rv
+	}
+
+	type load struct {
+		inFlight  int
+		unloaded  bool
+		running   int
+		reachable bool
+	}
+	loads := make([]load, n)
+	var wg sync.WaitGroup
+	for i, backend := range b.Backends {
+		loads[i].inFlight = inFlight[i]
+		rm, ok := backend.Provider.(RunningModels)
+		if !ok {
+			loads[i].unloaded, loads[i].reachable = true, true
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, psTimeout)
+			defer cancel()
+			res, err := rm.ListRunning(ctx)
+			if err != nil {
+				return
+			}
+			loads[i] = load{inFlight: inFlight[i], unloaded: true, running: len(res.Models), reachable: true}
+			for _, m := range res.Models {
+				if SameModel(m.Name, model) || SameModel(m.Model, model) {
+					loads[i].unloaded = false
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	// stable, so ties are broken in turn
+	slices.SortStableFunc(rv, func(i, j int) int {
+		li, lj := loads[i], loads[j]
+		switch {
+		case li.reachable != lj.reachable:
+			return boolCmp(!li.reachable, !lj.reachable)
+		case li.inFlight != lj.inFlight:
+			return li.inFlight - lj.inFlight
+		case li.unloaded != lj.unloaded:
+			return boolCmp(li.unloaded, lj.unloaded)
+		}
+		return li.running - lj.running
+	})
+	return rv
+}
+
+func boolCmp(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
+
+// Show describes the model with the first host that can.
+func (b *Balancer) Show(ctx context.Context, req *api.ShowRequest) (*api.ShowResponse, error) {
+	err := errors.New("no host can describe models")
+	for _, backend := range b.Backends {
+		mi, ok := backend.Provider.(ModelInfo)
+		if !ok {
+			continue
+		}
+		var res *api.ShowResponse
+		if res, err = mi.Show(ctx, req); err == nil {
+			return res, nil
+		}
+	}
+	return nil, err
+}
+
+// List lists the models every reachable host has, so a model missing on
+// any is pulled.
+func (b *Balancer) List(ctx context.Context) (*api.ListResponse, error) {
+	var rv *api.ListResponse
+	err := errors.New("no host can list models")
+	for _, backend := range b.Backends {
+		mm, ok := backend.Provider.(ModelManager)
+		if !ok {
+			continue
+		}
+		res, err2 := mm.List(ctx)
+		if err2 != nil {
+			err = err2
+			continue
+		}
+		if rv == nil {
+			rv = res
+			continue
+		}
+		rv.Models = slices.DeleteFunc(rv.Models, func(m api.ListModelResponse) bool {
+			return !slices.ContainsFunc(res.Models, func(o api.ListModelResponse) bool { return o.Name == m.Name })
+		})
+	}
+	if rv == nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// Pull pulls a model to every host.
+func (b *Balancer) Pull(ctx context.Context, req *api.PullRequest, fn api.PullProgressFunc) error {
+	var errs []error
+	for _, backend := range b.Backends {
+		mm, ok := backend.Provider.(ModelManager)
+		if !ok {
+			continue
+		}
+		if err := mm.Pull(ctx, req, fn); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", backend.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ListRunning lists the models loaded by all hosts.
+func (b *Balancer) ListRunning(ctx context.Context) (*api.ProcessResponse, error) {
+	rv := &api.ProcessResponse{}
+	err := errors.New("no host can list running models")
+	answered := false
+	for _, backend := range b.Backends {
+		rm, ok := backend.Provider.(RunningModels)
+		if !ok {
+			continue
+		}
+		res, err2 := rm.ListRunning(ctx)
+		if err2 != nil {
+			err = err2
+			continue
+		}
+		answered = true
+		rv.Models = append(rv.Models, res.Models...)
+	}
+	if !answered {
+		return nil, err
+	}
+	return rv, nil
+}
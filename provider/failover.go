@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/style"
+)
+
+// DefaultAttempts is how often a provider is tried before failing over.
+const DefaultAttempts = 2
+
+// Backend is a provider in a failover chain, Model replaces the requested
+// model when set.
+type Backend struct {
+	Name     string
+	Provider Provider
+	Model    string
+}
+
+// Failover is a Provider that sends requests to the first backend until it
+// fails Attempts times in a row, each attempt limited to Timeout (if set),
+// and then fails over to the next one for good.
+type Failover struct {
+	Backends []Backend
+	Attempts int
+	Timeout  time.Duration
+
+	// current is shared by the requests of parallel workers
+	m       sync.Mutex
+	current int
+}
+
+func NewFailover(backends ...Backend) *Failover {
+	return &Failover{
+		Backends: backends,
+		Attempts: DefaultAttempts,
+	}
+}
+
+func (f *Failover) Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	// a streamed response cut short is not tried again, its parts were
+	// passed on already
+	delivered := false
+	deliver := func(resp api.ChatResponse) error {
+		delivered = true
+		return fn(resp)
+	}
+	for i := f.backend(); ; i++ {
+		b := f.Backends[i]
+		r := *req
+		if b.Model != "" {
+			r.Model = b.Model
+		}
+		var err error
+		for attempt := 0; attempt < max(f.Attempts, 1); attempt++ {
+			err = f.chat(ctx, b.Provider, &r, deliver)
+			if err == nil || ctx.Err() != nil || delivered {
+				return err
+			}
+		}
+		if i+1 >= len(f.Backends) {
+			// stay on the last backend, there is nothing left to fail over to
+			return err
+		}
+		fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("Warning")), i18n.T("%s failed %d times (%v), failing over to %s", b.Name, max(f.Attempts, 1), err, f.Backends[i+1].Name))
+		f.failOver(i)
+	}
+}
+
+// backend returns the index of the current backend.
+func (f *Failover) backend() int {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.current
+}
+
+// failOver makes the backend after the ith the current one, unless another
+// request failed over already.
+func (f *Failover) failOver(i int) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.current = max(f.current, i+1)
+}
+
+func (f *Failover) chat(ctx context.Context, p Provider, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+	return p.Chat(ctx, req, fn)
+}
+
+// Show describes the model with the current backend, if it can.
+func (f *Failover) Show(ctx context.Context, req *api.ShowRequest) (*api.ShowResponse, error) {
+	b := f.Backends[f.backend()]
+	mi, ok := b.Provider.(ModelInfo)
+	if !ok {
+		return nil, fmt.Errorf("%s cannot describe models", b.Name)
+	}
+	r := *req
+	if b.Model != "" {
+		r.Model = b.Model
+	}
+	return mi.Show(ctx, &r)
+}
+
+// List lists the models of the current backend, if it can.
+func (f *Failover) List(ctx context.Context) (*api.ListResponse, error) {
+	b := f.Backends[f.backend()]
+	mm, ok := b.Provider.(ModelManager)
+	if !ok {
+		return nil, fmt.Errorf("%s cannot list models", b.Name)
+	}
+	return mm.List(ctx)
+}
+
+// Pull pulls a model to the current backend, if it can.
+func (f *Failover) Pull(ctx context.Context, req *api.PullRequest, fn api.PullProgressFunc) error {
+	b := f.Backends[f.backend()]
+	mm, ok := b.Provider.(ModelManager)
+	if !ok {
+		return fmt.Errorf("%s cannot pull models", b.Name)
+	}
+	return mm.Pull(ctx, req, fn)
+}
+
+// ListRunning lists the models loaded by the current backend, if it can.
+func (f *Failover) ListRunning(ctx context.Context) (*api.ProcessResponse, error) {
+	b := f.Backends[f.backend()]
+	rm, ok := b.Provider.(RunningModels)
+	if !ok {
+		return nil, fmt.Errorf("%s cannot list running models", b.Name)
+	}
+	return rm.ListRunning(ctx)
+}
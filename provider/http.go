@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// HTTPOptions configure the connection to an API behind a reverse proxy,
+// the zero value connects as http.DefaultClient does.
+type HTTPOptions struct {
+	// CA is a PEM bundle of certificate authorities to trust, in addition
+	// to the system's.
+	CA string
+	// Cert and Key are the PEM files of a client certificate.
+	Cert string
+	Key  string
+	// Proxy is the URL of the proxy to connect through, by default that of
+	// $HTTPS_PROXY or $HTTP_PROXY, unless excluded by $NO_PROXY.
+	Proxy string
+	// Headers are set on every request, such as Authorization.
+	Headers map[string]string
+	// APIKey, when set, is sent as a bearer token with every request.
+	APIKey string
+}
+
+// Client returns the HTTP client to connect with.
+func (o HTTPOptions) Client() (*http.Client, error) {
+	if o.CA == "" && o.Cert == "" && o.Key == "" && o.Proxy == "" && len(o.Headers) == 0 && o.APIKey == "" {
+		return http.DefaultClient, nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if o.CA != "" || o.Cert != "" || o.Key != "" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if o.CA != "" {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			buf, err := os.ReadFile(o.CA)
+			if err != nil {
+				return nil, err
+			}
+			if !pool.AppendCertsFromPEM(buf) {
+				return nil, fmt.Errorf("no certificates found in %s", o.CA)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if o.Cert != "" || o.Key != "" {
+			cert, err := tls.LoadX509KeyPair(o.Cert, o.Key)
+			if err != nil {
+				return nil, fmt.Errorf("error loading the client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	if o.Proxy != "" {
+		proxy, err := url.Parse(o.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url %q: %w", Redacted(o.Proxy), err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	var rt http.RoundTripper = transport
+	if len(o.Headers) > 0 || o.APIKey != "" {
+		headers := maps.Clone(o.Headers)
+		if o.APIKey != "" {
+			if headers == nil {
+				headers = map[string]string{}
+			}
+			headers["Authorization"] = "Bearer " + o.APIKey
+		}
+		rt = headerTransport{headers: headers, next: transport}
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+// headerTransport sets the headers on every request.
+type headerTransport struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// Redacted returns the URL with the password of its credentials, if any,
+// replaced by xxxxx, for messages.
+func Redacted(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
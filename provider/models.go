@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// ModelManager is implemented by providers that can list and pull models,
+// *api.Client satisfies it.
+type ModelManager interface {
+	List(ctx context.Context) (*api.ListResponse, error)
+	Pull(ctx context.Context, req *api.PullRequest, fn api.PullProgressFunc) error
+}
+
+// HasModel reports whether the model is available.
+func HasModel(ctx context.Context, mm ModelManager, model string) (bool, error) {
+	res, err := mm.List(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range res.Models {
+		if SameModel(m.Name, model) || SameModel(m.Model, model) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SameModel reports whether the names refer to the same model, a model
+// without a tag is the latest.
+func SameModel(a, b string) bool {
+	return withTag(a) == withTag(b)
+}
+
+func withTag(name string) string {
+	if !strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+		name += ":latest"
+	}
+	return name
+}
+
+// RunningModels is implemented by providers that report the models loaded
+// and how much of them is in VRAM, *api.Client satisfies it.
+type RunningModels interface {
+	ListRunning(ctx context.Context) (*api.ProcessResponse, error)
+}
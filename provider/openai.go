@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// OpenAI is a Provider for OpenAI compatible chat completion endpoints,
+// such as vLLM, llama.cpp's server or LM Studio. Requests and responses
+// are translated from and to the Ollama API.
+type OpenAI struct {
+	// BaseURL is the URL the /chat/completions path is appended to, for
+	// example http://localhost:8000/v1.
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+}
+
+func NewOpenAI(baseURL, apiKey string) *OpenAI {
+	return &OpenAI{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		APIKey:  apiKey,
+		Client:  http.DefaultClient,
+	}
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIRequest struct {
+	Model          string          `json:"model"`
+	Messages       []openAIMessage `json:"messages"`
+	Tools          api.Tools       `json:"tools,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	ResponseFormat map[string]any  `json:"response_format,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+func (o *OpenAI) Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	oreq := openAIRequest{
+		Model:    req.Model,
+		Messages: openAIMessages(req.Messages),
+		Tools:    req.Tools,
+	}
+	if t, ok := req.Options["temperature"].(float64); ok {
+		oreq.Temperature = &t
+	}
+	if n, ok := req.Options["num_predict"].(int); ok && n > 0 {
+		oreq.MaxTokens = n
+	}
+	switch format := bytes.TrimSpace(req.Format); {
+	case len(format) == 0:
+	case string(format) == `"json"`:
+		oreq.ResponseFormat = map[string]any{"type": "json_object"}
+	default:
+		oreq.ResponseFormat = map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   "answer",
+				"schema": json.RawMessage(format),
+			},
+		}
+	}
+
+	body, err := json.Marshal(oreq)
+	if err != nil {
+		return err
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		hreq.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+	resp, err := o.Client.Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("chat completion failed: %s: %s", resp.Status, strings.TrimSpace(string(buf)))
+	}
+
+	var ores openAIResponse
+	if err = json.Unmarshal(buf, &ores); err != nil {
+		return fmt.Errorf("error parsing chat completion: %v", err)
+	}
+	if len(ores.Choices) == 0 {
+		return fmt.Errorf("chat completion returned no choices")
+	}
+	choice := ores.Choices[0]
+	msg := api.Message{
+		Role:    "assistant",
+		Content: choice.Message.Content,
+	}
+	for n, tc := range choice.Message.ToolCalls {
+		var args api.ToolCallFunctionArguments
+		if err = json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+			return fmt.Errorf("invalid arguments for tool call %s: %v", tc.Function.Name, err)
+		}
+		msg.ToolCalls = append(msg.ToolCalls, api.ToolCall{Function: api.ToolCallFunction{
+			Index:     n,
+			Name:      tc.Function.Name,
+			Arguments: args,
+		}})
+	}
+	// the token counts are left unset, num_ctx does not apply to these
+	// servers so they cannot be used to detect a truncated prompt
+	return fn(api.ChatResponse{
+		Model:      req.Model,
+		CreatedAt:  time.Now(),
+		Message:    msg,
+		DoneReason: choice.FinishReason,
+		Done:       true,
+	})
+}
+
+// openAIMessages translates the conversation. The agent sends tool results
+// as the user messages following the call, they become tool messages
+// answering the calls in order.
+func openAIMessages(msgs []api.Message) []openAIMessage {
+	var rv []openAIMessage
+	var pending []string
+	for n, m := range msgs {
+		om := openAIMessage{Role: m.Role, Content: m.Content}
+		if m.Role == "user" && len(pending) > 0 {
+			om.Role, om.ToolCallID = "tool", pending[0]
+			pending = pending[1:]
+		}
+		if m.Role == "assistant" {
+			pending = nil
+			for i, tc := range m.ToolCalls {
+				args, _ := json.Marshal(tc.Function.Arguments)
+				otc := openAIToolCall{ID: fmt.Sprintf("call_%d_%d", n, i), Type: "function"}
+				otc.Function.Name = tc.Function.Name
+				otc.Function.Arguments = string(args)
+				om.ToolCalls = append(om.ToolCalls, otc)
+				pending = append(pending, otc.ID)
+			}
+		}
+		rv = append(rv, om)
+	}
+	return rv
+}
@@ -0,0 +1,215 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/style"
+)
+
+// Priority orders the requests waiting for a busy backend.
+type Priority int
+
+const (
+	// Interactive requests are those the user waits for, they go first.
+	Interactive Priority = iota
+	// Background requests are those of sub-agents.
+	Background
+)
+
+const (
+	// how often a request retries at most while first in line, and how
+	// often the others look for their turn
+	maxQueueDelay  = 10 * time.Second
+	queuePollDelay = time.Second
+)
+
+type priorityKey struct{}
+type queueStatusKey struct{}
+
+// WithPriority returns a context whose requests wait in line with the
+// priority, by default they are Interactive.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// WithQueueStatus returns a context whose requests report their place in
+// line to status, and "" once it is their turn. By default it is printed.
+func WithQueueStatus(ctx context.Context, status func(string)) context.Context {
+	return context.WithValue(ctx, queueStatusKey{}, status)
+}
+
+// Queue is a Provider that, when the backend answers it is busy, holds the
+// request in line, Interactive ones first, retrying the first in line with
+// a backoff. New requests join the end of their priority while any wait.
+type Queue struct {
+	Provider Provider
+
+	m       sync.Mutex
+	waiting []*waiter
+	// average is of the requests' durations, for the estimated wait
+	average time.Duration
+}
+
+type waiter struct {
+	priority Priority
+}
+
+func NewQueue(p Provider) *Queue {
+	return &Queue{Provider: p}
+}
+
+func (q *Queue) Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	q.m.Lock()
+	queued := len(q.waiting) > 0
+	q.m.Unlock()
+	if !queued {
+		if retry, err := q.try(ctx, req, fn); !retry {
+			return err
+		}
+	}
+
+	priority, _ := ctx.Value(priorityKey{}).(Priority)
+	w := q.enqueue(priority)
+	defer q.leave(w)
+	status, _ := ctx.Value(queueStatusKey{}).(func(string))
+	shown := ""
+	show := func(s string) {
+		if s == shown {
+			return
+		}
+		shown = s
+		if status != nil {
+			status(s)
+		} else if s != "" {
+			fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("Waiting")), s)
+		}
+	}
+	defer show("")
+	delay := queuePollDelay
+	for {
+		position, eta := q.position(w)
+		s := fmt.Sprintf("%s is busy, position %d in line", req.Model, position)
+		if eta >= time.Second {
+			s += fmt.Sprintf(", about %s", eta.Round(time.Second))
+		}
+		show(s)
+		wait := queuePollDelay
+		if position == 1 {
+			if retry, err := q.try(ctx, req, fn); !retry {
+				return err
+			}
+			wait = delay
+			delay = min(2*delay, maxQueueDelay)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// try sends the request, retry is set when the backend was busy.
+func (q *Queue) try(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) (retry bool, err error) {
+	start := time.Now()
+	err = q.Provider.Chat(ctx, req, fn)
+	if busy(err) {
+		return true, err
+	}
+	if err == nil {
+		q.m.Lock()
+		if q.average == 0 {
+			q.average = time.Since(start)
+		} else {
+			q.average = (3*q.average + time.Since(start)) / 4
+		}
+		q.m.Unlock()
+	}
+	return false, err
+}
+
+func (q *Queue) enqueue(p Priority) *waiter {
+	q.m.Lock()
+	defer q.m.Unlock()
+	w := &waiter{priority: p}
+	i := len(q.waiting)
+	for i > 0 && q.waiting[i-1].priority > p {
+		i--
+	}
+	q.waiting = slices.Insert(q.waiting, i, w)
+	return w
+}
+
+func (q *Queue) leave(w *waiter) {
+	q.m.Lock()
+	defer q.m.Unlock()
+	q.waiting = slices.DeleteFunc(q.waiting, func(o *waiter) bool { return o == w })
+}
+
+// position returns the place of w in line, and the estimated wait.
+func (q *Queue) position(w *waiter) (int, time.Duration) {
+	q.m.Lock()
+	defer q.m.Unlock()
+	position := slices.Index(q.waiting, w) + 1
+	return position, time.Duration(position) * q.average
+}
+
+// busy reports whether the error is the backend refusing a request for
+// being overloaded, such as Ollama's when its queue is full.
+func busy(err error) bool {
+	if err == nil {
+		return false
+	}
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusServiceUnavailable || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "503 Service Unavailable") || strings.Contains(msg, "429 Too Many Requests") || strings.Contains(msg, "server busy")
+}
+
+// Show describes the model, if the backend can.
+func (q *Queue) Show(ctx context.Context, req *api.ShowRequest) (*api.ShowResponse, error) {
+	mi, ok := q.Provider.(ModelInfo)
+	if !ok {
+		return nil, errors.New("the backend cannot describe models")
+	}
+	return mi.Show(ctx, req)
+}
+
+// List lists the models, if the backend can.
+func (q *Queue) List(ctx context.Context) (*api.ListResponse, error) {
+	mm, ok := q.Provider.(ModelManager)
+	if !ok {
+		return nil, errors.New("the backend cannot list models")
+	}
+	return mm.List(ctx)
+}
+
+// Pull pulls a model, if the backend can.
+func (q *Queue) Pull(ctx context.Context, req *api.PullRequest, fn api.PullProgressFunc) error {
+	mm, ok := q.Provider.(ModelManager)
+	if !ok {
+		return errors.New("the backend cannot pull models")
+	}
+	return mm.Pull(ctx, req, fn)
+}
+
+// ListRunning lists the loaded models, if the backend can.
+func (q *Queue) ListRunning(ctx context.Context) (*api.ProcessResponse, error) {
+	rm, ok := q.Provider.(RunningModels)
+	if !ok {
+		return nil, errors.New("the backend cannot list running models")
+	}
+	return rm.ListRunning(ctx)
+}
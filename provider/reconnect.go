@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/i18n"
+	"github.com/mschoch/dacs/style"
+)
+
+const (
+	// DefaultReconnectWait is how long Reconnecting tries to reconnect.
+	DefaultReconnectWait = 30 * time.Second
+
+	// the time to an Ollama that is up is short, give a dead one little
+	pingTimeout = 5 * time.Second
+)
+
+// Ping checks that Ollama answers at host.
+func Ping(ctx context.Context, c *api.Client, host string) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if _, err := c.Version(ctx); err != nil {
+		return fmt.Errorf("no answer from Ollama at %s (%v), is it running (ollama serve) and is OLLAMA_HOST right?", Redacted(host), err)
+	}
+	return nil
+}
+
+// Reconnecting is an Ollama client that, when the connection drops, waits
+// up to Wait for Ollama to answer again and repeats the request, unless
+// part of its response was passed on already.
+type Reconnecting struct {
+	*api.Client
+	Host string
+	Wait time.Duration
+}
+
+func NewReconnecting(c *api.Client, host string) *Reconnecting {
+	return &Reconnecting{Client: c, Host: Redacted(host), Wait: DefaultReconnectWait}
+}
+
+func (r *Reconnecting) Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	delivered := false
+	deliver := func(resp api.ChatResponse) error {
+		delivered = true
+		return fn(resp)
+	}
+	err := r.Client.Chat(ctx, req, deliver)
+	if err == nil || delivered || ctx.Err() != nil || !connectionLost(err) {
+		return err
+	}
+	fmt.Printf("%s: %s\n", style.Label(style.Yellow, i18n.T("Warning")), i18n.T("lost the connection to Ollama at %s (%v), reconnecting", r.Host, err))
+	if err := r.reconnect(ctx); err != nil {
+		return i18n.Errorf("lost the connection to Ollama at %s: %w", r.Host, err)
+	}
+	fmt.Println(i18n.T("reconnected"))
+	return r.Client.Chat(ctx, req, deliver)
+}
+
+// reconnect waits for Ollama to answer, backing off from a second to five
+// between tries.
+func (r *Reconnecting) reconnect(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.Wait)
+	defer cancel()
+	delay := time.Second
+	for {
+		_, err := r.Client.Version(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no answer for %s: %w", r.Wait, err)
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, 5*time.Second)
+	}
+}
+
+// connectionLost reports whether the error is that of a connection refused
+// or dropped, rather than an error of Ollama.
+func connectionLost(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,241 @@
+// Package render renders the model's markdown for display in a terminal.
+package render
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2/quick"
+	"golang.org/x/term"
+
+	"github.com/mschoch/dacs/style"
+)
+
+const (
+	reset  = "\u001b[0m"
+	bold   = "\u001b[1m"
+	dim    = "\u001b[2m"
+	italic = "\u001b[3m"
+	cyan   = "\u001b[36m"
+
+	DefaultStyle = "monokai"
+)
+
+type Renderer struct {
+	// Width wraps prose at the given number of columns, code blocks are
+	// never wrapped. 0 wraps at the width of the terminal, when output is
+	// to one, and a negative width disables wrapping.
+	Width int
+	// Style is the chroma style used for code blocks.
+	Style string
+	// CodeBlocks holds every code block rendered so far, each is labeled
+	// with its 1-based position so it can be referred to later.
+	CodeBlocks []string
+}
+
+func New() *Renderer {
+	return &Renderer{
+		Style: DefaultStyle,
+	}
+}
+
+var (
+	fenceRe    = regexp.MustCompile("^\\s*(```|~~~)\\s*([\\w+#.-]*)")
+	headingRe  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletRe   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	numberedRe = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	ruleRe     = regexp.MustCompile(`^\s*([-*_])(\s*([-*_]))(\s*([-*_]))+\s*$`)
+	tableSepRe = regexp.MustCompile(`^\s*\|?(\s*:?-+:?\s*\|)+\s*:?-*:?\s*$`)
+	boldRe     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicRe   = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*)\*`)
+	codeRe     = regexp.MustCompile("`([^`]+)`")
+	ansiRe     = regexp.MustCompile("\u001b\\[[0-9;]*m")
+)
+
+// Render returns markdown formatted with ANSI escape sequences, or as plain
+// text with style.Plain.
+func (r *Renderer) Render(markdown string) string {
+	if style.Plain {
+		return ansiRe.ReplaceAllString(r.render(markdown), "")
+	}
+	return r.render(markdown)
+}
+
+func (r *Renderer) render(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]) {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			out = append(out, r.codeBlock(strings.Join(code, "\n"), m[2]))
+			continue
+		}
+
+		if isTableRow(line) && i+1 < len(lines) && tableSepRe.MatchString(lines[i+1]) {
+			rows := [][]string{splitRow(line)}
+			for i += 2; i < len(lines) && isTableRow(lines[i]); i++ {
+				rows = append(rows, splitRow(lines[i]))
+			}
+			i--
+			out = append(out, r.table(rows))
+			continue
+		}
+
+		switch {
+		case ruleRe.MatchString(line):
+			out = append(out, dim+strings.Repeat("─", r.ruleWidth())+reset)
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			out = append(out, bold+inline(m[2])+reset)
+		case bulletRe.MatchString(line):
+			m := bulletRe.FindStringSubmatch(line)
+			out = append(out, r.wrap(inline(m[2]), m[1]+"• ", m[1]+"  "))
+		case numberedRe.MatchString(line):
+			m := numberedRe.FindStringSubmatch(line)
+			hang := m[1] + strings.Repeat(" ", len(m[2])+1)
+			out = append(out, r.wrap(inline(m[3]), m[1]+m[2]+" ", hang))
+		case strings.HasPrefix(line, ">"):
+			text := strings.TrimSpace(strings.TrimPrefix(line, ">"))
+			out = append(out, r.wrap(dim+inline(text)+reset, dim+"│ "+reset, dim+"│ "+reset))
+		default:
+			out = append(out, r.wrap(inline(line), "", ""))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func (r *Renderer) codeBlock(code, lang string) string {
+	r.CodeBlocks = append(r.CodeBlocks, code)
+	label := fmt.Sprintf("%s[%d] %s%s", dim, len(r.CodeBlocks), lang, reset)
+
+	if style.Plain {
+		return label + "\n" + code
+	}
+	if lang == "" {
+		lang = "plaintext"
+	}
+	var sb strings.Builder
+	err := quick.Highlight(&sb, code, lang, "terminal256", r.Style)
+	if err != nil {
+		return label + "\n" + code
+	}
+	return label + "\n" + strings.TrimSuffix(sb.String(), "\n") + reset
+}
+
+func (r *Renderer) table(rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		for c, cell := range row {
+			if c >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[c] = max(widths[c], visibleLen(inline(cell)))
+		}
+	}
+
+	var out []string
+	for n, row := range rows {
+		var cells []string
+		for c := range widths {
+			cell := ""
+			if c < len(row) {
+				cell = inline(row[c])
+			}
+			cell += strings.Repeat(" ", widths[c]-visibleLen(cell))
+			if n == 0 {
+				cell = bold + cell + reset
+			}
+			cells = append(cells, cell)
+		}
+		out = append(out, strings.Join(cells, dim+" │ "+reset))
+		if n == 0 {
+			var seps []string
+			for _, w := range widths {
+				seps = append(seps, strings.Repeat("─", w))
+			}
+			out = append(out, dim+strings.Join(seps, "─┼─")+reset)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func (r *Renderer) ruleWidth() int {
+	if width := r.columns(); width > 0 {
+		return width
+	}
+	return 40
+}
+
+// columns returns the width prose is wrapped at, 0 when it is not.
+func (r *Renderer) columns() int {
+	if r.Width != 0 {
+		return max(r.Width, 0)
+	}
+	// as it is now, the terminal may have been resized
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// wrap breaks text into lines of at most columns visible columns, the
+// first line is prefixed with first and the rest with rest.
+func (r *Renderer) wrap(text, first, rest string) string {
+	width := r.columns()
+	if width <= 0 || visibleLen(first+text) <= width {
+		return first + text
+	}
+	var lines []string
+	line, prefix := "", first
+	for _, word := range strings.Fields(text) {
+		if line != "" && visibleLen(prefix+line+" "+word) > width {
+			lines = append(lines, prefix+line)
+			line, prefix = "", rest
+		}
+		if line == "" {
+			line = word
+		} else {
+			line += " " + word
+		}
+	}
+	lines = append(lines, prefix+line)
+	return strings.Join(lines, "\n")
+}
+
+func inline(s string) string {
+	s = codeRe.ReplaceAllString(s, cyan+"$1"+reset)
+	s = boldRe.ReplaceAllString(s, bold+"$1$2"+reset)
+	s = italicRe.ReplaceAllString(s, "$1"+italic+"$2"+reset)
+	return s
+}
+
+func isTableRow(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "|")
+}
+
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+func visibleLen(s string) int {
+	return utf8.RuneCountInString(ansiRe.ReplaceAllString(s, ""))
+}
@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-a * * * *",
+		"@yearly",
+	} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// a Thursday
+	from := time.Date(2026, 1, 1, 10, 7, 30, 0, time.UTC)
+	for _, test := range []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 1, 1, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"5,50 * * * *", time.Date(2026, 1, 1, 10, 50, 0, 0, time.UTC)},
+		{"0 8-10/2 * * *", time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"@nightly", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)},
+		// 7 is sunday too
+		{"0 12 * * 7", time.Date(2026, 1, 4, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 3 *", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// both days restricted, either one matching is enough
+		{"0 0 3 * 1", time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 10 * 1", time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
+		// a day starting with * is unrestricted, both have to match
+		{"0 0 */2 * 1", time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 3 * */7", time.Date(2026, 5, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	} {
+		c, err := ParseCron(test.spec)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", test.spec, err)
+			continue
+		}
+		if got := c.Next(from); !got.Equal(test.want) {
+			t.Errorf("%q: Next(%v) = %v, want %v", test.spec, from, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/mschoch/dacs/crypt"
+	"github.com/mschoch/dacs/i18n"
+)
+
+// RunFunc runs the task's prompt, writing its transcript to w.
+type RunFunc func(ctx context.Context, task Task, w io.Writer) error
+
+var ansiRe = regexp.MustCompile("\u001b\\[[0-9;]*[A-Za-z]")
+
+// Run runs the tasks in the schedule at path whenever they are due, until
+// the context is canceled. The schedule is re-read every minute so changes
+// apply without a restart. Each run's transcript is written to a report in
+// reports/<id>/, encrypted with the passphrase of crypt when encrypt is
+// set. Tasks run one at a time, runs missed while another task was running
+// happen as soon as it finishes.
+func Run(ctx context.Context, path, reports string, encrypt bool, run RunFunc) error {
+	last := time.Now()
+	for {
+		now := time.Now()
+		tasks, err := Load(path)
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			c, err := ParseCron(task.Cron)
+			if err != nil {
+				i18n.Printf("Error: task %d: %s\n", task.ID, err.Error())
+				continue
+			}
+			if next := c.Next(last); next.IsZero() || next.After(now) {
+				continue
+			}
+			report, err := runTask(ctx, task, reports, encrypt, run)
+			if err != nil {
+				i18n.Printf("Error: task %d: %s\n", task.ID, err.Error())
+			}
+			if report != "" {
+				fmt.Printf("task %d finished, report written to %s\n", task.ID, report)
+			}
+		}
+		last = now
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(now.Truncate(time.Minute).Add(time.Minute))):
+		}
+	}
+}
+
+func runTask(ctx context.Context, task Task, reports string, encrypt bool, run RunFunc) (string, error) {
+	dir := filepath.Join(reports, fmt.Sprint(task.ID))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, time.Now().Format("20060102-150405")+".log")
+	// an encrypted report is written at once when the run is over
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	if !encrypt {
+		f, err := os.Create(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	fmt.Fprintf(w, "task %d: %s\ncron: %s\ndir: %s\nstarted: %s\n\n", task.ID, task.Prompt, task.Cron, task.Dir, time.Now().Format(time.RFC3339))
+	runErr := run(ctx, task, &plainWriter{w: w})
+	if runErr != nil {
+		fmt.Fprintf(w, "\nfailed: %s\n", runErr.Error())
+	}
+	fmt.Fprintf(w, "\nfinished: %s\n", time.Now().Format(time.RFC3339))
+	if encrypt {
+		if err := crypt.WriteFile(path, buf.Bytes(), 0600, true); err != nil {
+			return "", err
+		}
+	}
+	return path, runErr
+}
+
+// plainWriter strips terminal escape sequences from the transcript. They
+// are assumed not to span writes, which holds for the agent's output.
+type plainWriter struct {
+	w io.Writer
+}
+
+func (p *plainWriter) Write(b []byte) (int, error) {
+	_, err := p.w.Write(ansiRe.ReplaceAll(b, nil))
+	return len(b), err
+}
@@ -0,0 +1,85 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Task is a prompt run headless, in Dir, whenever Cron matches.
+type Task struct {
+	ID     int    `yaml:"id"`
+	Cron   string `yaml:"cron"`
+	Prompt string `yaml:"prompt"`
+	Dir    string `yaml:"dir"`
+}
+
+// Path returns the location of the schedule, dacs/schedule.yaml in the
+// user's config directory.
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dacs", "schedule.yaml"), nil
+}
+
+// Load returns the tasks in the schedule at path, none if it does not exist.
+func Load(path string) ([]Task, error) {
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	if err = yaml.Unmarshal(buf, &tasks); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return tasks, nil
+}
+
+func Save(path string, tasks []Task) error {
+	buf, err := yaml.Marshal(tasks)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf, 0644)
+}
+
+// Add appends a task to the schedule at path, with the next free id.
+func Add(path string, task Task) (Task, error) {
+	if _, err := ParseCron(task.Cron); err != nil {
+		return task, err
+	}
+	tasks, err := Load(path)
+	if err != nil {
+		return task, err
+	}
+	task.ID = 1
+	for _, t := range tasks {
+		task.ID = max(task.ID, t.ID+1)
+	}
+	return task, Save(path, append(tasks, task))
+}
+
+// Remove deletes the task with the id from the schedule at path.
+func Remove(path string, id int) error {
+	tasks, err := Load(path)
+	if err != nil {
+		return err
+	}
+	for i, t := range tasks {
+		if t.ID == id {
+			return Save(path, append(tasks[:i], tasks[i+1:]...))
+		}
+	}
+	return fmt.Errorf("no task %d", id)
+}
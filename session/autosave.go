@@ -0,0 +1,99 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/crypt"
+)
+
+// Autosave is the conversation in a directory as saved after every turn
+// and tool call, to be restored after dacs crashed or lost its terminal.
+type Autosave struct {
+	Saved   time.Time `json:"saved"`
+	Dir     string    `json:"dir"`
+	Session *Session  `json:"session"`
+	// Pending are the results of the tool calls of the last round that
+	// ran before the save, not yet in the session.
+	Pending []api.Message `json:"pending,omitempty"`
+}
+
+// AutosavePath is where the session in dir is autosaved, in the user's
+// cache directory.
+func AutosavePath(dir string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(dir))
+	return filepath.Join(cacheDir, "dacs", "autosave", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// Autosave writes the session with the pending tool results to path,
+// replacing what was saved before, encrypted when encrypt is set.
+func (s *Session) Autosave(path, dir string, pending []api.Message, encrypt bool) error {
+	buf, err := json.Marshal(Autosave{
+		Saved:   time.Now(),
+		Dir:     dir,
+		Session: s,
+		Pending: pending,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := crypt.WriteFile(tmp, buf, 0600, encrypt); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadAutosave reads the session autosaved at path, nil when there is none.
+func LoadAutosave(path string) (*Autosave, error) {
+	buf, err := crypt.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var rv Autosave
+	if err := json.Unmarshal(buf, &rv); err != nil {
+		return nil, err
+	}
+	if rv.Session == nil {
+		return nil, nil
+	}
+	return &rv, nil
+}
+
+// Restore returns the autosaved session with the pending tool results, and
+// tells the model about the calls that never ran.
+func (a *Autosave) Restore() *Session {
+	s := a.Session
+	s.Append(a.Pending...)
+	calls, results := 0, 0
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if m := s.Messages[i]; m.Role == "assistant" {
+			calls = len(m.ToolCalls)
+			results = len(s.Messages) - 1 - i
+			break
+		}
+	}
+	if calls > results {
+		s.Append(api.Message{
+			Role:    "user",
+			Content: "dacs exited before the remaining tool calls of the last round ran, they have no results.",
+		})
+	}
+	return s
+}
@@ -0,0 +1,208 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/crypt"
+)
+
+// maxImportedResult is the length tool results from other tools are cut
+// to, they are history rather than something to work from.
+const maxImportedResult = 4000
+
+// Import reads the messages of a conversation held elsewhere: a session
+// exported by dacs, a Claude Code transcript (JSONL), an Aider chat history
+// or a markdown transcript with User and Assistant headings. It returns the
+// messages, without system prompts, and the name of the format. Encrypted
+// files are decrypted.
+func Import(path string) ([]api.Message, string, error) {
+	buf, err := crypt.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	trimmed := bytes.TrimSpace(buf)
+	var msgs []api.Message
+	var format string
+	switch {
+	case bytes.HasPrefix(trimmed, []byte(`{"`)) && bytes.Contains(trimmed, []byte(`"sessionId"`)):
+		format = "Claude Code"
+		msgs, err = importClaudeCode(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		format = "dacs"
+		var s Session
+		err = json.Unmarshal(trimmed, &s)
+		msgs = s.Messages
+	case bytes.HasPrefix(trimmed, []byte("# aider chat started")) || bytes.Contains(buf, []byte("\n#### ")):
+		format = "Aider"
+		msgs = importAider(string(buf))
+	default:
+		format = "markdown"
+		msgs = importMarkdown(string(buf))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading %s transcript %s: %w", format, path, err)
+	}
+	var rv []api.Message
+	for _, m := range msgs {
+		if m.Role != "system" {
+			rv = append(rv, m)
+		}
+	}
+	if len(rv) == 0 {
+		return nil, "", fmt.Errorf("no messages found in %s", path)
+	}
+	return rv, format, nil
+}
+
+type claudeCodeBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	Thinking  string          `json:"thinking"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+}
+
+// claudeCodeContent decodes content that is either a string or a list of
+// blocks.
+func claudeCodeContent(raw json.RawMessage) []claudeCodeBlock {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return []claudeCodeBlock{{Type: "text", Text: s}}
+	}
+	var blocks []claudeCodeBlock
+	_ = json.Unmarshal(raw, &blocks)
+	return blocks
+}
+
+// importClaudeCode converts the user and assistant entries of a Claude Code
+// session log. Its tools are not those of dacs, so calls and results are
+// kept as text rather than as tool calls the model could take for its own.
+func importClaudeCode(buf []byte) ([]api.Message, error) {
+	var msgs []api.Message
+	toolNames := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var entry struct {
+			Type        string `json:"type"`
+			IsSidechain bool   `json:"isSidechain"`
+			IsMeta      bool   `json:"isMeta"`
+			Message     struct {
+				Role    string          `json:"role"`
+				Content json.RawMessage `json:"content"`
+			} `json:"message"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, err
+		}
+		if (entry.Type != "user" && entry.Type != "assistant") || entry.IsSidechain || entry.IsMeta {
+			continue
+		}
+		var text, thinking, results []string
+		for _, b := range claudeCodeContent(entry.Message.Content) {
+			switch b.Type {
+			case "text":
+				text = append(text, b.Text)
+			case "thinking":
+				thinking = append(thinking, b.Thinking)
+			case "tool_use":
+				toolNames[b.ID] = b.Name
+				text = append(text, fmt.Sprintf("[called %s %s]", b.Name, b.Input))
+			case "tool_result":
+				var out []string
+				for _, rb := range claudeCodeContent(b.Content) {
+					if rb.Type == "text" {
+						out = append(out, rb.Text)
+					}
+				}
+				result := strings.Join(out, "\n")
+				if len(result) > maxImportedResult {
+					result = result[:maxImportedResult] + "\n[cut]"
+				}
+				results = append(results, fmt.Sprintf("[result of %s]\n%s", toolNames[b.ToolUseID], result))
+			}
+		}
+		content := strings.Join(append(text, results...), "\n")
+		if content == "" {
+			continue
+		}
+		// consecutive entries of the same role are parts of one message
+		if n := len(msgs); n > 0 && msgs[n-1].Role == entry.Type {
+			msgs[n-1].Content += "\n" + content
+			msgs[n-1].Thinking = strings.TrimSpace(msgs[n-1].Thinking + "\n" + strings.Join(thinking, "\n"))
+			continue
+		}
+		msgs = append(msgs, api.Message{Role: entry.Type, Content: content, Thinking: strings.Join(thinking, "\n")})
+	}
+	return msgs, scanner.Err()
+}
+
+// importAider converts an .aider.chat.history.md, where user input is on
+// lines starting with "#### ", aider's own output is quoted with "> " and
+// the rest is the model's.
+func importAider(history string) []api.Message {
+	var msgs []api.Message
+	add := func(role, line string) {
+		if n := len(msgs); n > 0 && msgs[n-1].Role == role {
+			msgs[n-1].Content += "\n" + line
+			return
+		}
+		msgs = append(msgs, api.Message{Role: role, Content: line})
+	}
+	for _, line := range strings.Split(history, "\n") {
+		switch {
+		case strings.HasPrefix(line, "# aider chat started"), strings.HasPrefix(line, ">"):
+		case strings.HasPrefix(line, "#### "):
+			add("user", strings.TrimPrefix(line, "#### "))
+		case len(msgs) > 0:
+			add("assistant", line)
+		}
+	}
+	return trimMessages(msgs)
+}
+
+var markdownRole = regexp.MustCompile(`(?i)^(?:#{1,6}\s*|\*\*)(user|human|you|me|assistant|ai|model|dacs|claude|aider)\b(?:\*\*)?\s*:?(?:\*\*)?\s*(.*)$`)
+
+// importMarkdown converts a transcript where each message starts with a
+// heading or bold line naming the speaker, such as "## User" or
+// "**Assistant:** text".
+func importMarkdown(transcript string) []api.Message {
+	var msgs []api.Message
+	for _, line := range strings.Split(transcript, "\n") {
+		if m := markdownRole.FindStringSubmatch(line); m != nil {
+			role := "assistant"
+			switch strings.ToLower(m[1]) {
+			case "user", "human", "you", "me":
+				role = "user"
+			}
+			msgs = append(msgs, api.Message{Role: role, Content: m[2]})
+			continue
+		}
+		if len(msgs) > 0 {
+			msgs[len(msgs)-1].Content += "\n" + line
+		}
+	}
+	return trimMessages(msgs)
+}
+
+// trimMessages trims the content of the messages, dropping empty ones.
+func trimMessages(msgs []api.Message) []api.Message {
+	var rv []api.Message
+	for _, m := range msgs {
+		m.Content = strings.TrimSpace(m.Content)
+		if m.Content != "" {
+			rv = append(rv, m)
+		}
+	}
+	return rv
+}
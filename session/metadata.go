@@ -0,0 +1,93 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Metadata is what is kept of every session for dacs stats: the statistics
+// of its tasks, none of the conversation.
+type Metadata struct {
+	Started time.Time   `json:"started"`
+	Dir     string      `json:"dir"`
+	Tasks   []TaskStats `json:"tasks"`
+}
+
+// MetadataDir is where the metadata of the sessions is saved, in the user's
+// cache directory.
+func MetadataDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dacs", "sessions"), nil
+}
+
+// SaveMetadata writes the metadata of the session, which ran in dir, to a
+// file of its own in MetadataDir, replacing what was saved before.
+func (s *Session) SaveMetadata(dir string) error {
+	if len(s.Tasks) == 0 {
+		return nil
+	}
+	metadataDir, err := MetadataDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(metadataDir, 0755); err != nil {
+		return err
+	}
+	buf, err := json.Marshal(Metadata{Started: s.Started, Dir: dir, Tasks: s.Tasks})
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(metadataDir, fmt.Sprintf("%d.json", s.Started.UnixNano()))
+	f, err := os.CreateTemp(metadataDir, "session-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(buf)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return fmt.Errorf("error saving session metadata: %w", err)
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// LoadMetadata reads the metadata of the sessions started since then,
+// skipping files that cannot be read.
+func LoadMetadata(since time.Time) ([]Metadata, error) {
+	dir, err := MetadataDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var rv []Metadata
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		buf, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var m Metadata
+		if json.Unmarshal(buf, &m) != nil || m.Started.Before(since) {
+			continue
+		}
+		rv = append(rv, m)
+	}
+	return rv, nil
+}
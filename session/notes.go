@@ -0,0 +1,29 @@
+package session
+
+// Note is an entry in the model's scratchpad, kept out of the conversation.
+type Note struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// WriteNote adds a note, or replaces the note with the same title.
+func (s *Session) WriteNote(title, content string) {
+	for n := range s.Notes {
+		if s.Notes[n].Title == title {
+			s.Notes[n].Content = content
+			return
+		}
+	}
+	s.Notes = append(s.Notes, Note{Title: title, Content: content})
+}
+
+// DeleteNote removes the note with the title, reporting whether it existed.
+func (s *Session) DeleteNote(title string) bool {
+	for n := range s.Notes {
+		if s.Notes[n].Title == title {
+			s.Notes = append(s.Notes[:n], s.Notes[n+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+package session
+
+// Outcome is the model's own report of how a task went, used in headless
+// runs to set the exit code.
+type Outcome struct {
+	Success bool   `json:"success"`
+	Summary string `json:"summary"`
+}
@@ -0,0 +1,34 @@
+package session
+
+import "fmt"
+
+// Result is a large tool result kept by ID, so that it can be referred to,
+// and fetched again, after it left the conversation.
+type Result struct {
+	ID      string `json:"id"`
+	Tool    string `json:"tool"`
+	Content string `json:"content"`
+}
+
+// AddResult keeps the result and returns its ID, the ID of an identical
+// result kept before if there is one.
+func (s *Session) AddResult(tool, content string) string {
+	for _, r := range s.Results {
+		if r.Tool == tool && r.Content == content {
+			return r.ID
+		}
+	}
+	id := fmt.Sprintf("R%d", len(s.Results)+1)
+	s.Results = append(s.Results, Result{ID: id, Tool: tool, Content: content})
+	return id
+}
+
+// Result returns the result with the ID.
+func (s *Session) Result(id string) (Result, bool) {
+	for _, r := range s.Results {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Result{}, false
+}
@@ -0,0 +1,60 @@
+// Package session holds the conversation between the user, the model and
+// the tools.
+package session
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/crypt"
+)
+
+type Session struct {
+	Started   time.Time            `json:"started"`
+	Messages  []api.Message        `json:"messages"`
+	ToolStats map[string]*ToolStat `json:"tool_stats,omitempty"`
+	// Tasks are the statistics of each task, see dacs stats.
+	Tasks []TaskStats `json:"tasks,omitempty"`
+	// Pinned files are re-read and shown to the model on every turn.
+	Pinned []string `json:"pinned,omitempty"`
+	// Todos is the model's plan for the current task.
+	Todos []Todo `json:"todos,omitempty"`
+	// Notes is the model's scratchpad.
+	Notes []Note `json:"notes,omitempty"`
+	// Results are the large tool results, by ID.
+	Results []Result `json:"results,omitempty"`
+	// Outcome is set when the model reports the task finished.
+	Outcome *Outcome `json:"outcome,omitempty"`
+}
+
+func New(systemPrompt string) *Session {
+	rv := &Session{Started: time.Now()}
+	if systemPrompt != "" {
+		rv.Append(api.Message{
+			Role:    "system",
+			Content: systemPrompt,
+		})
+	}
+	return rv
+}
+
+func (s *Session) Append(msgs ...api.Message) {
+	s.Messages = append(s.Messages, msgs...)
+}
+
+// Export writes the session, including the tool statistics, as JSON,
+// encrypted with the passphrase of crypt when encrypt is set.
+func (s *Session) Export(path string, encrypt bool) error {
+	buf, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return crypt.WriteFile(path, buf, 0644, encrypt)
+}
+
+// Replace replaces the messages in [start, end) with msgs.
+func (s *Session) Replace(start, end int, msgs ...api.Message) {
+	s.Messages = append(s.Messages[:start:start], append(msgs, s.Messages[end:]...)...)
+}
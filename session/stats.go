@@ -0,0 +1,78 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+type ToolStat struct {
+	Calls     int           `json:"calls"`
+	Successes int           `json:"successes"`
+	Failures  int           `json:"failures"`
+	Duration  time.Duration `json:"duration"`
+}
+
+func (t *ToolStat) Average() time.Duration {
+	if t.Calls == 0 {
+		return 0
+	}
+	return t.Duration / time.Duration(t.Calls)
+}
+
+// RecordToolCall adds the call to the session's and the current task's
+// statistics.
+func (s *Session) RecordToolCall(name string, duration time.Duration, err error) {
+	if s.ToolStats == nil {
+		s.ToolStats = map[string]*ToolStat{}
+	}
+	s.ToolStats[name] = s.ToolStats[name].record(duration, err)
+	if t := s.currentTask(); t != nil {
+		if t.Tools == nil {
+			t.Tools = map[string]*ToolStat{}
+		}
+		t.Tools[name] = t.Tools[name].record(duration, err)
+	}
+}
+
+// record adds a call to the stat, which is created when nil.
+func (t *ToolStat) record(duration time.Duration, err error) *ToolStat {
+	if t == nil {
+		t = &ToolStat{}
+	}
+	t.Calls++
+	t.Duration += duration
+	if err != nil {
+		t.Failures++
+	} else {
+		t.Successes++
+	}
+	return t
+}
+
+// WriteToolStats writes a per-tool report, slowest cumulative time first.
+func (s *Session) WriteToolStats(w io.Writer) error {
+	if len(s.ToolStats) == 0 {
+		_, err := fmt.Fprintln(w, "no tools have run yet")
+		return err
+	}
+
+	names := make([]string, 0, len(s.ToolStats))
+	for name := range s.ToolStats {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return s.ToolStats[names[i]].Duration > s.ToolStats[names[j]].Duration
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOOL\tCALLS\tOK\tFAILED\tTOTAL\tAVG")
+	for _, name := range names {
+		stat := s.ToolStats[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%s\n", name, stat.Calls, stat.Successes,
+			stat.Failures, stat.Duration.Round(time.Millisecond), stat.Average().Round(time.Millisecond))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,91 @@
+package session
+
+import "time"
+
+// TaskStats is what a task, the work following one user input, did and
+// what it cost, without any of its content.
+type TaskStats struct {
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	// Inferences are the model's turns, one per response
+	Inferences int                    `json:"inferences"`
+	Models     map[string]*ModelUsage `json:"models,omitempty"`
+	Tools      map[string]*ToolStat   `json:"tools,omitempty"`
+}
+
+// ModelUsage is the tokens a model processed.
+type ModelUsage struct {
+	Inferences   int `json:"inferences"`
+	PromptTokens int `json:"prompt_tokens"`
+	EvalTokens   int `json:"eval_tokens"`
+}
+
+// StartTask starts recording the statistics of a new task.
+func (s *Session) StartTask() {
+	s.Tasks = append(s.Tasks, TaskStats{Started: time.Now()})
+}
+
+// currentTask returns the task being recorded, nil before the first.
+func (s *Session) currentTask() *TaskStats {
+	if len(s.Tasks) == 0 {
+		return nil
+	}
+	t := &s.Tasks[len(s.Tasks)-1]
+	t.Duration = time.Since(t.Started)
+	return t
+}
+
+// RecordInference adds a response of the model to the current task.
+func (s *Session) RecordInference(model string, promptTokens, evalTokens int) {
+	t := s.currentTask()
+	if t == nil {
+		return
+	}
+	if t.Models == nil {
+		t.Models = map[string]*ModelUsage{}
+	}
+	usage, ok := t.Models[model]
+	if !ok {
+		usage = &ModelUsage{}
+		t.Models[model] = usage
+	}
+	t.Inferences++
+	usage.Inferences++
+	usage.PromptTokens += promptTokens
+	usage.EvalTokens += evalTokens
+}
+
+// AddTaskStats adds the statistics of tasks done on its behalf, such as by
+// worker agents, to the current task.
+func (s *Session) AddTaskStats(tasks ...TaskStats) {
+	t := s.currentTask()
+	if t == nil {
+		return
+	}
+	for _, other := range tasks {
+		t.Inferences += other.Inferences
+		for model, u := range other.Models {
+			if t.Models == nil {
+				t.Models = map[string]*ModelUsage{}
+			}
+			if t.Models[model] == nil {
+				t.Models[model] = &ModelUsage{}
+			}
+			t.Models[model].Inferences += u.Inferences
+			t.Models[model].PromptTokens += u.PromptTokens
+			t.Models[model].EvalTokens += u.EvalTokens
+		}
+		for name, stat := range other.Tools {
+			if t.Tools == nil {
+				t.Tools = map[string]*ToolStat{}
+			}
+			if t.Tools[name] == nil {
+				t.Tools[name] = &ToolStat{}
+			}
+			t.Tools[name].Calls += stat.Calls
+			t.Tools[name].Successes += stat.Successes
+			t.Tools[name].Failures += stat.Failures
+			t.Tools[name].Duration += stat.Duration
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	TodoPending    = "pending"
+	TodoInProgress = "in_progress"
+	TodoDone       = "done"
+)
+
+// Todo is a step of the plan the model keeps for multi-step tasks.
+type Todo struct {
+	Content string `json:"content"`
+	Status  string `json:"status"`
+}
+
+// FormatTodos renders the plan as a checklist.
+func FormatTodos(todos []Todo) string {
+	if len(todos) == 0 {
+		return "no todos"
+	}
+	var sb strings.Builder
+	for n, t := range todos {
+		mark := "[ ]"
+		switch t.Status {
+		case TodoInProgress:
+			mark = "[~]"
+		case TodoDone:
+			mark = "[x]"
+		}
+		fmt.Fprintf(&sb, "%s %d. %s\n", mark, n+1, t.Content)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the session, for the tools that
+// keep state in it.
+func NewContext(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the session set with NewContext, if any.
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(contextKey{}).(*Session)
+	return s, ok
+}
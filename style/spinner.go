@@ -0,0 +1,89 @@
+package style
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// Spinner shows that the agent is waiting, with the time it has waited and
+// the tokens received so far.
+type Spinner struct {
+	label   string
+	started time.Time
+	mu      sync.Mutex
+	tokens  int
+	status  string
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Spin starts a spinner labeled with what is waited for. It shows nothing
+// when plain or when the output is not a terminal.
+func Spin(label string) *Spinner {
+	s := &Spinner{label: label, started: time.Now()}
+	if Plain || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return s
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run()
+	return s
+}
+
+func (s *Spinner) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for frame := 0; ; frame++ {
+		s.mu.Lock()
+		line := fmt.Sprintf("%c %s %ds", spinnerFrames[frame%len(spinnerFrames)], s.label, int(time.Since(s.started).Seconds()))
+		if s.tokens > 0 {
+			line += fmt.Sprintf(", %d tokens", s.tokens)
+		}
+		if s.status != "" {
+			line += ", " + s.status
+		}
+		s.mu.Unlock()
+		fmt.Print("\r\u001b[2K" + Color(Dim, line))
+		select {
+		case <-s.stop:
+			fmt.Print("\r\u001b[2K")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Token counts a token received.
+func (s *Spinner) Token() {
+	s.mu.Lock()
+	s.tokens++
+	s.mu.Unlock()
+}
+
+// Status shows what the wait is for, such as a place in a queue, until it
+// is changed again. When the spinner shows nothing it is printed instead.
+func (s *Spinner) Status(status string) {
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+	if s.stop == nil && status != "" {
+		fmt.Println(Color(Dim, s.label+": "+status))
+	}
+}
+
+// Stop stops the spinner and clears its line.
+func (s *Spinner) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
@@ -0,0 +1,70 @@
+// Package style formats what dacs prints to the terminal: colored labels
+// and progress on a single line, or plain output without ANSI escape
+// sequences, for screen readers, NO_COLOR and log files.
+package style
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Colors, as ANSI SGR parameters.
+const (
+	Dim    = "2"
+	Red    = "91"
+	Green  = "92"
+	Yellow = "93"
+	Blue   = "94"
+)
+
+// Plain leaves out colors, other escape sequences and progress redrawn in
+// place. Lines are labeled with upper case prefixes, such as AGENT: and
+// TOOL:, instead.
+var Plain = os.Getenv("NO_COLOR") != ""
+
+// Color returns s in the color, or as is when plain.
+func Color(color, s string) string {
+	if Plain {
+		return s
+	}
+	return "\u001b[" + color + "m" + s + "\u001b[0m"
+}
+
+// Label returns the label of a line, e.g. Agent, in the color, or in upper
+// case when plain.
+func Label(color, label string) string {
+	if Plain {
+		return strings.ToUpper(label)
+	}
+	return Color(color, label)
+}
+
+// progress is the last line Progress printed.
+var progress string
+
+// Progress shows the line in place of the previous one, when plain only
+// lines that changed are printed, each on its own.
+func Progress(line string) {
+	if Plain {
+		if line != progress {
+			fmt.Println(line)
+		}
+	} else {
+		fmt.Printf("\r\u001b[2K%s", line)
+	}
+	progress = line
+}
+
+// EndProgress ends the lines shown with Progress, keeping the last one
+// when keep is set and clearing it otherwise.
+func EndProgress(keep bool) {
+	switch {
+	case progress == "" || Plain:
+	case keep:
+		fmt.Println()
+	default:
+		fmt.Print("\r\u001b[2K")
+	}
+	progress = ""
+}
@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var AnalyzeTraceDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name: "analyze_trace",
+		Description: `Parse a stack trace or panic (Go, Python, JavaScript, Java and similar) and return the source around each frame that is in the workspace.
+
+Pass the trace itself, or the path of a log file containing it. Frames outside of the workspace, such as the standard library, are listed without source.`,
+		Parameters: objectParameters(nil, map[string]Property{
+			"trace": {
+				Type:        api.PropertyType{"string"},
+				Description: "The stack trace text.",
+			},
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "Alternatively, the relative path of a log file containing the trace, its last trace is analyzed.",
+			},
+		}),
+	},
+	Function: AnalyzeTrace,
+}
+
+type AnalyzeTraceInput struct {
+	Trace string `json:"trace,omitempty"`
+	Path  string `json:"path,omitempty"`
+}
+
+const (
+	// frames with source returned, the innermost are the most relevant
+	maxTraceFrames = 8
+	// lines of source shown before and after a frame's line
+	traceContext = 5
+	// the end of a log searched for a trace
+	maxTraceLog = 64 * 1024
+)
+
+var (
+	// Python: File "app/views.py", line 12, in handler
+	pythonFrame = regexp.MustCompile(`File "([^"]+)", line (\d+)`)
+	// Java: at com.example.Foo.bar(Foo.java:42)
+	javaFrame = regexp.MustCompile(`\(([\w$.-]+\.\w+):(\d+)\)`)
+	// Go, JavaScript, Rust and most others: path/to/file.ext:12[:3]
+	fileLineFrame = regexp.MustCompile(`((?:[A-Za-z]:)?[\w@$~.+/\\-]*[\w-]\.[A-Za-z]+):(\d+)`)
+	// the start of a trace in a log
+	traceStart = regexp.MustCompile(`(?m)^(panic: |fatal error: |goroutine \d+ \[|Traceback \(most recent call last\)|Exception in thread |\w*(Error|Exception)\b.*\n\s+at )`)
+)
+
+type traceFrame struct {
+	path string
+	line int
+}
+
+func AnalyzeTrace(ctx context.Context, input json.RawMessage) (string, error) {
+	analyzeTraceInput := AnalyzeTraceInput{}
+	err := json.Unmarshal(input, &analyzeTraceInput)
+	if err != nil {
+		return "", err
+	}
+	ws := workspace.FromContext(ctx)
+
+	trace := analyzeTraceInput.Trace
+	if trace == "" {
+		if analyzeTraceInput.Path == "" {
+			return "", fmt.Errorf("invalid input parameters")
+		}
+		root, path, err := ws.Resolve(analyzeTraceInput.Path)
+		if err != nil {
+			return "", err
+		}
+		content, err := root.FS.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		trace = lastTrace(string(content))
+	}
+
+	frames := parseTrace(trace)
+	if len(frames) == 0 {
+		return "", fmt.Errorf("no stack frames found")
+	}
+
+	var out strings.Builder
+	var outside []string
+	files := map[string][]string{}
+	shown := 0
+	for _, f := range frames {
+		display, lines := resolveFrame(ws, f.path, files)
+		if lines == nil || shown == maxTraceFrames {
+			outside = append(outside, fmt.Sprintf("%s:%d", f.path, f.line))
+			continue
+		}
+		shown++
+		fmt.Fprintf(&out, "%s:%d\n", display, f.line)
+		from, to := max(f.line-traceContext, 1), min(f.line+traceContext, len(lines))
+		for n := from; n <= to; n++ {
+			marker := " "
+			if n == f.line {
+				marker = ">"
+			}
+			fmt.Fprintf(&out, "%s%5d  %s\n", marker, n, lines[n-1])
+		}
+		out.WriteString("\n")
+	}
+	if shown == 0 {
+		out.WriteString("no frames are in the workspace\n")
+	}
+	if len(outside) > 0 {
+		fmt.Fprintf(&out, "other frames:\n%s\n", strings.Join(outside, "\n"))
+	}
+	return out.String(), nil
+}
+
+// lastTrace returns the last trace in a log, or its end when no start of a
+// trace is recognized.
+func lastTrace(log string) string {
+	if len(log) > maxTraceLog {
+		log = log[len(log)-maxTraceLog:]
+	}
+	starts := traceStart.FindAllStringIndex(log, -1)
+	if len(starts) == 0 {
+		return log
+	}
+	// a Go panic is followed by its goroutines, start at the panic
+	start := starts[len(starts)-1][0]
+	for i := len(starts) - 1; i >= 0; i-- {
+		start = starts[i][0]
+		if !strings.HasPrefix(log[start:], "goroutine ") {
+			break
+		}
+	}
+	return log[start:]
+}
+
+// parseTrace returns the frames of a trace in order, each once.
+func parseTrace(trace string) []traceFrame {
+	var frames []traceFrame
+	seen := map[traceFrame]bool{}
+	for _, line := range strings.Split(trace, "\n") {
+		var m []string
+		for _, re := range []*regexp.Regexp{pythonFrame, javaFrame, fileLineFrame} {
+			if m = re.FindStringSubmatch(line); m != nil {
+				break
+			}
+		}
+		if m == nil {
+			continue
+		}
+		n, err := strconv.Atoi(m[2])
+		if err != nil || n < 1 {
+			continue
+		}
+		f := traceFrame{path: strings.TrimPrefix(m[1], "file://"), line: n}
+		if !seen[f] {
+			seen[f] = true
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
+
+// resolveFrame finds the file of a frame in the workspace, by its path or,
+// as traces often come from other machines, by the longest suffix of its
+// path that exists in a root. It returns the display path and the lines of
+// the file, nil when it is not found.
+func resolveFrame(ws *workspace.Workspace, path string, files map[string][]string) (string, []string) {
+	parts := strings.FieldsFunc(filepath.ToSlash(path), func(r rune) bool { return r == '/' })
+	// a bare file name is too ambiguous unless that is all the frame has
+	minParts := min(len(parts), 2)
+	for i := 0; len(parts)-i >= minParts; i++ {
+		suffix := filepath.Join(parts[i:]...)
+		for _, root := range ws.Roots {
+			abs := filepath.Join(root.Path, suffix)
+			display := ws.Display(root, abs)
+			if lines, ok := files[display]; ok {
+				if lines == nil {
+					continue
+				}
+				return display, lines
+			}
+			r, resolved, err := ws.Resolve(root.Name + ":" + suffix)
+			var content []byte
+			if err == nil {
+				content, err = r.FS.ReadFile(resolved)
+			}
+			if err != nil {
+				files[display] = nil
+				continue
+			}
+			lines := strings.Split(string(content), "\n")
+			files[display] = lines
+			return display, lines
+		}
+	}
+	return "", nil
+}
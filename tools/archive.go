@@ -0,0 +1,481 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var ListArchiveDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "list_archive",
+		Description: "List the files in a zip, tar or tar.gz archive, with their sizes.",
+		Parameters: objectParameters([]string{"path"}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "The relative path of the archive.",
+			},
+		}),
+	},
+	Function: ListArchive,
+}
+
+var ExtractArchiveDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "extract_archive",
+		Description: "Extract a zip, tar or tar.gz archive into a directory, creating it if needed. Entries that would land outside of the directory, and links, are skipped.",
+		Parameters: objectParameters([]string{"path", "destination"}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "The relative path of the archive.",
+			},
+			"destination": {
+				Type:        api.PropertyType{"string"},
+				Description: "The relative path of the directory to extract into.",
+			},
+		}),
+	},
+	Function: ExtractArchive,
+	Effect:   EffectWrite,
+}
+
+var CreateArchiveDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "create_archive",
+		Description: "Create a zip, tar or tar.gz archive, by the extension of its path, of files and directories in the workspace.",
+		Parameters: objectParameters([]string{"path", "files"}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "The relative path of the archive to create, ending in .zip, .tar, .tar.gz or .tgz.",
+			},
+			"files": {
+				Type:        api.PropertyType{"array"},
+				Items:       map[string]string{"type": "string"},
+				Description: "The relative paths of the files and directories to add, directories are added with their contents.",
+			},
+		}),
+	},
+	Function: CreateArchive,
+	Effect:   EffectWrite,
+}
+
+const (
+	// limits on extraction, against archives that expand without bound
+	maxExtractSize    = 1 << 30
+	maxExtractEntries = 100000
+	// entries listed by list_archive
+	maxArchiveList = 1000
+)
+
+type archiveEntry struct {
+	name string
+	size int64
+	dir  bool
+	// link entries, symbolic or hard, are never extracted
+	link bool
+	open func() (io.ReadCloser, error)
+}
+
+type ListArchiveInput struct {
+	Path string `json:"path"`
+}
+
+func ListArchive(ctx context.Context, input json.RawMessage) (string, error) {
+	listArchiveInput := ListArchiveInput{}
+	err := json.Unmarshal(input, &listArchiveInput)
+	if err != nil {
+		return "", err
+	}
+	if listArchiveInput.Path == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	var out strings.Builder
+	var total int64
+	n := 0
+	err = walkArchive(ctx, listArchiveInput.Path, func(e archiveEntry) error {
+		n++
+		total += e.size
+		if n > maxArchiveList {
+			return nil
+		}
+		switch {
+		case e.dir:
+			fmt.Fprintf(&out, "%s\n", e.name)
+		case e.link:
+			fmt.Fprintf(&out, "%s (link)\n", e.name)
+		default:
+			fmt.Fprintf(&out, "%s %d\n", e.name, e.size)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if n > maxArchiveList {
+		fmt.Fprintf(&out, "... %d more entries\n", n-maxArchiveList)
+	}
+	fmt.Fprintf(&out, "%d entries, %d bytes uncompressed\n", n, total)
+	return out.String(), nil
+}
+
+type ExtractArchiveInput struct {
+	Path        string `json:"path"`
+	Destination string `json:"destination"`
+}
+
+func ExtractArchive(ctx context.Context, input json.RawMessage) (string, error) {
+	extractArchiveInput := ExtractArchiveInput{}
+	err := json.Unmarshal(input, &extractArchiveInput)
+	if err != nil {
+		return "", err
+	}
+	if extractArchiveInput.Path == "" || extractArchiveInput.Destination == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	ws := workspace.FromContext(ctx)
+	root, dest, err := ws.Resolve(extractArchiveInput.Destination)
+	if err != nil {
+		return "", err
+	}
+
+	var written, skipped, denied []string
+	var total int64
+	entries := 0
+	err = walkArchive(ctx, extractArchiveInput.Path, func(e archiveEntry) error {
+		entries++
+		if entries > maxExtractEntries {
+			return fmt.Errorf("more than %d entries, not extracting the rest", maxExtractEntries)
+		}
+		name, ok := safeEntryName(e.name)
+		if !ok || e.link {
+			skipped = append(skipped, e.name)
+			return nil
+		}
+		// resolved again, so symlinks already in the destination can't
+		// lead outside of the root either
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		r, target, err := ws.Resolve(ws.Display(root, target))
+		if err != nil || r != root {
+			skipped = append(skipped, e.name)
+			return nil
+		}
+		if err := CheckPath(ctx, ws.Display(root, target)); err != nil {
+			denied = append(denied, err.Error())
+			return nil
+		}
+		if DryRun(ctx) {
+			if !e.dir {
+				written = append(written, ws.Display(root, target))
+			}
+			return nil
+		}
+		if e.dir {
+			return root.FS.MkdirAll(target, 0755)
+		}
+		rc, err := e.open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		// the sizes in the headers are not trusted
+		remaining := int64(maxExtractSize) - total
+		content, err := io.ReadAll(io.LimitReader(rc, remaining+1))
+		if err != nil {
+			return err
+		}
+		if int64(len(content)) > remaining {
+			return fmt.Errorf("more than %d bytes, not extracting the rest", maxExtractSize)
+		}
+		total += int64(len(content))
+		if err := root.FS.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := root.FS.WriteFile(target, content, 0644); err != nil {
+			return err
+		}
+		written = append(written, ws.Display(root, target))
+		return nil
+	})
+
+	if err != nil && len(written) == 0 && len(skipped) == 0 && len(denied) == 0 {
+		return "", err
+	}
+
+	var out strings.Builder
+	if DryRun(ctx) {
+		fmt.Fprintf(&out, "dry run, nothing was extracted, it would write %d files", len(written))
+	} else {
+		fmt.Fprintf(&out, "extracted %d files", len(written))
+	}
+	if len(written) > 0 {
+		fmt.Fprintf(&out, ":\n%s", strings.Join(written[:min(len(written), maxArchiveList)], "\n"))
+		if len(written) > maxArchiveList {
+			fmt.Fprintf(&out, "\n... %d more", len(written)-maxArchiveList)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(&out, "\nskipped %d links or entries outside of the destination:\n%s", len(skipped), strings.Join(skipped[:min(len(skipped), maxArchiveList)], "\n"))
+	}
+	if len(denied) > 0 {
+		fmt.Fprintf(&out, "\nnot extracted, denied by the policy:\n%s", strings.Join(denied[:min(len(denied), maxArchiveList)], "\n"))
+	}
+	if err != nil {
+		fmt.Fprintf(&out, "\nstopped: %v", err)
+	}
+	return out.String(), nil
+}
+
+// safeEntryName cleans an entry's name, reporting whether it stays inside
+// of the directory it is extracted to.
+func safeEntryName(name string) (string, bool) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if path.IsAbs(name) || filepath.VolumeName(name) != "" || strings.Contains(name, ":") {
+		return "", false
+	}
+	name = path.Clean(name)
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
+
+// walkArchive calls fn with each entry of the archive at the tool path,
+// with the format detected from its content.
+func walkArchive(ctx context.Context, archivePath string, fn func(archiveEntry) error) error {
+	root, p, err := workspace.FromContext(ctx).Resolve(archivePath)
+	if err != nil {
+		return err
+	}
+	content, err := root.FS.ReadFile(p)
+	if err != nil {
+		return err
+	}
+
+	if bytes.HasPrefix(content, []byte("PK\x03\x04")) || bytes.HasPrefix(content, []byte("PK\x05\x06")) {
+		zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return fmt.Errorf("invalid zip archive: %v", err)
+		}
+		for _, f := range zr.File {
+			mode := f.Mode()
+			err := fn(archiveEntry{
+				name: f.Name,
+				size: int64(f.UncompressedSize64),
+				dir:  mode.IsDir(),
+				link: !mode.IsDir() && !mode.IsRegular(),
+				open: f.Open,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var r io.Reader = bytes.NewReader(content)
+	if bytes.HasPrefix(content, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("invalid gzip: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("not a zip, tar or tar.gz archive: %v", err)
+		}
+		err = fn(archiveEntry{
+			name: h.Name,
+			size: h.Size,
+			dir:  h.Typeflag == tar.TypeDir,
+			link: h.Typeflag != tar.TypeReg && h.Typeflag != tar.TypeDir,
+			open: func() (io.ReadCloser, error) { return io.NopCloser(tr), nil },
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type CreateArchiveInput struct {
+	Path  string   `json:"path"`
+	Files []string `json:"files"`
+}
+
+func CreateArchive(ctx context.Context, input json.RawMessage) (string, error) {
+	createArchiveInput := CreateArchiveInput{}
+	err := json.Unmarshal(input, &createArchiveInput)
+	if err != nil {
+		return "", err
+	}
+	if createArchiveInput.Path == "" || len(createArchiveInput.Files) == 0 {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	lower := strings.ToLower(createArchiveInput.Path)
+	format := ""
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		format = "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		format = "tgz"
+	case strings.HasSuffix(lower, ".tar"):
+		format = "tar"
+	default:
+		return "", fmt.Errorf("unsupported archive %s, use .zip, .tar, .tar.gz or .tgz", createArchiveInput.Path)
+	}
+	ws := workspace.FromContext(ctx)
+	root, archive, err := ws.Resolve(createArchiveInput.Path)
+	if err != nil {
+		return "", err
+	}
+
+	// the files, by the names they are stored as
+	type file struct {
+		name, path string
+		root       *workspace.Root
+	}
+	var files []file
+	for _, f := range createArchiveInput.Files {
+		r, p, err := ws.Resolve(f)
+		if err != nil {
+			return "", err
+		}
+		name := filepath.ToSlash(strings.TrimPrefix(ws.Display(r, p), r.Name+":"))
+		walked, err := r.FS.Walk(p)
+		if err != nil {
+			return "", err
+		}
+		if len(walked) == 0 {
+			// a file or an empty directory, Walk returns nothing below
+			// either, directories are not stored on their own
+			dir, err := isDir(r.FS, p)
+			if err != nil {
+				return "", err
+			}
+			if !dir {
+				files = append(files, file{name, p, r})
+			}
+			continue
+		}
+		for _, w := range walked {
+			if strings.HasSuffix(w, "/") {
+				continue
+			}
+			wp := filepath.Join(p, w)
+			if _, _, err := ws.Resolve(ws.Display(r, wp)); err != nil {
+				// a symlink out of the root
+				continue
+			}
+			files = append(files, file{path.Join(name, filepath.ToSlash(w)), wp, r})
+		}
+	}
+	for _, f := range files {
+		if err := CheckPath(ctx, ws.Display(f.root, f.path)); err != nil {
+			return "", fmt.Errorf("%v, leave it out of the archive", err)
+		}
+	}
+	if DryRun(ctx) {
+		names := make([]string, len(files))
+		for i, f := range files {
+			names[i] = f.name
+		}
+		return fmt.Sprintf("dry run, the archive was not created, it would contain:\n%s", strings.Join(names, "\n")), nil
+	}
+
+	var buf bytes.Buffer
+	var add func(name string, content []byte) error
+	var finish func() error
+	switch format {
+	case "zip":
+		zw := zip.NewWriter(&buf)
+		add = func(name string, content []byte) error {
+			w, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(content)
+			return err
+		}
+		finish = zw.Close
+	default:
+		var w io.Writer = &buf
+		var gz *gzip.Writer
+		if format == "tgz" {
+			gz = gzip.NewWriter(&buf)
+			w = gz
+		}
+		tw := tar.NewWriter(w)
+		add = func(name string, content []byte) error {
+			err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+			if err != nil {
+				return err
+			}
+			_, err = tw.Write(content)
+			return err
+		}
+		finish = func() error {
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			if gz != nil {
+				return gz.Close()
+			}
+			return nil
+		}
+	}
+	seen := map[string]bool{}
+	for _, f := range files {
+		if f.root == root && f.path == archive || seen[f.name] {
+			continue
+		}
+		seen[f.name] = true
+		content, err := f.root.FS.ReadFile(f.path)
+		if err != nil {
+			return "", err
+		}
+		if err := add(f.name, content); err != nil {
+			return "", err
+		}
+	}
+	if err := finish(); err != nil {
+		return "", err
+	}
+	if err := root.FS.MkdirAll(filepath.Dir(archive), 0755); err != nil {
+		return "", err
+	}
+	if err := root.FS.WriteFile(archive, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("created %s with %d files, %d bytes", createArchiveInput.Path, len(seen), buf.Len()), nil
+}
+
+// isDir reports whether path is a directory, by listing its parent, as
+// the file systems have no stat.
+func isDir(fsys workspace.FS, path string) (bool, error) {
+	entries, err := fsys.List(filepath.Dir(path), 1)
+	if err != nil {
+		return false, err
+	}
+	base := filepath.Base(path)
+	return slices.Contains(entries, base+"/") || slices.Contains(entries, base+string(filepath.Separator)), nil
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mschoch/dacs/config"
+	"github.com/mschoch/dacs/workspace"
+)
+
+func TestSafeEntryName(t *testing.T) {
+	for _, test := range []struct {
+		name, want string
+		ok         bool
+	}{
+		{"a/b.txt", "a/b.txt", true},
+		{"./a//b/", "a/b", true},
+		{"a/../b", "b", true},
+		{`dir\file`, "dir/file", true},
+		{"..a/b", "..a/b", true},
+		{".", "", false},
+		{"..", "", false},
+		{"../x", "", false},
+		{"a/../../x", "", false},
+		{`..\x`, "", false},
+		{"/etc/passwd", "", false},
+		{`\etc\passwd`, "", false},
+		{"C:/x", "", false},
+		{"c:x", "", false},
+	} {
+		got, ok := safeEntryName(test.name)
+		if got != test.want || ok != test.ok {
+			t.Errorf("safeEntryName(%q) = %q, %t, want %q, %t", test.name, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestCreateArchiveStaysInRoot(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{"src", "empty"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dir, "src", "link")); err != nil {
+		t.Fatal(err)
+	}
+	ws, err := workspace.New([]config.Root{{Path: dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := workspace.NewContext(context.Background(), ws)
+
+	_, err = CreateArchive(ctx, json.RawMessage(`{"path":"out.zip","files":["src","empty"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(filepath.Join(dir, "out.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if want := []string{"src/a.txt"}; !slices.Equal(names, want) {
+		t.Errorf("archived %v, want %v", names, want)
+	}
+}
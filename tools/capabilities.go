@@ -0,0 +1,34 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ollama/ollama/api"
+)
+
+var GetCapabilitiesDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "get_capabilities",
+		Description: "Report what the agent can currently do: the model, the workspace roots, the tools offered and disabled, and the policy rules restricting them. Use this to check whether a tool or path is available before relying on it.",
+		Parameters:  objectParameters(nil, map[string]Property{}),
+	},
+	Function: GetCapabilities,
+}
+
+type capabilitiesKey struct{}
+
+// WithCapabilities returns a context in which get_capabilities reports the
+// capabilities returned by fn.
+func WithCapabilities(ctx context.Context, fn func() string) context.Context {
+	return context.WithValue(ctx, capabilitiesKey{}, fn)
+}
+
+func GetCapabilities(ctx context.Context, _ json.RawMessage) (string, error) {
+	fn, ok := ctx.Value(capabilitiesKey{}).(func() string)
+	if !ok {
+		return "", fmt.Errorf("no capabilities to report")
+	}
+	return fn(), nil
+}
@@ -0,0 +1,244 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var TestCoverageDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "test_coverage",
+		Description: "Run the tests of a Go package with coverage and return its coverage per function, with the line ranges not covered, least covered first. Use this to find the gaps to target when raising test coverage.",
+		Parameters: objectParameters([]string{"package"}, map[string]Property{
+			"package": {
+				Type:        api.PropertyType{"string"},
+				Description: "The package to test, e.g. ./parser.",
+			},
+			"run": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional regular expression selecting the tests to run, as with go test -run.",
+			},
+		}),
+	},
+	Function: TestCoverage,
+	Effect:   EffectExec,
+}
+
+type TestCoverageInput struct {
+	Package string `json:"package"`
+	Run     string `json:"run,omitempty"`
+}
+
+const (
+	// separates the go test output from the profile in the command output,
+	// also used by profile_benchmark
+	profileMarker = "--- dacs coverage profile ---"
+	// functions listed, the least covered first
+	maxCoverageFuncs = 40
+)
+
+func TestCoverage(ctx context.Context, input json.RawMessage) (string, error) {
+	testCoverageInput := TestCoverageInput{}
+	err := json.Unmarshal(input, &testCoverageInput)
+	if err != nil {
+		return "", err
+	}
+	if testCoverageInput.Package == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	test := "go test -coverprofile=\"$f\" " + workspace.ShellQuote(testCoverageInput.Package)
+	if testCoverageInput.Run != "" {
+		test += " -run " + workspace.ShellQuote(testCoverageInput.Run)
+	}
+	ws := workspace.FromContext(ctx)
+	root := ws.Primary()
+	if DryRun(ctx) {
+		return fmt.Sprintf("dry run, the tests were not run, it would run in %s:\n%s", root.Path, test), nil
+	}
+
+	// the profile is written where the tests run, which may be remote, and
+	// names files by import path, go list maps those to directories
+	script := fmt.Sprintf(`f=$(mktemp) || exit 1; %s; s=$?; echo %s; cat "$f"; rm -f "$f"; echo %[2]s; go list -f '{{.ImportPath}} {{.Dir}}' %s; exit $s`,
+		test, workspace.ShellQuote(profileMarker), workspace.ShellQuote(testCoverageInput.Package))
+	out, _ := root.FS.Command(ctx, root.Path, script).CombinedOutput()
+	parts := strings.SplitN(string(out), profileMarker+"\n", 3)
+	if len(parts) != 3 || ctx.Err() != nil {
+		return "", fmt.Errorf("running the tests failed:\n%s", out)
+	}
+	testOutput, profile := parts[0], parts[1]
+	blocks := parseProfile(profile)
+	if len(blocks) == 0 {
+		return fmt.Sprintf("no coverage, the test output was:\n%s", testOutput), nil
+	}
+	dirs := map[string]string{}
+	for _, line := range strings.Split(parts[2], "\n") {
+		if importPath, dir, ok := strings.Cut(line, " "); ok {
+			dirs[importPath] = dir
+		}
+	}
+
+	funcs := coverageByFunc(ws, root, dirs, blocks)
+	sort.SliceStable(funcs, func(i, j int) bool {
+		return funcs[i].percent() < funcs[j].percent()
+	})
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(testOutput) + "\n\n")
+	listed := 0
+	for _, f := range funcs {
+		if f.covered == f.statements {
+			continue
+		}
+		if listed == maxCoverageFuncs {
+			b.WriteString("...\n")
+			break
+		}
+		listed++
+		fmt.Fprintf(&b, "%s %s %.1f%%, not covered: lines %s\n", f.file, f.name, f.percent(), strings.Join(f.uncovered, ", "))
+	}
+	if listed == 0 {
+		b.WriteString("every function is fully covered\n")
+	}
+	return b.String(), nil
+}
+
+type profileBlock struct {
+	file               string
+	startLine, endLine int
+	statements, count  int
+}
+
+// parseProfile parses a cover profile, merging the counts of blocks
+// repeated for several test binaries.
+func parseProfile(profile string) []profileBlock {
+	var blocks []profileBlock
+	index := map[string]int{}
+	s := bufio.NewScanner(strings.NewReader(profile))
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		// file.go:12.34,15.2 3 1
+		pos, rest, ok := strings.Cut(line, " ")
+		i := strings.LastIndex(pos, ":")
+		fields := strings.Fields(rest)
+		if !ok || i < 0 || len(fields) != 2 {
+			continue
+		}
+		start, end, _ := strings.Cut(pos[i+1:], ",")
+		startLine, err1 := strconv.Atoi(strings.Split(start, ".")[0])
+		endLine, err2 := strconv.Atoi(strings.Split(end, ".")[0])
+		statements, err3 := strconv.Atoi(fields[0])
+		count, err4 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+		if j, ok := index[pos]; ok {
+			blocks[j].count += count
+			continue
+		}
+		index[pos] = len(blocks)
+		blocks = append(blocks, profileBlock{file: pos[:i], startLine: startLine, endLine: endLine, statements: statements, count: count})
+	}
+	return blocks
+}
+
+type funcCoverage struct {
+	file, name          string
+	statements, covered int
+	uncovered           []string
+}
+
+func (f funcCoverage) percent() float64 {
+	if f.statements == 0 {
+		return 100
+	}
+	return 100 * float64(f.covered) / float64(f.statements)
+}
+
+// coverageByFunc attributes the blocks to the functions containing them,
+// dirs maps the import paths the files are named by to directories.
+func coverageByFunc(ws *workspace.Workspace, root *workspace.Root, dirs map[string]string, blocks []profileBlock) []*funcCoverage {
+	var funcs []*funcCoverage
+	type span struct {
+		fn         *funcCoverage
+		start, end int
+	}
+	spans := map[string][]span{}
+	for _, b := range blocks {
+		fileSpans, ok := spans[b.file]
+		if !ok {
+			importPath, name := path.Split(b.file)
+			file := filepath.Join(dirs[strings.TrimSuffix(importPath, "/")], name)
+			src, err := root.FS.ReadFile(file)
+			if err == nil {
+				display := ws.Display(root, file)
+				fset := token.NewFileSet()
+				f, err := parser.ParseFile(fset, display, src, parser.SkipObjectResolution)
+				if err == nil {
+					for _, decl := range f.Decls {
+						fd, ok := decl.(*ast.FuncDecl)
+						if !ok {
+							continue
+						}
+						fn := &funcCoverage{file: display, name: funcName(fd)}
+						funcs = append(funcs, fn)
+						fileSpans = append(fileSpans, span{fn, fset.Position(fd.Pos()).Line, fset.Position(fd.End()).Line})
+					}
+				}
+			}
+			spans[b.file] = fileSpans
+		}
+		for _, s := range fileSpans {
+			if b.startLine < s.start || b.startLine > s.end {
+				continue
+			}
+			s.fn.statements += b.statements
+			if b.count > 0 {
+				s.fn.covered += b.statements
+			} else if b.statements > 0 {
+				lines := strconv.Itoa(b.startLine)
+				if b.endLine > b.startLine {
+					lines += "-" + strconv.Itoa(b.endLine)
+				}
+				s.fn.uncovered = append(s.fn.uncovered, lines)
+			}
+			break
+		}
+	}
+	return funcs
+}
+
+func funcName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name
+	}
+	recv := fd.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	switch t := recv.(type) {
+	case *ast.IndexExpr:
+		recv = t.X
+	case *ast.IndexListExpr:
+		recv = t.X
+	}
+	if id, ok := recv.(*ast.Ident); ok {
+		return "(" + id.Name + ")." + fd.Name.Name
+	}
+	return fd.Name.Name
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/config"
+	"github.com/mschoch/dacs/workspace"
+)
+
+var customToolName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+// NewCustomTool returns a tool declared in the config, running its command
+// with the parameters the model passes, shell quoted, in the primary root.
+func NewCustomTool(def config.CustomTool) (Tool, error) {
+	if !customToolName.MatchString(def.Name) {
+		return Tool{}, fmt.Errorf("custom tool %q: invalid name", def.Name)
+	}
+	if def.Command == "" {
+		return Tool{}, fmt.Errorf("custom tool %s: no command", def.Name)
+	}
+	tmpl, err := template.New(def.Name).Option("missingkey=zero").Parse(def.Command)
+	if err != nil {
+		return Tool{}, fmt.Errorf("custom tool %s: %w", def.Name, err)
+	}
+
+	var effect Effect
+	switch def.Effect {
+	case "", "exec":
+		effect = EffectExec
+	case "none":
+		effect = EffectNone
+	case "write", "delete", "network":
+		effect = Effect(def.Effect)
+	default:
+		return Tool{}, fmt.Errorf("custom tool %s: unknown effect %q", def.Name, def.Effect)
+	}
+
+	params := map[string]config.CustomParam{}
+	required := []string{}
+	properties := map[string]Property{}
+	for name, p := range def.Parameters {
+		switch p.Type {
+		case "":
+			p.Type = "string"
+		case "string", "integer", "number", "boolean":
+		default:
+			return Tool{}, fmt.Errorf("custom tool %s: parameter %s has unknown type %q", def.Name, name, p.Type)
+		}
+		params[name] = p
+		prop := Property{
+			Type:        api.PropertyType{p.Type},
+			Description: p.Description,
+		}
+		for _, v := range p.Enum {
+			prop.Enum = append(prop.Enum, v)
+		}
+		properties[name] = prop
+		if p.Required {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+
+	timeout := defaultCommandTimeout
+	if def.Timeout > 0 {
+		timeout = time.Duration(def.Timeout) * time.Second
+	}
+
+	return Tool{
+		Definition: api.ToolFunction{
+			Name:        def.Name,
+			Description: def.Description,
+			Parameters:  objectParameters(required, properties),
+		},
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			script, err := renderCustomCommand(tmpl, params, input)
+			if err != nil {
+				return "", err
+			}
+			root := workspace.FromContext(ctx).Primary()
+			if DryRun(ctx) && effect != EffectNone {
+				return fmt.Sprintf("dry run, the command was not run, it would run in %s:\n%s", root.Path, script), nil
+			}
+			return runScript(ctx, root, script, timeout)
+		},
+		Effect:    effect,
+		Namespace: CustomNamespace,
+	}, nil
+}
+
+// renderCustomCommand checks the input against the parameters and renders
+// the command with it, strings are shell quoted and parameters that were
+// not passed, or false, are empty.
+func renderCustomCommand(tmpl *template.Template, params map[string]config.CustomParam, input json.RawMessage) (string, error) {
+	var values map[string]any
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return "", err
+	}
+
+	data := map[string]string{}
+	for name, p := range params {
+		v, ok := values[name]
+		if !ok || v == nil {
+			if p.Required {
+				return "", fmt.Errorf("missing parameter %s", name)
+			}
+			continue
+		}
+		var s string
+		switch p.Type {
+		case "string":
+			str, ok := v.(string)
+			if !ok {
+				return "", fmt.Errorf("parameter %s must be a string", name)
+			}
+			if len(p.Enum) > 0 && !slices.Contains(p.Enum, str) {
+				return "", fmt.Errorf("parameter %s must be one of %s", name, strings.Join(p.Enum, ", "))
+			}
+			s = workspace.ShellQuote(str)
+		case "integer", "number":
+			n, ok := v.(json.Number)
+			if !ok {
+				return "", fmt.Errorf("parameter %s must be a %s", name, p.Type)
+			}
+			if _, err := n.Int64(); err != nil && p.Type == "integer" {
+				return "", fmt.Errorf("parameter %s must be an integer", name)
+			}
+			if _, err := n.Float64(); err != nil {
+				return "", fmt.Errorf("parameter %s must be a number", name)
+			}
+			s = n.String()
+		case "boolean":
+			b, ok := v.(bool)
+			if !ok {
+				return "", fmt.Errorf("parameter %s must be a boolean", name)
+			}
+			// so that {{if .flag}} works
+			if !b {
+				continue
+			}
+			s = "true"
+		}
+		data[name] = s
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
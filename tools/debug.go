@@ -0,0 +1,663 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+// The debug tools drive a headless delve (dlv) over its JSON-RPC API, one
+// debugging session at a time.
+
+var DebugStartDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "debug_start",
+		Description: "Build a Go package, or its tests, with the delve debugger and start it stopped at the beginning, replacing any running debug session. Then set breakpoints with debug_breakpoint and run with debug_command.",
+		Parameters: objectParameters([]string{"package"}, map[string]Property{
+			"package": {
+				Type:        api.PropertyType{"string"},
+				Description: "The package to debug, e.g. ./cmd/server.",
+			},
+			"test": {
+				Type:        api.PropertyType{"boolean"},
+				Description: "Optional, debug the package's tests instead of its main program.",
+			},
+			"args": {
+				Type:        api.PropertyType{"array"},
+				Items:       map[string]string{"type": "string"},
+				Description: "Optional arguments for the program, or for the test binary, e.g. [\"-test.run\", \"TestParse\"].",
+			},
+		}),
+	},
+	Function: DebugStart,
+	Effect:   EffectExec,
+}
+
+var DebugBreakpointDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "debug_breakpoint",
+		Description: "Set a breakpoint in the debug session, at a file and line or at a function.",
+		Parameters: objectParameters([]string{"location"}, map[string]Property{
+			"location": {
+				Type:        api.PropertyType{"string"},
+				Description: "Where to break, e.g. parser/parse.go:42 or parser.(*Parser).Parse.",
+			},
+			"condition": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional Go expression, only break when it is true, e.g. i == 10.",
+			},
+		}),
+	},
+	Function: DebugBreakpoint,
+}
+
+var DebugCommandDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "debug_command",
+		Description: "Run the program in the debug session until the next breakpoint (continue), the next line (next), into a call (step) or out of the current function (stepout), and return where it stopped and the program's output meanwhile.",
+		Parameters: objectParameters([]string{"command"}, map[string]Property{
+			"command": {
+				Type:        api.PropertyType{"string"},
+				Description: "The command to run.",
+				Enum:        []any{"continue", "next", "step", "stepout"},
+			},
+		}),
+	},
+	Function: DebugCommand,
+	Effect:   EffectExec,
+}
+
+var DebugInspectDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "debug_inspect",
+		Description: "Inspect the stopped program in the debug session: evaluate an expression, or without one, show the stack and the arguments and local variables of the current function.",
+		Parameters: objectParameters(nil, map[string]Property{
+			"expression": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional Go expression to evaluate, e.g. p.tokens[i] or len(buf).",
+			},
+			"frame": {
+				Type:        api.PropertyType{"integer"},
+				Description: "Optional stack frame to evaluate in, 0 (the default) is the current function, 1 its caller and so on.",
+			},
+		}),
+	},
+	Function: DebugInspect,
+}
+
+var DebugStopDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "debug_stop",
+		Description: "End the debug session, killing the program.",
+		Parameters:  objectParameters(nil, map[string]Property{}),
+	},
+	Function: DebugStop,
+	Effect:   EffectExec,
+}
+
+const (
+	// how long to wait for delve to build the program and listen
+	debugStartTimeout = 2 * time.Minute
+	// the program output returned with each command
+	maxDebugOutput  = 8 * 1024
+	debugStackDepth = 20
+)
+
+var debugLoadConfig = dlvLoadConfig{
+	FollowPointers:     true,
+	MaxVariableRecurse: 2,
+	MaxStringLen:       256,
+	MaxArrayValues:     32,
+	MaxStructFields:    -1,
+}
+
+// debugger is the running debug session.
+var debugger struct {
+	sync.Mutex
+	client *rpc.Client
+	stop   func()
+	output *debugOutput
+}
+
+type DebugStartInput struct {
+	Package string   `json:"package"`
+	Test    bool     `json:"test,omitempty"`
+	Args    []string `json:"args,omitempty"`
+}
+
+func DebugStart(ctx context.Context, input json.RawMessage) (string, error) {
+	debugStartInput := DebugStartInput{}
+	err := json.Unmarshal(input, &debugStartInput)
+	if err != nil {
+		return "", err
+	}
+	if debugStartInput.Package == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	root := workspace.FromContext(ctx).Primary()
+	if root.FS != workspace.Local {
+		return "", fmt.Errorf("debugging is only supported in local workspace roots")
+	}
+	verb := "debug"
+	if debugStartInput.Test {
+		verb = "test"
+	}
+	addr, err := freeAddr()
+	if err != nil {
+		return "", err
+	}
+	script := []string{"exec", "dlv", verb, debugStartInput.Package, "--headless", "--api-version=2", "--listen=" + addr}
+	if len(debugStartInput.Args) > 0 {
+		script = append(script, "--")
+		script = append(script, debugStartInput.Args...)
+	}
+	for i, arg := range script[1:] {
+		script[i+1] = workspace.ShellQuote(arg)
+	}
+	if DryRun(ctx) {
+		return fmt.Sprintf("dry run, the debugger was not started, it would run:\n%s", strings.Join(script[1:], " ")), nil
+	}
+
+	debugger.Lock()
+	defer debugger.Unlock()
+	stopDebugger()
+
+	// the session outlives this call
+	procCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	output := &debugOutput{}
+	cmd := root.FS.Command(procCtx, root.Path, strings.Join(script, " "))
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.WaitDelay = time.Second
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return "", fmt.Errorf("starting dlv: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	deadline := time.Now().Add(debugStartTimeout)
+	for {
+		client, err := jsonrpc.Dial("tcp", addr)
+		if err == nil {
+			debugger.client, debugger.output = client, output
+			debugger.stop = func() {
+				var out struct{}
+				_ = client.Call("RPCServer.Detach", dlvDetachIn{Kill: true}, &out)
+				_ = client.Close()
+				cancel()
+				<-exited
+			}
+			return fmt.Sprintf("debugging %s, stopped before main\n%s", debugStartInput.Package, output.Since()), nil
+		}
+		select {
+		case <-exited:
+			cancel()
+			return "", fmt.Errorf("dlv exited:\n%s", output.Since())
+		case <-ctx.Done():
+			cancel()
+			<-exited
+			return "", ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			cancel()
+			<-exited
+			return "", fmt.Errorf("dlv did not start listening within %s:\n%s", debugStartTimeout, output.Since())
+		}
+	}
+}
+
+type DebugBreakpointInput struct {
+	Location  string `json:"location"`
+	Condition string `json:"condition,omitempty"`
+}
+
+func DebugBreakpoint(ctx context.Context, input json.RawMessage) (string, error) {
+	debugBreakpointInput := DebugBreakpointInput{}
+	err := json.Unmarshal(input, &debugBreakpointInput)
+	if err != nil {
+		return "", err
+	}
+	if debugBreakpointInput.Location == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	debugger.Lock()
+	defer debugger.Unlock()
+	if debugger.client == nil {
+		return "", errNoDebugSession
+	}
+
+	var found struct {
+		Locations []dlvLocation
+	}
+	err = debugger.client.Call("RPCServer.FindLocation", dlvFindLocationIn{
+		Scope: dlvEvalScope{GoroutineID: -1},
+		Loc:   debugBreakpointInput.Location,
+	}, &found)
+	if err != nil {
+		return "", fmt.Errorf("finding %s: %v", debugBreakpointInput.Location, err)
+	}
+	if len(found.Locations) == 0 {
+		return "", fmt.Errorf("location %s not found", debugBreakpointInput.Location)
+	}
+	loc := found.Locations[0]
+	var created struct {
+		Breakpoint dlvBreakpoint
+	}
+	err = debugger.client.Call("RPCServer.CreateBreakpoint", dlvCreateBreakpointIn{
+		Breakpoint: dlvBreakpoint{File: loc.File, Line: loc.Line, Cond: debugBreakpointInput.Condition},
+	}, &created)
+	if err != nil {
+		return "", fmt.Errorf("setting breakpoint: %v", err)
+	}
+	return fmt.Sprintf("breakpoint %d at %s", created.Breakpoint.ID, formatLocation(ctx, loc)), nil
+}
+
+type DebugCommandInput struct {
+	Command string `json:"command"`
+}
+
+func DebugCommand(ctx context.Context, input json.RawMessage) (string, error) {
+	debugCommandInput := DebugCommandInput{}
+	err := json.Unmarshal(input, &debugCommandInput)
+	if err != nil {
+		return "", err
+	}
+	switch debugCommandInput.Command {
+	case "continue", "next", "step", "stepout":
+	default:
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	if DryRun(ctx) {
+		return "dry run, the program was not run", nil
+	}
+	debugger.Lock()
+	defer debugger.Unlock()
+	if debugger.client == nil {
+		return "", errNoDebugSession
+	}
+
+	var out struct {
+		State dlvDebuggerState
+	}
+	call := debugger.client.Go("RPCServer.Command", dlvDebuggerCommand{Name: debugCommandInput.Command}, &out, nil)
+	interrupted := false
+	select {
+	case <-call.Done:
+	case <-ctx.Done():
+		// stop the program wherever it is, the pending call then returns
+		interrupted = true
+		var halted struct {
+			State dlvDebuggerState
+		}
+		_ = debugger.client.Call("RPCServer.Command", dlvDebuggerCommand{Name: "halt"}, &halted)
+		<-call.Done
+	}
+	if call.Error != nil {
+		return "", fmt.Errorf("%s: %v", debugCommandInput.Command, call.Error)
+	}
+
+	var b strings.Builder
+	state := out.State
+	switch {
+	case state.Exited:
+		fmt.Fprintf(&b, "the program exited with status %d\n", state.ExitStatus)
+	case state.CurrentThread != nil:
+		t := state.CurrentThread
+		reason := "stopped"
+		if t.Breakpoint != nil && t.Breakpoint.ID > 0 {
+			reason = fmt.Sprintf("stopped at breakpoint %d", t.Breakpoint.ID)
+		}
+		if interrupted {
+			reason = "interrupted by the user"
+		}
+		fmt.Fprintf(&b, "%s in %s\n", reason, formatLocation(ctx, dlvLocation{File: t.File, Line: t.Line, Function: t.Function}))
+		b.WriteString(sourceLines(t.File, t.Line))
+	}
+	if output := debugger.output.Since(); output != "" {
+		fmt.Fprintf(&b, "program output:\n%s", output)
+	}
+	return b.String(), nil
+}
+
+type DebugInspectInput struct {
+	Expression string `json:"expression,omitempty"`
+	Frame      int    `json:"frame,omitempty"`
+}
+
+func DebugInspect(ctx context.Context, input json.RawMessage) (string, error) {
+	debugInspectInput := DebugInspectInput{}
+	err := json.Unmarshal(input, &debugInspectInput)
+	if err != nil {
+		return "", err
+	}
+	debugger.Lock()
+	defer debugger.Unlock()
+	if debugger.client == nil {
+		return "", errNoDebugSession
+	}
+	scope := dlvEvalScope{GoroutineID: -1, Frame: debugInspectInput.Frame}
+
+	if debugInspectInput.Expression != "" {
+		var out struct {
+			Variable *dlvVariable
+		}
+		err := debugger.client.Call("RPCServer.Eval", dlvEvalIn{
+			Scope: scope,
+			Expr:  debugInspectInput.Expression,
+			Cfg:   &debugLoadConfig,
+		}, &out)
+		if err != nil {
+			return "", fmt.Errorf("evaluating %s: %v", debugInspectInput.Expression, err)
+		}
+		if out.Variable == nil {
+			return "no value", nil
+		}
+		return fmt.Sprintf("%s = %s", out.Variable.Type, formatVariable(*out.Variable, 0)), nil
+	}
+
+	var b strings.Builder
+	var stack struct {
+		Locations []dlvStackframe
+	}
+	err = debugger.client.Call("RPCServer.Stacktrace", dlvStacktraceIn{Id: -1, Depth: debugStackDepth}, &stack)
+	if err != nil {
+		return "", fmt.Errorf("getting the stack: %v", err)
+	}
+	b.WriteString("stack:\n")
+	for i, f := range stack.Locations {
+		fmt.Fprintf(&b, "%3d  %s\n", i, formatLocation(ctx, f.dlvLocation))
+	}
+
+	var args struct {
+		Args []dlvVariable
+	}
+	err = debugger.client.Call("RPCServer.ListFunctionArgs", dlvListVarsIn{Scope: scope, Cfg: debugLoadConfig}, &args)
+	if err != nil {
+		return "", fmt.Errorf("listing arguments: %v", err)
+	}
+	var locals struct {
+		Variables []dlvVariable
+	}
+	err = debugger.client.Call("RPCServer.ListLocalVars", dlvListVarsIn{Scope: scope, Cfg: debugLoadConfig}, &locals)
+	if err != nil {
+		return "", fmt.Errorf("listing local variables: %v", err)
+	}
+	fmt.Fprintf(&b, "arguments of frame %d:\n", debugInspectInput.Frame)
+	for _, v := range args.Args {
+		fmt.Fprintf(&b, "  %s %s = %s\n", v.Name, v.Type, formatVariable(v, 0))
+	}
+	b.WriteString("local variables:\n")
+	for _, v := range locals.Variables {
+		fmt.Fprintf(&b, "  %s %s = %s\n", v.Name, v.Type, formatVariable(v, 0))
+	}
+	return b.String(), nil
+}
+
+func DebugStop(ctx context.Context, _ json.RawMessage) (string, error) {
+	if DryRun(ctx) {
+		return "dry run, the debug session was not stopped", nil
+	}
+	debugger.Lock()
+	defer debugger.Unlock()
+	if debugger.client == nil {
+		return "", errNoDebugSession
+	}
+	stopDebugger()
+	return "OK", nil
+}
+
+var errNoDebugSession = errors.New("no debug session, start one with debug_start")
+
+// stopDebugger ends the running debug session, if any, with the lock held.
+func stopDebugger() {
+	if debugger.client == nil {
+		return
+	}
+	debugger.stop()
+	debugger.client, debugger.stop, debugger.output = nil, nil, nil
+}
+
+func freeAddr() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
+
+func formatLocation(ctx context.Context, loc dlvLocation) string {
+	ws := workspace.FromContext(ctx)
+	file := loc.File
+	for _, root := range ws.Roots {
+		if strings.HasPrefix(file, root.Path+"/") {
+			file = ws.Display(root, file)
+			break
+		}
+	}
+	s := fmt.Sprintf("%s:%d", file, loc.Line)
+	if loc.Function != nil && loc.Function.Name != "" {
+		s = loc.Function.Name + " " + s
+	}
+	return s
+}
+
+// sourceLines returns the lines around line in the local file, marking it.
+func sourceLines(file string, line int) string {
+	content, err := workspace.Local.ReadFile(file)
+	if err != nil || line < 1 {
+		return ""
+	}
+	lines := strings.Split(string(content), "\n")
+	var b strings.Builder
+	for n := max(line-traceContext, 1); n <= min(line+traceContext, len(lines)); n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s%5d  %s\n", marker, n, lines[n-1])
+	}
+	return b.String()
+}
+
+// formatVariable renders a variable loaded by delve on one line.
+func formatVariable(v dlvVariable, depth int) string {
+	if v.Unreadable != "" {
+		return "(unreadable " + v.Unreadable + ")"
+	}
+	if depth > debugLoadConfig.MaxVariableRecurse+1 {
+		return "..."
+	}
+	more := ""
+	if v.Len > int64(len(v.Children)) {
+		more = fmt.Sprintf(", ...+%d more", v.Len-int64(len(v.Children)))
+	}
+	var parts []string
+	switch reflect.Kind(v.Kind) {
+	case reflect.String:
+		if v.Len > int64(len(v.Value)) {
+			return strconv.Quote(v.Value) + fmt.Sprintf("...+%d more", v.Len-int64(len(v.Value)))
+		}
+		return strconv.Quote(v.Value)
+	case reflect.Pointer:
+		if len(v.Children) == 0 || v.Children[0].Addr == 0 {
+			return "nil"
+		}
+		return "&" + formatVariable(v.Children[0], depth+1)
+	case reflect.Interface:
+		if len(v.Children) == 0 {
+			return "nil"
+		}
+		return formatVariable(v.Children[0], depth+1)
+	case reflect.Map:
+		if v.Base == 0 && len(v.Children) == 0 {
+			return "nil"
+		}
+		for i := 0; i+1 < len(v.Children); i += 2 {
+			parts = append(parts, formatVariable(v.Children[i], depth+1)+": "+formatVariable(v.Children[i+1], depth+1))
+		}
+		if v.Len > int64(len(v.Children)/2) {
+			more = fmt.Sprintf(", ...+%d more", v.Len-int64(len(v.Children)/2))
+		}
+	case reflect.Struct:
+		for _, c := range v.Children {
+			parts = append(parts, c.Name+": "+formatVariable(c, depth+1))
+		}
+	case reflect.Slice:
+		if v.Base == 0 {
+			return "nil"
+		}
+		fallthrough
+	case reflect.Array:
+		for _, c := range v.Children {
+			parts = append(parts, formatVariable(c, depth+1))
+		}
+	default:
+		if v.Value == "" && len(v.Children) == 0 {
+			return "nil"
+		}
+		return v.Value
+	}
+	return "{" + strings.Join(parts, ", ") + more + "}"
+}
+
+// debugOutput collects the program's output, Since returns what was
+// written since the previous call.
+type debugOutput struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	read int
+}
+
+func (o *debugOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.Write(p)
+}
+
+func (o *debugOutput) Since() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	unread := o.buf.Bytes()[o.read:]
+	o.read = o.buf.Len()
+	if len(unread) > maxDebugOutput {
+		return fmt.Sprintf("[%d bytes omitted]\n%s", len(unread)-maxDebugOutput, unread[len(unread)-maxDebugOutput:])
+	}
+	return string(unread)
+}
+
+// The subset of delve's service/api and service/rpc2 types used.
+
+type dlvLoadConfig struct {
+	FollowPointers     bool
+	MaxVariableRecurse int
+	MaxStringLen       int
+	MaxArrayValues     int
+	MaxStructFields    int
+}
+
+type dlvEvalScope struct {
+	GoroutineID  int64
+	Frame        int
+	DeferredCall int
+}
+
+type dlvFunction struct {
+	Name string `json:"name"`
+}
+
+type dlvLocation struct {
+	PC       uint64       `json:"pc"`
+	File     string       `json:"file"`
+	Line     int          `json:"line"`
+	Function *dlvFunction `json:"function,omitempty"`
+}
+
+type dlvStackframe struct {
+	dlvLocation
+}
+
+type dlvBreakpoint struct {
+	ID   int    `json:"id"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Cond string `json:"Cond"`
+}
+
+type dlvThread struct {
+	File       string         `json:"file"`
+	Line       int            `json:"line"`
+	Function   *dlvFunction   `json:"function,omitempty"`
+	Breakpoint *dlvBreakpoint `json:"breakPoint,omitempty"`
+}
+
+type dlvDebuggerState struct {
+	CurrentThread *dlvThread `json:"currentThread,omitempty"`
+	Exited        bool       `json:"exited"`
+	ExitStatus    int        `json:"exitStatus"`
+}
+
+type dlvDebuggerCommand struct {
+	Name string `json:"name"`
+}
+
+type dlvVariable struct {
+	Name       string        `json:"name"`
+	Addr       uint64        `json:"addr"`
+	OnlyAddr   bool          `json:"onlyAddr"`
+	Type       string        `json:"type"`
+	Kind       uint          `json:"kind"`
+	Value      string        `json:"value"`
+	Len        int64         `json:"len"`
+	Children   []dlvVariable `json:"children"`
+	Base       uint64        `json:"base"`
+	Unreadable string        `json:"unreadable"`
+}
+
+type dlvFindLocationIn struct {
+	Scope dlvEvalScope
+	Loc   string
+}
+
+type dlvCreateBreakpointIn struct {
+	Breakpoint dlvBreakpoint
+}
+
+type dlvEvalIn struct {
+	Scope dlvEvalScope
+	Expr  string
+	Cfg   *dlvLoadConfig
+}
+
+type dlvListVarsIn struct {
+	Scope dlvEvalScope
+	Cfg   dlvLoadConfig
+}
+
+type dlvStacktraceIn struct {
+	Id    int64
+	Depth int
+}
+
+type dlvDetachIn struct {
+	Kill bool
+}
@@ -0,0 +1,350 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var ListDependenciesDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "list_dependencies",
+		Description: "List the dependencies declared in the go.mod and package.json files in a directory, with their versions.",
+		Parameters: objectParameters(nil, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional relative path of the directory containing go.mod or package.json, defaults to the current directory.",
+			},
+		}),
+	},
+	Function: ListDependencies,
+}
+
+var DependencyInfoDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "dependency_info",
+		Description: "Look up a Go module in the module proxy or an npm package in the npm registry: its latest version, whether it is newer than the given version, and where its documentation is.",
+		Parameters: objectParameters([]string{"ecosystem", "name"}, map[string]Property{
+			"ecosystem": {
+				Type:        api.PropertyType{"string"},
+				Description: "The package ecosystem.",
+				Enum:        []any{"go", "npm"},
+			},
+			"name": {
+				Type:        api.PropertyType{"string"},
+				Description: "The module path or package name, e.g. golang.org/x/term or react.",
+			},
+			"version": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional version in use, to check for updates.",
+			},
+		}),
+	},
+	Function: DependencyInfo,
+	Effect:   EffectNetwork,
+}
+
+type ListDependenciesInput struct {
+	Path string `json:"path,omitempty"`
+}
+
+func ListDependencies(ctx context.Context, input json.RawMessage) (string, error) {
+	listDependenciesInput := ListDependenciesInput{}
+	err := json.Unmarshal(input, &listDependenciesInput)
+	if err != nil {
+		return "", err
+	}
+	root, dir, err := workspace.FromContext(ctx).Resolve(listDependenciesInput.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	found := false
+	goMod, err := root.FS.ReadFile(filepath.Join(dir, "go.mod"))
+	switch {
+	case err == nil:
+		found = true
+		out.WriteString("go.mod:\n")
+		for _, dep := range parseGoMod(goMod) {
+			fmt.Fprintf(&out, "%s %s%s\n", dep.name, dep.version, dep.note)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return "", err
+	}
+	packageJSON, err := root.FS.ReadFile(filepath.Join(dir, "package.json"))
+	switch {
+	case err == nil:
+		found = true
+		deps, err := parsePackageJSON(packageJSON)
+		if err != nil {
+			return "", fmt.Errorf("invalid package.json: %v", err)
+		}
+		out.WriteString("package.json:\n")
+		for _, dep := range deps {
+			fmt.Fprintf(&out, "%s %s%s\n", dep.name, dep.version, dep.note)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("no go.mod or package.json found")
+	}
+	return out.String(), nil
+}
+
+type dependency struct {
+	name, version, note string
+}
+
+// parseGoMod returns the requirements and replacements in a go.mod file.
+func parseGoMod(buf []byte) []dependency {
+	var deps []dependency
+	block := ""
+	s := bufio.NewScanner(bytes.NewReader(buf))
+	for s.Scan() {
+		line, comment := s.Text(), ""
+		if i := strings.Index(line, "//"); i >= 0 {
+			line, comment = line[:i], strings.TrimSpace(line[i+2:])
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		verb := block
+		switch fields[0] {
+		case ")":
+			block = ""
+			continue
+		case "require", "replace":
+			verb, fields = fields[0], fields[1:]
+			if len(fields) == 1 && fields[0] == "(" {
+				block = verb
+				continue
+			}
+		}
+		switch {
+		case verb == "require" && len(fields) >= 2:
+			dep := dependency{name: fields[0], version: fields[1]}
+			if comment == "indirect" {
+				dep.note = " (indirect)"
+			}
+			deps = append(deps, dep)
+		case verb == "replace":
+			deps = append(deps, dependency{name: strings.Join(fields, " "), note: " (replace)"})
+		}
+	}
+	return deps
+}
+
+// parsePackageJSON returns the dependencies in a package.json file.
+func parsePackageJSON(buf []byte) ([]dependency, error) {
+	var pkg map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &pkg); err != nil {
+		return nil, err
+	}
+	var deps []dependency
+	for _, kind := range []struct{ key, note string }{
+		{"dependencies", ""},
+		{"devDependencies", " (dev)"},
+		{"peerDependencies", " (peer)"},
+		{"optionalDependencies", " (optional)"},
+	} {
+		var versions map[string]string
+		if raw, ok := pkg[kind.key]; ok {
+			if err := json.Unmarshal(raw, &versions); err != nil {
+				return nil, fmt.Errorf("%s: %v", kind.key, err)
+			}
+		}
+		names := make([]string, 0, len(versions))
+		for name := range versions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			deps = append(deps, dependency{name: name, version: versions[name], note: kind.note})
+		}
+	}
+	return deps, nil
+}
+
+type DependencyInfoInput struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+}
+
+const dependencyInfoTimeout = 30 * time.Second
+
+func DependencyInfo(ctx context.Context, input json.RawMessage) (string, error) {
+	dependencyInfoInput := DependencyInfoInput{}
+	err := json.Unmarshal(input, &dependencyInfoInput)
+	if err != nil {
+		return "", err
+	}
+	name, version := dependencyInfoInput.Name, dependencyInfoInput.Version
+	if name == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	if DryRun(ctx) {
+		return fmt.Sprintf("dry run, the %s registry was not queried for %s", dependencyInfoInput.Ecosystem, name), nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, dependencyInfoTimeout)
+	defer cancel()
+
+	var latest, released, docs, description string
+	switch dependencyInfoInput.Ecosystem {
+	case "go":
+		var info struct {
+			Version string
+			Time    time.Time
+		}
+		if err := getJSON(ctx, goProxy()+"/"+escapeModulePath(name)+"/@latest", &info); err != nil {
+			return "", err
+		}
+		latest = info.Version
+		if !info.Time.IsZero() {
+			released = info.Time.Format(time.DateOnly)
+		}
+		docs = "https://pkg.go.dev/" + name + "@" + latest
+	case "npm":
+		var info struct {
+			Description string            `json:"description"`
+			Homepage    string            `json:"homepage"`
+			DistTags    map[string]string `json:"dist-tags"`
+			Time        map[string]string `json:"time"`
+		}
+		if err := getJSON(ctx, "https://registry.npmjs.org/"+url.PathEscape(name), &info); err != nil {
+			return "", err
+		}
+		latest, description = info.DistTags["latest"], info.Description
+		if t, err := time.Parse(time.RFC3339, info.Time[latest]); err == nil {
+			released = t.Format(time.DateOnly)
+		}
+		docs = info.Homepage
+		if docs == "" {
+			docs = "https://www.npmjs.com/package/" + name
+		}
+		// ranges like ^1.2.3 name their lowest version
+		version = strings.TrimLeft(version, "^~=v ")
+	default:
+		return "", fmt.Errorf("unknown ecosystem %q, use go or npm", dependencyInfoInput.Ecosystem)
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s\nlatest: %s", name, latest)
+	if released != "" {
+		fmt.Fprintf(&out, " (released %s)", released)
+	}
+	out.WriteString("\n")
+	if version != "" {
+		switch c := compareVersions(latest, version); {
+		case c > 0:
+			fmt.Fprintf(&out, "update available from %s\n", dependencyInfoInput.Version)
+		case c == 0:
+			fmt.Fprintf(&out, "%s is the latest version\n", dependencyInfoInput.Version)
+		default:
+			fmt.Fprintf(&out, "%s is newer than the latest release\n", dependencyInfoInput.Version)
+		}
+	}
+	if description != "" {
+		fmt.Fprintf(&out, "description: %s\n", description)
+	}
+	fmt.Fprintf(&out, "docs: %s\n", docs)
+	return out.String(), nil
+}
+
+// goProxy returns the first proxy in GOPROXY that can be queried.
+func goProxy() string {
+	for _, p := range strings.FieldsFunc(os.Getenv("GOPROXY"), func(r rune) bool { return r == ',' || r == '|' }) {
+		if strings.HasPrefix(p, "https://") || strings.HasPrefix(p, "http://") {
+			return strings.TrimSuffix(p, "/")
+		}
+	}
+	return "https://proxy.golang.org"
+}
+
+// escapeModulePath escapes upper case letters as the module proxy
+// protocol requires, e.g. github.com/BurntSushi as github.com/!burnt!sushi.
+func escapeModulePath(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone {
+		return fmt.Errorf("not found: %s", u)
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", u, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// compareVersions compares semantic versions, with or without a leading v,
+// numerically by major, minor and patch, a pre-release sorting before its
+// release.
+func compareVersions(a, b string) int {
+	parse := func(v string) ([3]int, string) {
+		v = strings.TrimPrefix(v, "v")
+		v, _, _ = strings.Cut(v, "+")
+		v, pre, _ := strings.Cut(v, "-")
+		var n [3]int
+		for i, part := range strings.SplitN(v, ".", 3) {
+			n[i], _ = strconv.Atoi(part)
+		}
+		return n, pre
+	}
+	an, apre := parse(a)
+	bn, bpre := parse(b)
+	for i := range an {
+		if an[i] != bn[i] {
+			if an[i] < bn[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case apre == bpre:
+		return 0
+	case apre == "":
+		return 1
+	case bpre == "":
+		return -1
+	}
+	return strings.Compare(apre, bpre)
+}
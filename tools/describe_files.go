@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/index"
+	"github.com/mschoch/dacs/workspace"
+)
+
+const (
+	// files described per call, summarizing a new file takes an inference
+	maxDescribeFiles = 40
+	// larger files are likely generated or data
+	maxDescribeBytes = 256 * 1024
+)
+
+func NewDescribeFiles(summaries *index.Summaries) Tool {
+	return Tool{
+		Definition: api.ToolFunction{
+			Name:        "describe_files",
+			Description: "Describe files in one line each, without returning their contents, to decide which ones are worth reading fully. Directories are described file by file, recursively.",
+			Parameters: objectParameters([]string{"paths"}, map[string]Property{
+				"paths": {
+					Type:        api.PropertyType{"array"},
+					Items:       map[string]string{"type": "string"},
+					Description: "The relative paths of the files and directories to describe.",
+				},
+			}),
+		},
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			return describeFiles(ctx, summaries, input)
+		},
+	}
+}
+
+type DescribeFilesInput struct {
+	Paths []string `json:"paths"`
+}
+
+func describeFiles(ctx context.Context, summaries *index.Summaries, input json.RawMessage) (string, error) {
+	describeFilesInput := DescribeFilesInput{}
+	err := json.Unmarshal(input, &describeFilesInput)
+	if err != nil {
+		return "", err
+	}
+	if len(describeFilesInput.Paths) == 0 {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+
+	ws := workspace.FromContext(ctx)
+	type file struct {
+		root *workspace.Root
+		abs  string
+	}
+	var files []file
+	for _, p := range describeFilesInput.Paths {
+		root, abs, err := ws.Resolve(p)
+		if err != nil {
+			return "", err
+		}
+		// walking a file lists nothing
+		entries, err := root.FS.Walk(abs)
+		if err != nil {
+			return "", err
+		}
+		if len(entries) == 0 {
+			files = append(files, file{root, abs})
+			continue
+		}
+		for _, e := range entries {
+			if strings.HasSuffix(e, "/") || skipDescribe(e) {
+				continue
+			}
+			path := filepath.Join(abs, e)
+			if _, _, err := ws.Resolve(ws.Display(root, path)); err != nil {
+				// a symlink out of the root
+				continue
+			}
+			if CheckPath(ctx, ws.Display(root, path)) != nil {
+				continue
+			}
+			files = append(files, file{root, path})
+		}
+	}
+
+	more := len(files) - maxDescribeFiles
+	if more > 0 {
+		files = files[:maxDescribeFiles]
+	}
+	defer summaries.Save()
+
+	var sb strings.Builder
+	for _, f := range files {
+		path := ws.Display(f.root, f.abs)
+		content, err := f.root.FS.ReadFile(f.abs)
+		switch {
+		case err != nil:
+			fmt.Fprintf(&sb, "%s: error: %s\n", path, err)
+		case len(content) == 0:
+			fmt.Fprintf(&sb, "%s: empty\n", path)
+		case len(content) > maxDescribeBytes:
+			fmt.Fprintf(&sb, "%s: large file, %d bytes\n", path, len(content))
+		case bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0:
+			fmt.Fprintf(&sb, "%s: binary file, %d bytes\n", path, len(content))
+		default:
+			summary, err := summaries.Summarize(ctx, path, content)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&sb, "%s: %s\n", path, summary)
+		}
+	}
+	if more > 0 {
+		fmt.Fprintf(&sb, "... %d more files, describe them by narrower paths\n", more)
+	}
+	return sb.String(), nil
+}
+
+// skipDescribe reports whether a file found in a directory is in a hidden
+// or vendored directory, or hidden itself.
+func skipDescribe(rel string) bool {
+	for _, part := range strings.Split(rel, "/") {
+		if strings.HasPrefix(part, ".") || part == "vendor" || part == "node_modules" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// lines of context around each change in a diff
+	diffContext = 3
+	// files with more lines than this are not diffed line by line
+	maxDiffLines = 5000
+)
+
+// UnifiedDiff returns the changes from a to b in unified diff format.
+func UnifiedDiff(path, a, b string) string {
+	al, bl := splitLines(a), splitLines(b)
+	if len(al) > maxDiffLines || len(bl) > maxDiffLines {
+		return fmt.Sprintf("--- %s\n+++ %s\n(%d lines changed to %d lines, too large to diff)\n", path, path, len(al), len(bl))
+	}
+
+	// ops is the edit script, ' ' keeps, '-' deletes from a, '+' inserts b
+	type op struct {
+		kind byte
+		line string
+	}
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			ops = append(ops, op{' ', al[i]})
+			i, j = i+1, j+1
+		case i < len(al) && (j == len(bl) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', al[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', bl[j]})
+			j++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", path, path)
+	// group the changes into hunks with their context
+	for start := 0; start < len(ops); {
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		from := max(start-diffContext, 0)
+		end := start
+		for unchanged := 0; end < len(ops) && unchanged <= 2*diffContext; end++ {
+			if ops[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		// trim the trailing context down to diffContext lines
+		for end > start && ops[end-1].kind == ' ' {
+			end--
+		}
+		end = min(end+diffContext, len(ops))
+
+		aStart, bStart := 1, 1
+		for _, o := range ops[:from] {
+			if o.kind != '+' {
+				aStart++
+			}
+			if o.kind != '-' {
+				bStart++
+			}
+		}
+		aLen, bLen := 0, 0
+		for _, o := range ops[from:end] {
+			if o.kind != '+' {
+				aLen++
+			}
+			if o.kind != '-' {
+				bLen++
+			}
+		}
+		// empty ranges start at the line before them
+		if aLen == 0 {
+			aStart--
+		}
+		if bLen == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, o := range ops[from:end] {
+			fmt.Fprintf(&sb, "%c%s\n", o.kind, o.line)
+		}
+		start = end
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var EditFileDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name: "edit_file",
+		Description: `Make edits to a text file.
+
+Replaces 'old_str' with 'new_str' in the given file. 'old_str' and 'new_str' MUST be different from each other.
+
+If the file specified with path doesn't exist, it will be created.
+`,
+		Parameters: objectParameters(nil, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "The path to the file",
+			},
+			"old_str": {
+				Type:        api.PropertyType{"string"},
+				Description: "Text to search for - must match exactly and must only have one match exactly",
+			},
+			"new_str": {
+				Type:        api.PropertyType{"string"},
+				Description: "Text to replace old_str with",
+			},
+		}),
+	},
+	Function: EditFile,
+	Effect:   EffectWrite,
+}
+
+type EditFileInput struct {
+	Path   string `json:"path"`
+	OldStr string `json:"old_str"`
+	NewStr string `json:"new_str"`
+}
+
+func EditFile(ctx context.Context, input json.RawMessage) (string, error) {
+	editFileInput := EditFileInput{}
+	err := json.Unmarshal(input, &editFileInput)
+	if err != nil {
+		return "", err
+	}
+
+	if editFileInput.Path == "" || editFileInput.OldStr == editFileInput.NewStr {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+
+	root, path, err := workspace.FromContext(ctx).Resolve(editFileInput.Path)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := openText(root, path)
+	if err != nil {
+		return "", err
+	}
+	// the model may copy \r\n line endings, the text has \n
+	oldStr := strings.ReplaceAll(editFileInput.OldStr, "\r\n", "\n")
+	newStr := strings.ReplaceAll(editFileInput.NewStr, "\r\n", "\n")
+	if !f.exists {
+		if oldStr != "" {
+			return "", fmt.Errorf("%s does not exist, pass an empty old_str to create it", editFileInput.Path)
+		}
+		newStr = f.style.conform(newStr, true, true)
+		if DryRun(ctx) {
+			return "dry run, the file was not created, it would have this content:\n" + UnifiedDiff(editFileInput.Path, "", newStr), nil
+		}
+		if err := f.write(newStr); err != nil {
+			return "", fmt.Errorf("failed to create file: %w", err)
+		}
+		return fmt.Sprintf("Successfully created file %s", editFileInput.Path), nil
+	}
+
+	oldContent := f.content
+	startsLine, endsLine := wholeLines(oldContent, oldStr)
+	newStr = f.style.conform(newStr, startsLine, endsLine)
+	newContent := strings.Replace(oldContent, oldStr, newStr, -1)
+
+	if oldContent == newContent && oldStr != "" {
+		return "", fmt.Errorf("old_str not found in file")
+	}
+
+	if DryRun(ctx) {
+		return "dry run, the file was not changed, the edit would make these changes:\n" + UnifiedDiff(editFileInput.Path, oldContent, newContent), nil
+	}
+
+	if err := f.write(newContent); err != nil {
+		return "", err
+	}
+
+	return "OK", nil
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/session"
+)
+
+// FinishDefinition is only offered in headless runs, where nobody reads the
+// answer, the reported outcome becomes the exit code.
+var FinishDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "finish",
+		Description: "Report that you are done with the task, whether it succeeded and a short summary of what was done. Call this exactly once, when there is nothing left to do or you cannot make further progress. Be honest, report failure if the task was not completed or verified.",
+		Parameters: objectParameters([]string{"success", "summary"}, map[string]Property{
+			"success": {
+				Type:        api.PropertyType{"boolean"},
+				Description: "Whether the task was completed successfully.",
+			},
+			"summary": {
+				Type:        api.PropertyType{"string"},
+				Description: "A short summary of what was done, or why it failed.",
+			},
+		}),
+	},
+	Function: Finish,
+}
+
+type FinishInput struct {
+	Success bool   `json:"success"`
+	Summary string `json:"summary"`
+}
+
+func Finish(ctx context.Context, input json.RawMessage) (string, error) {
+	finishInput := FinishInput{}
+	err := json.Unmarshal(input, &finishInput)
+	if err != nil {
+		return "", err
+	}
+	s, ok := session.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("no session to report to")
+	}
+	s.Outcome = &session.Outcome{
+		Success: finishInput.Success,
+		Summary: finishInput.Summary,
+	}
+	return "reported", nil
+}
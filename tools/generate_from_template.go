@@ -0,0 +1,281 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+// TemplateDir is where generate_from_template finds the project's
+// templates, relative to the primary root, one NAME.tmpl file each.
+const TemplateDir = ".dacs/templates"
+
+var GenerateFromTemplateDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name: "generate_from_template",
+		Description: `Create a file from one of the project's templates in ` + TemplateDir + `, such as a handler, migration or component, filled with the variables given, so it matches the project's conventions exactly.
+
+Call it without a template to list the templates and their variables.`,
+		Parameters: objectParameters(nil, map[string]Property{
+			"template": {
+				Type:        api.PropertyType{"string"},
+				Description: "The name of the template, its file name without .tmpl.",
+			},
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "The relative path of the file to create, it must not exist.",
+			},
+			"variables": {
+				Type:        api.PropertyType{"object"},
+				Description: "The values of the template's variables, by name.",
+			},
+		}),
+	},
+	Function: GenerateFromTemplate,
+	Effect:   EffectWrite,
+}
+
+type GenerateFromTemplateInput struct {
+	Template  string         `json:"template"`
+	Path      string         `json:"path"`
+	Variables map[string]any `json:"variables"`
+}
+
+// templateComment is a comment a template starts with, describing it
+var templateComment = regexp.MustCompile(`^\s*\{\{-?\s*/\*((?s:.*?))\*/`)
+
+// templateFuncs are the functions of the templates, for the conventional
+// forms of names.
+var templateFuncs = template.FuncMap{
+	"lower":  strings.ToLower,
+	"upper":  strings.ToUpper,
+	"pascal": func(s string) string { return pascalCase(s, true) },
+	"camel":  func(s string) string { return pascalCase(s, false) },
+	"snake":  func(s string) string { return strings.Join(nameWords(s), "_") },
+	"kebab":  func(s string) string { return strings.Join(nameWords(s), "-") },
+	"join":   strings.Join,
+	"plural": plural,
+}
+
+func GenerateFromTemplate(ctx context.Context, input json.RawMessage) (string, error) {
+	genInput := GenerateFromTemplateInput{}
+	if err := json.Unmarshal(input, &genInput); err != nil {
+		return "", err
+	}
+
+	ws := workspace.FromContext(ctx)
+	primary := ws.Primary()
+	dir := filepath.Join(primary.Path, TemplateDir)
+	if genInput.Template == "" {
+		return listTemplates(primary.FS, dir)
+	}
+	if strings.ContainsAny(genInput.Template, `/\`) || strings.Contains(genInput.Template, "..") {
+		return "", fmt.Errorf("invalid template name %q, use the name of a template in %s", genInput.Template, TemplateDir)
+	}
+	src, err := primary.FS.ReadFile(filepath.Join(dir, genInput.Template+".tmpl"))
+	if errors.Is(err, fs.ErrNotExist) {
+		list, err := listTemplates(primary.FS, dir)
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("no template %s, %s", genInput.Template, list)
+	} else if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New(genInput.Template).Funcs(templateFuncs).Option("missingkey=error").Parse(string(src))
+	if err != nil {
+		return "", fmt.Errorf("invalid template %s: %w", genInput.Template, err)
+	}
+	if genInput.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	var missing []string
+	for _, v := range templateVariables(tmpl) {
+		if _, ok := genInput.Variables[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing variables %s of the template %s", strings.Join(missing, ", "), genInput.Template)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, genInput.Variables); err != nil {
+		return "", err
+	}
+
+	root, path, err := ws.Resolve(genInput.Path)
+	if err != nil {
+		return "", err
+	}
+	f, err := openText(root, path)
+	if err != nil {
+		return "", err
+	}
+	if f.exists {
+		return "", fmt.Errorf("%s exists already, use edit_file to change it", genInput.Path)
+	}
+	if DryRun(ctx) {
+		return "dry run, the file was not created, it would have this content:\n" + UnifiedDiff(genInput.Path, "", buf.String()), nil
+	}
+	if err := f.write(buf.String()); err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	return fmt.Sprintf("Successfully created file %s", genInput.Path), nil
+}
+
+// listTemplates describes the templates in dir, with their variables and
+// the text of a comment they start with, if any.
+func listTemplates(fsys workspace.FS, dir string) (string, error) {
+	files, err := fsys.Walk(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("the project has no templates, they are NAME.tmpl files in %s", TemplateDir)
+	} else if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString("the templates are:\n")
+	for _, f := range files {
+		name, ok := strings.CutSuffix(f, ".tmpl")
+		if !ok || strings.Contains(name, "/") {
+			continue
+		}
+		src, err := fsys.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "- %s", name)
+		tmpl, err := template.New(name).Funcs(templateFuncs).Parse(string(src))
+		if err != nil {
+			fmt.Fprintf(&sb, ", invalid: %v\n", err)
+			continue
+		}
+		if vars := templateVariables(tmpl); len(vars) > 0 {
+			fmt.Fprintf(&sb, ", variables %s", strings.Join(vars, ", "))
+		}
+		if m := templateComment.FindSubmatch(src); m != nil {
+			fmt.Fprintf(&sb, ": %s", strings.Join(strings.Fields(string(m[1])), " "))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// templateVariables returns the names of the fields of the data the
+// template uses outside of range and with, sorted.
+func templateVariables(tmpl *template.Template) []string {
+	var rv []string
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Cmds {
+				walk(c)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			if !slices.Contains(rv, n.Ident[0]) {
+				rv = append(rv, n.Ident[0])
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			// the dot is the element inside
+			walk(n.Pipe)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.ElseList)
+		}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root)
+		}
+	}
+	slices.Sort(rv)
+	return rv
+}
+
+// nameWords splits a name in camel case, snake case or words into its
+// lower case words.
+func nameWords(s string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			if len(word) > 0 {
+				words, word = append(words, string(word)), nil
+			}
+			continue
+		case unicode.IsUpper(r) && len(word) > 0 &&
+			(!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			// HTTPServer is http server
+			words, word = append(words, string(word)), nil
+		}
+		word = append(word, unicode.ToLower(r))
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// pascalCase joins the words of the name capitalized, but for the first
+// unless upper is set.
+func pascalCase(s string, upper bool) string {
+	var sb strings.Builder
+	for i, w := range nameWords(s) {
+		if i == 0 && !upper {
+			sb.WriteString(w)
+			continue
+		}
+		r := []rune(w)
+		sb.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+	}
+	return sb.String()
+}
+
+// plural returns the English plural of the word, by the regular rules.
+func plural(s string) string {
+	switch {
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "z"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	}
+	return s + "s"
+}
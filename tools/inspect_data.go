@@ -0,0 +1,462 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var InspectDataDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name: "inspect_data",
+		Description: `Summarize a CSV, TSV, JSON, JSON Lines or YAML data file: its number of rows or items, its columns or fields and their types, and a few samples from its start and end.
+
+Use this instead of read_file for data files, which can be too large to read whole.`,
+		Parameters: objectParameters([]string{"path"}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "The relative path of the data file.",
+			},
+			"samples": {
+				Type:        api.PropertyType{"integer"},
+				Description: "Optional number of rows or items to show from the start and from the end, defaults to 3.",
+			},
+		}),
+	},
+	Function: InspectData,
+}
+
+type InspectDataInput struct {
+	Path    string `json:"path"`
+	Samples int    `json:"samples,omitempty"`
+}
+
+const (
+	defaultDataSamples = 3
+	maxDataSamples     = 50
+	// samples longer than this are cut
+	maxSampleLen = 300
+	// nesting shown when describing the structure of documents
+	maxDataDepth = 4
+)
+
+func InspectData(ctx context.Context, input json.RawMessage) (string, error) {
+	inspectDataInput := InspectDataInput{}
+	err := json.Unmarshal(input, &inspectDataInput)
+	if err != nil {
+		return "", err
+	}
+	if inspectDataInput.Path == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	samples := defaultDataSamples
+	if inspectDataInput.Samples > 0 {
+		samples = min(inspectDataInput.Samples, maxDataSamples)
+	}
+	root, path, err := workspace.FromContext(ctx).Resolve(inspectDataInput.Path)
+	if err != nil {
+		return "", err
+	}
+	content, err := root.FS.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return inspectCSV(content, ',', samples)
+	case ".tsv", ".tab":
+		return inspectCSV(content, '\t', samples)
+	case ".jsonl", ".ndjson":
+		return inspectJSONLines(content, samples)
+	case ".json", ".geojson":
+		var doc any
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return "", fmt.Errorf("invalid JSON: %v", err)
+		}
+		return inspectDocument(doc, samples), nil
+	case ".yaml", ".yml":
+		var out strings.Builder
+		dec := yaml.NewDecoder(bytes.NewReader(content))
+		for i := 0; ; i++ {
+			var doc any
+			err := dec.Decode(&doc)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", fmt.Errorf("invalid YAML: %v", err)
+			}
+			if i > 0 {
+				fmt.Fprintf(&out, "--- document %d\n", i+1)
+			}
+			out.WriteString(inspectDocument(doc, samples))
+		}
+		return out.String(), nil
+	}
+	return "", fmt.Errorf("unsupported data file %s, use csv, tsv, json, jsonl or yaml", filepath.Base(path))
+}
+
+func inspectCSV(content []byte, comma rune, samples int) (string, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("invalid CSV: %v", err)
+	}
+	if len(records) == 0 {
+		return "empty file", nil
+	}
+	header, rows := records[0], records[1:]
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%d rows, %d columns (the first line is taken as the header)\n", len(rows), len(header))
+	ragged := 0
+	for _, row := range rows {
+		if len(row) != len(header) {
+			ragged++
+		}
+	}
+	if ragged > 0 {
+		fmt.Fprintf(&out, "%d rows have a different number of fields than the header\n", ragged)
+	}
+	out.WriteString("columns:\n")
+	for i, name := range header {
+		var values []string
+		for _, row := range rows {
+			if i < len(row) {
+				values = append(values, row[i])
+			}
+		}
+		fmt.Fprintf(&out, "  %s: %s\n", name, describeColumn(values))
+	}
+	writeSamples(&out, len(rows), samples, func(i int) string {
+		return strings.Join(rows[i], string(comma))
+	})
+	return out.String(), nil
+}
+
+// describeColumn infers the type of a column's values and summarizes them.
+func describeColumn(values []string) string {
+	kinds := map[string]int{}
+	distinct := map[string]bool{}
+	empty := 0
+	var minNum, maxNum float64
+	numbers := 0
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			empty++
+			continue
+		}
+		if len(distinct) <= 1000 {
+			distinct[v] = true
+		}
+		kind := valueKind(v)
+		kinds[kind]++
+		if kind == "integer" || kind == "number" {
+			n, _ := strconv.ParseFloat(v, 64)
+			if numbers == 0 || n < minNum {
+				minNum = n
+			}
+			if numbers == 0 || n > maxNum {
+				maxNum = n
+			}
+			numbers++
+		}
+	}
+
+	kind := "empty"
+	switch {
+	case len(kinds) == 1:
+		for k := range kinds {
+			kind = k
+		}
+	case len(kinds) == 2 && kinds["integer"] > 0 && kinds["number"] > 0:
+		kind = "number"
+	case len(kinds) > 1:
+		kind = "mixed (" + countsString(kinds) + ")"
+	}
+	var parts []string
+	parts = append(parts, kind)
+	if (kind == "integer" || kind == "number") && numbers > 0 {
+		parts = append(parts, fmt.Sprintf("min %g, max %g", minNum, maxNum))
+	}
+	if len(distinct) > 1000 {
+		parts = append(parts, "over 1000 distinct values")
+	} else {
+		parts = append(parts, fmt.Sprintf("%d distinct", len(distinct)))
+	}
+	if empty > 0 {
+		parts = append(parts, fmt.Sprintf("%d empty", empty))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func valueKind(v string) string {
+	if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return "integer"
+	}
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return "number"
+	}
+	switch strings.ToLower(v) {
+	case "true", "false":
+		return "boolean"
+	}
+	for _, layout := range []string{time.RFC3339, time.DateTime, time.DateOnly} {
+		if _, err := time.Parse(layout, v); err == nil {
+			return "date"
+		}
+	}
+	return "string"
+}
+
+func inspectJSONLines(content []byte, samples int) (string, error) {
+	var items []any
+	var lines []string
+	s := bufio.NewScanner(bytes.NewReader(content))
+	s.Buffer(nil, 64*1024*1024)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		var item any
+		if err := json.Unmarshal([]byte(line), &item); err != nil {
+			return "", fmt.Errorf("invalid JSON on line %d: %v", n, err)
+		}
+		items = append(items, item)
+		lines = append(lines, line)
+	}
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, "%d items\n", len(items))
+	out.WriteString(describeItems(items, 1))
+	writeSamples(&out, len(lines), samples, func(i int) string { return lines[i] })
+	return out.String(), nil
+}
+
+// inspectDocument describes a decoded JSON or YAML document, with samples
+// when it is a list.
+func inspectDocument(doc any, samples int) string {
+	var out strings.Builder
+	switch d := doc.(type) {
+	case []any:
+		fmt.Fprintf(&out, "array of %d items\n", len(d))
+		out.WriteString(describeItems(d, 1))
+		writeSamples(&out, len(d), samples, func(i int) string {
+			buf, _ := json.Marshal(normalizeYAML(d[i]))
+			return string(buf)
+		})
+	default:
+		out.WriteString(describeValue(doc, 0) + "\n")
+	}
+	return out.String()
+}
+
+// describeItems summarizes the fields of a list of objects, or the types of
+// a list of other values.
+func describeItems(items []any, depth int) string {
+	indent := strings.Repeat("  ", depth)
+	fields := map[string]map[string]int{}
+	var order []string
+	others := map[string]int{}
+	objects := 0
+	for _, item := range items {
+		obj, ok := asObject(item)
+		if !ok {
+			others[typeName(item)]++
+			continue
+		}
+		objects++
+		for k, v := range obj {
+			if fields[k] == nil {
+				fields[k] = map[string]int{}
+				order = append(order, k)
+			}
+			fields[k][typeName(v)]++
+		}
+	}
+	var out strings.Builder
+	if objects > 0 {
+		fmt.Fprintf(&out, "%sfields of the %d objects:\n", indent, objects)
+		for _, k := range order {
+			missing := ""
+			n := 0
+			for _, c := range fields[k] {
+				n += c
+			}
+			if n < objects {
+				missing = fmt.Sprintf(", missing in %d", objects-n)
+			}
+			fmt.Fprintf(&out, "%s  %s: %s%s\n", indent, k, countsString(fields[k]), missing)
+		}
+	}
+	if len(others) > 0 {
+		fmt.Fprintf(&out, "%sother items: %s\n", indent, countsString(others))
+	}
+	return out.String()
+}
+
+// describeValue outlines the structure of a value, the types of its fields
+// and the sizes of its lists, down to maxDataDepth.
+func describeValue(v any, depth int) string {
+	indent := strings.Repeat("  ", depth)
+	if obj, ok := asObject(v); ok {
+		if depth >= maxDataDepth {
+			return fmt.Sprintf("object with %d keys", len(obj))
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var out strings.Builder
+		fmt.Fprintf(&out, "object with %d keys:", len(obj))
+		for _, k := range keys {
+			fmt.Fprintf(&out, "\n%s  %s: %s", indent, k, describeValue(obj[k], depth+1))
+		}
+		return out.String()
+	}
+	if list, ok := v.([]any); ok {
+		if depth >= maxDataDepth || len(list) == 0 {
+			return fmt.Sprintf("array of %d items", len(list))
+		}
+		return fmt.Sprintf("array of %d items:\n%s", len(list), strings.TrimRight(describeItems(list, depth+1), "\n"))
+	}
+	s := fmt.Sprint(v)
+	if len(s) > 60 {
+		s = s[:60] + "..."
+	}
+	return fmt.Sprintf("%s (%s)", typeName(v), s)
+}
+
+func asObject(v any) (map[string]any, bool) {
+	switch o := v.(type) {
+	case map[string]any:
+		return o, true
+	case map[any]any:
+		// YAML allows keys other than strings
+		obj := make(map[string]any, len(o))
+		for k, v := range o {
+			obj[fmt.Sprint(k)] = v
+		}
+		return obj, true
+	}
+	return nil, false
+}
+
+func normalizeYAML(v any) any {
+	if obj, ok := asObject(v); ok {
+		for k, c := range obj {
+			obj[k] = normalizeYAML(c)
+		}
+		return obj
+	}
+	if list, ok := v.([]any); ok {
+		for i, c := range list {
+			list[i] = normalizeYAML(c)
+		}
+	}
+	return v
+}
+
+func typeName(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		if t == float64(int64(t)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case time.Time:
+		return "date"
+	case []any:
+		return "array"
+	}
+	if _, ok := asObject(v); ok {
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// countsString formats type counts, most common first, e.g. "string 10,
+// null 2", or just the type when there is one.
+func countsString(counts map[string]int) string {
+	if len(counts) == 1 {
+		for k := range counts {
+			return k
+		}
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s %d", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeSamples writes the first and last n of count rows, all of them when
+// they overlap.
+func writeSamples(out *strings.Builder, count, n int, row func(i int) string) {
+	if count == 0 {
+		return
+	}
+	sample := func(i int) string {
+		s := row(i)
+		if len(s) > maxSampleLen {
+			s = s[:maxSampleLen] + "..."
+		}
+		return s
+	}
+	if count <= 2*n {
+		out.WriteString("rows:\n")
+		for i := range count {
+			fmt.Fprintf(out, "  %d: %s\n", i+1, sample(i))
+		}
+		return
+	}
+	out.WriteString("first rows:\n")
+	for i := range n {
+		fmt.Fprintf(out, "  %d: %s\n", i+1, sample(i))
+	}
+	out.WriteString("last rows:\n")
+	for i := count - n; i < count; i++ {
+		fmt.Fprintf(out, "  %d: %s\n", i+1, sample(i))
+	}
+}
@@ -0,0 +1,239 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+const (
+	defaultKubeLogLines = 200
+	maxKubeLogLines     = 2000
+	maxKubeOutput       = 20000
+)
+
+// kubeName matches resource kinds and names, which must not be flags
+var kubeName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/-]*$`)
+
+// Kubernetes is a cluster the read-only kubectl tools are scoped to, a
+// kubeconfig context and a namespace in it.
+type Kubernetes struct {
+	Context   string
+	Namespace string
+}
+
+// NewKubernetes returns the kube_get, kube_describe and kube_logs tools,
+// running kubectl in the primary root with the cluster's context and
+// namespace.
+func NewKubernetes(k Kubernetes) []Tool {
+	scope := fmt.Sprintf("the %s namespace of the %s cluster", k.Namespace, k.Context)
+	if k.Namespace == "" {
+		scope = fmt.Sprintf("the default namespace of the %s cluster", k.Context)
+	}
+	return []Tool{
+		{
+			Definition: api.ToolFunction{
+				Name:        "kube_get",
+				Description: fmt.Sprintf("List Kubernetes resources in %s, like kubectl get -o wide, e.g. the pods of a deployment and their status.", scope),
+				Parameters: objectParameters([]string{"kind"}, map[string]Property{
+					"kind": {
+						Type:        api.PropertyType{"string"},
+						Description: "The kind of resources, e.g. pods, deployments, services, events.",
+					},
+					"name": {
+						Type:        api.PropertyType{"string"},
+						Description: "Optional name of a single resource.",
+					},
+					"selector": {
+						Type:        api.PropertyType{"string"},
+						Description: "Optional label selector, e.g. app=api.",
+					},
+				}),
+			},
+			Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+				return kubeGet(ctx, k, input)
+			},
+		},
+		{
+			Definition: api.ToolFunction{
+				Name:        "kube_describe",
+				Description: fmt.Sprintf("Describe a Kubernetes resource in %s, like kubectl describe, with its conditions and recent events. Use this to find why a pod is not ready or keeps restarting.", scope),
+				Parameters: objectParameters([]string{"kind", "name"}, map[string]Property{
+					"kind": {
+						Type:        api.PropertyType{"string"},
+						Description: "The kind of the resource, e.g. pod.",
+					},
+					"name": {
+						Type:        api.PropertyType{"string"},
+						Description: "The name of the resource.",
+					},
+				}),
+			},
+			Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+				return kubeDescribe(ctx, k, input)
+			},
+		},
+		{
+			Definition: api.ToolFunction{
+				Name:        "kube_logs",
+				Description: fmt.Sprintf("Return the last lines of the logs of a pod in %s, or of the previous instance of a container that crashed.", scope),
+				Parameters: objectParameters([]string{"pod"}, map[string]Property{
+					"pod": {
+						Type:        api.PropertyType{"string"},
+						Description: "The name of the pod, or kind/name, e.g. deployment/api.",
+					},
+					"container": {
+						Type:        api.PropertyType{"string"},
+						Description: "Optional container, needed for pods with several.",
+					},
+					"lines": {
+						Type:        api.PropertyType{"integer"},
+						Description: fmt.Sprintf("Optional number of lines, defaults to %d.", defaultKubeLogLines),
+					},
+					"previous": {
+						Type:        api.PropertyType{"boolean"},
+						Description: "Return the logs of the previous instance of the container.",
+					},
+				}),
+			},
+			Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+				return kubeLogs(ctx, k, input)
+			},
+		},
+	}
+}
+
+// kubectl runs a read-only kubectl command scoped to the cluster.
+func kubectl(ctx context.Context, k Kubernetes, args ...string) (string, error) {
+	scope := []string{"--context", k.Context}
+	if k.Namespace != "" {
+		scope = append(scope, "--namespace", k.Namespace)
+	}
+	script := "kubectl"
+	for _, arg := range append(scope, args...) {
+		script += " " + workspace.ShellQuote(arg)
+	}
+	root := workspace.FromContext(ctx).Primary()
+	out, err := root.FS.Command(ctx, root.Path, script).CombinedOutput()
+	result := strings.TrimRight(string(out), "\n")
+	if len(result) > maxKubeOutput {
+		result = result[len(result)-maxKubeOutput:]
+		result = fmt.Sprintf("[%d bytes of output truncated]\n", len(out)-maxKubeOutput) + result[strings.Index(result, "\n")+1:]
+	}
+	if err != nil {
+		return "", fmt.Errorf("kubectl failed: %v\n%s", err, result)
+	}
+	if result == "" {
+		return "no output", nil
+	}
+	return result, nil
+}
+
+// checkKubeKind refuses kinds the tools must not read.
+func checkKubeKind(kind string) error {
+	if !kubeName.MatchString(kind) {
+		return fmt.Errorf("invalid kind %q", kind)
+	}
+	switch strings.ToLower(strings.SplitN(kind, ".", 2)[0]) {
+	case "secret", "secrets":
+		return fmt.Errorf("reading secrets is not allowed")
+	}
+	return nil
+}
+
+type KubeGetInput struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name,omitempty"`
+	Selector string `json:"selector,omitempty"`
+}
+
+func kubeGet(ctx context.Context, k Kubernetes, input json.RawMessage) (string, error) {
+	kubeGetInput := KubeGetInput{}
+	err := json.Unmarshal(input, &kubeGetInput)
+	if err != nil {
+		return "", err
+	}
+	if kubeGetInput.Kind == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	if err := checkKubeKind(kubeGetInput.Kind); err != nil {
+		return "", err
+	}
+	args := []string{"get", kubeGetInput.Kind}
+	if kubeGetInput.Name != "" {
+		if !kubeName.MatchString(kubeGetInput.Name) {
+			return "", fmt.Errorf("invalid name %q", kubeGetInput.Name)
+		}
+		args = append(args, kubeGetInput.Name)
+	}
+	if kubeGetInput.Selector != "" {
+		args = append(args, "--selector", kubeGetInput.Selector)
+	}
+	return kubectl(ctx, k, append(args, "-o", "wide")...)
+}
+
+type KubeDescribeInput struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+func kubeDescribe(ctx context.Context, k Kubernetes, input json.RawMessage) (string, error) {
+	kubeDescribeInput := KubeDescribeInput{}
+	err := json.Unmarshal(input, &kubeDescribeInput)
+	if err != nil {
+		return "", err
+	}
+	if kubeDescribeInput.Kind == "" || kubeDescribeInput.Name == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	if err := checkKubeKind(kubeDescribeInput.Kind); err != nil {
+		return "", err
+	}
+	if !kubeName.MatchString(kubeDescribeInput.Name) {
+		return "", fmt.Errorf("invalid name %q", kubeDescribeInput.Name)
+	}
+	return kubectl(ctx, k, "describe", kubeDescribeInput.Kind, kubeDescribeInput.Name)
+}
+
+type KubeLogsInput struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container,omitempty"`
+	Lines     int    `json:"lines,omitempty"`
+	Previous  bool   `json:"previous,omitempty"`
+}
+
+func kubeLogs(ctx context.Context, k Kubernetes, input json.RawMessage) (string, error) {
+	kubeLogsInput := KubeLogsInput{}
+	err := json.Unmarshal(input, &kubeLogsInput)
+	if err != nil {
+		return "", err
+	}
+	if kubeLogsInput.Pod == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	if !kubeName.MatchString(kubeLogsInput.Pod) {
+		return "", fmt.Errorf("invalid pod %q", kubeLogsInput.Pod)
+	}
+	lines := kubeLogsInput.Lines
+	if lines <= 0 {
+		lines = defaultKubeLogLines
+	}
+	lines = min(lines, maxKubeLogLines)
+	args := []string{"logs", kubeLogsInput.Pod, fmt.Sprintf("--tail=%d", lines)}
+	if kubeLogsInput.Container != "" {
+		if !kubeName.MatchString(kubeLogsInput.Container) {
+			return "", fmt.Errorf("invalid container %q", kubeLogsInput.Container)
+		}
+		args = append(args, "--container", kubeLogsInput.Container)
+	}
+	if kubeLogsInput.Previous {
+		args = append(args, "--previous")
+	}
+	return kubectl(ctx, k, args...)
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var ListFilesDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "list_files",
+		Description: "List files and directories at a given path, directories end in /. If no path is provided, lists files in the current directory. The listing goes depth levels deep, list a directory to see what is deeper. Hidden and vendored directories, such as .git and node_modules, are only expanded when listed themselves.",
+		Parameters: objectParameters(nil, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional relative path to list files from. Defaults to current directory if not provided.",
+			},
+			"depth": {
+				Type:        api.PropertyType{"integer"},
+				Description: fmt.Sprintf("Optional number of directory levels to list, by default %d.", defaultListDepth),
+			},
+			"cursor": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional cursor returned by a listing that was cut short, to list the entries after it.",
+			},
+		}),
+	},
+	Function: ListFiles,
+}
+
+const (
+	defaultListDepth = 2
+	// maxListEntries is how many entries a listing returns at most, the
+	// rest are listed with the cursor
+	maxListEntries = 500
+)
+
+type ListFilesInput struct {
+	Path   string `json:"path,omitempty" jsonschema_description:"Optional relative path to list files from. Defaults to current directory if not provided."`
+	Depth  int    `json:"depth,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+func ListFiles(ctx context.Context, input json.RawMessage) (string, error) {
+	listFilesInput := ListFilesInput{}
+	err := json.Unmarshal(input, &listFilesInput)
+	if err != nil {
+		panic(err)
+	}
+
+	depth := listFilesInput.Depth
+	if depth <= 0 {
+		depth = defaultListDepth
+	}
+
+	ws := workspace.FromContext(ctx)
+
+	var files []string
+	if listFilesInput.Path == "" && len(ws.Roots) > 1 {
+		for _, root := range ws.Roots {
+			rootFiles, err := root.FS.List(root.Path, depth)
+			if err != nil {
+				return "", err
+			}
+			for _, f := range rootFiles {
+				files = append(files, ws.Display(root, filepath.Join(root.Path, f))+dirSuffix(f))
+			}
+		}
+		slices.Sort(files)
+	} else {
+		root, dir, err := ws.Resolve(listFilesInput.Path)
+		if err != nil {
+			return "", err
+		}
+		files, err = root.FS.List(dir, depth)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// the entries are sorted, the cursor is the last one returned
+	if listFilesInput.Cursor != "" {
+		i, _ := slices.BinarySearch(files, listFilesInput.Cursor)
+		if i < len(files) && files[i] == listFilesInput.Cursor {
+			i++
+		}
+		files = files[i:]
+	}
+	more := len(files) - maxListEntries
+	if more > 0 {
+		files = files[:maxListEntries]
+	}
+
+	result, err := json.Marshal(files)
+	if err != nil {
+		return "", err
+	}
+	if more > 0 {
+		return fmt.Sprintf("%s\n%d more entries, call list_files with cursor %q to list them, or list a directory", result, more, files[len(files)-1]), nil
+	}
+
+	return string(result), nil
+}
+
+// dirSuffix keeps the trailing slash that marks directories, which
+// filepath.Join drops.
+func dirSuffix(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return "/"
+	}
+	return ""
+}
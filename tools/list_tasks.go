@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var ListTasksDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "list_tasks",
+		Description: "List the project's own commands, from its Makefile, Taskfile, justfile and package.json scripts, with how to run them. Prefer these entry points to building or testing by hand.",
+		Parameters: objectParameters(nil, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional relative path of the directory to look in, defaults to the current directory.",
+			},
+		}),
+	},
+	Function: ListTasks,
+}
+
+type ListTasksInput struct {
+	Path string `json:"path,omitempty"`
+}
+
+type projectTask struct {
+	run, description string
+}
+
+// taskSources are the files listing tasks, in the order they are shown.
+var taskSources = []struct {
+	files []string
+	parse func(content []byte) ([]projectTask, error)
+}{
+	{[]string{"Makefile", "makefile", "GNUmakefile"}, parseMakefile},
+	{[]string{"Taskfile.yml", "Taskfile.yaml", "taskfile.yml", "taskfile.yaml"}, parseTaskfile},
+	{[]string{"justfile", "Justfile", ".justfile"}, parseJustfile},
+	{[]string{"package.json"}, parsePackageScripts},
+}
+
+func ListTasks(ctx context.Context, input json.RawMessage) (string, error) {
+	listTasksInput := ListTasksInput{}
+	err := json.Unmarshal(input, &listTasksInput)
+	if err != nil {
+		return "", err
+	}
+	root, dir, err := workspace.FromContext(ctx).Resolve(listTasksInput.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for _, source := range taskSources {
+		for _, name := range source.files {
+			content, err := root.FS.ReadFile(filepath.Join(dir, name))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			tasks, err := source.parse(content)
+			if err != nil {
+				fmt.Fprintf(&out, "%s: invalid: %v\n", name, err)
+				break
+			}
+			fmt.Fprintf(&out, "%s:\n", name)
+			for _, t := range tasks {
+				if t.description != "" {
+					fmt.Fprintf(&out, "  %s  # %s\n", t.run, t.description)
+				} else {
+					fmt.Fprintf(&out, "  %s\n", t.run)
+				}
+			}
+			break
+		}
+	}
+	if out.Len() == 0 {
+		return "no Makefile, Taskfile, justfile or package.json found", nil
+	}
+	return out.String(), nil
+}
+
+var (
+	// target: prerequisites, but not variable assignments like x := y
+	makeTarget = regexp.MustCompile(`^([A-Za-z0-9_][A-Za-z0-9_./-]*(?:\s+[A-Za-z0-9_][A-Za-z0-9_./-]*)*)\s*:([^=]|$)`)
+	// recipe [params]: [deps], but not assignments like x := y
+	justRecipe = regexp.MustCompile(`^@?([A-Za-z_][A-Za-z0-9_-]*)((?:\s+[^:]+?)?)\s*:([^=]|$)`)
+)
+
+// parseMakefile returns the explicit targets, described by a ## comment on
+// the line or a # comment above it.
+func parseMakefile(content []byte) ([]projectTask, error) {
+	var tasks []projectTask
+	seen := map[string]bool{}
+	comment := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "#") {
+			comment = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		}
+		m := makeTarget.FindStringSubmatch(line)
+		description := comment
+		comment = ""
+		if m == nil {
+			continue
+		}
+		if _, doc, ok := strings.Cut(line, "##"); ok {
+			description = strings.TrimSpace(doc)
+		}
+		for _, target := range strings.Fields(m[1]) {
+			if seen[target] {
+				continue
+			}
+			seen[target] = true
+			tasks = append(tasks, projectTask{run: "make " + target, description: description})
+		}
+	}
+	return tasks, nil
+}
+
+func parseTaskfile(content []byte) ([]projectTask, error) {
+	var taskfile struct {
+		Tasks map[string]struct {
+			Desc     string `yaml:"desc"`
+			Summary  string `yaml:"summary"`
+			Internal bool   `yaml:"internal"`
+		} `yaml:"tasks"`
+	}
+	if err := yaml.Unmarshal(content, &taskfile); err != nil {
+		return nil, err
+	}
+	var tasks []projectTask
+	for name, t := range taskfile.Tasks {
+		if t.Internal {
+			continue
+		}
+		description := t.Desc
+		if description == "" {
+			description, _, _ = strings.Cut(strings.TrimSpace(t.Summary), "\n")
+		}
+		tasks = append(tasks, projectTask{run: "task " + name, description: description})
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].run < tasks[j].run })
+	return tasks, nil
+}
+
+// parseJustfile returns the public recipes, described by the comment above
+// them as just does.
+func parseJustfile(content []byte) ([]projectTask, error) {
+	var tasks []projectTask
+	comment, private := "", false
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "#!") {
+			comment = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		}
+		// attributes like [private] apply to the recipe below them
+		if strings.HasPrefix(line, "[") {
+			private = private || strings.Contains(line, "private")
+			continue
+		}
+		m := justRecipe.FindStringSubmatch(line)
+		description, hidden := comment, private
+		comment, private = "", false
+		// recipes starting with _ are private too
+		if m == nil || hidden || strings.HasPrefix(m[1], "_") {
+			continue
+		}
+		run := "just " + m[1]
+		if params := strings.TrimSpace(m[2]); params != "" {
+			run += " " + params
+		}
+		tasks = append(tasks, projectTask{run: run, description: description})
+	}
+	return tasks, nil
+}
+
+func parsePackageScripts(content []byte) ([]projectTask, error) {
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil, err
+	}
+	var tasks []projectTask
+	for name, script := range pkg.Scripts {
+		tasks = append(tasks, projectTask{run: "npm run " + name, description: script})
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].run < tasks[j].run })
+	return tasks, nil
+}
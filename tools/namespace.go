@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CustomNamespace is the namespace of the tools declared in the config.
+const CustomNamespace = "custom"
+
+// BareName returns the tool's name without its namespace.
+func (t Tool) BareName() string {
+	if t.Namespace == "" {
+		return t.Definition.Name
+	}
+	return strings.TrimPrefix(t.Definition.Name, t.Namespace+".")
+}
+
+// Qualify resolves name collisions between tools from different sources.
+// Built-in tools keep their names, then the namespaces listed in precedence,
+// then the others in the order of their first tool. A tool losing its name
+// to another is offered by its qualified name, namespace.name. Tools that
+// still collide are an error.
+func Qualify(toolset []Tool, precedence []string) ([]Tool, error) {
+	rank := func(t Tool) int {
+		if t.Namespace == "" {
+			return -1
+		}
+		if i := slices.Index(precedence, t.Namespace); i >= 0 {
+			return i
+		}
+		return len(precedence) + slices.IndexFunc(toolset, func(o Tool) bool { return o.Namespace == t.Namespace })
+	}
+
+	// tools qualified before are qualified again, precedence may change
+	rv := slices.Clone(toolset)
+	for i, t := range rv {
+		if t.Namespace == "" {
+			continue
+		}
+		if strings.Contains(t.Namespace, ".") {
+			return nil, fmt.Errorf("tool %s: invalid namespace %q", t.Definition.Name, t.Namespace)
+		}
+		rv[i].Definition.Name = t.BareName()
+		if slices.ContainsFunc(toolset, func(o Tool) bool {
+			return o.BareName() == t.BareName() && rank(o) < rank(t)
+		}) {
+			rv[i].Definition.Name = t.Namespace + "." + t.BareName()
+		}
+	}
+
+	seen := map[string]Tool{}
+	for _, t := range rv {
+		if o, ok := seen[t.Definition.Name]; ok {
+			return nil, fmt.Errorf("tool %s is defined by both %s and %s", t.Definition.Name, source(o), source(t))
+		}
+		seen[t.Definition.Name] = t
+	}
+	return rv, nil
+}
+
+// Qualified returns the names the tools whose bare name is name are offered
+// by, as it was taken by another tool.
+func Qualified(toolset []Tool, name string) []string {
+	var rv []string
+	for _, t := range toolset {
+		if t.Namespace != "" && t.BareName() == name && t.Definition.Name != name {
+			rv = append(rv, t.Definition.Name)
+		}
+	}
+	return rv
+}
+
+func source(t Tool) string {
+	if t.Namespace == "" {
+		return "dacs"
+	}
+	return t.Namespace
+}
@@ -0,0 +1,327 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var ReadNotebookDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "read_notebook",
+		Description: "Read a Jupyter notebook (.ipynb) as its numbered cells, with their type, source and text outputs. Use this instead of read_file for notebooks.",
+		Parameters: objectParameters([]string{"path"}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "The relative path of the notebook.",
+			},
+		}),
+	},
+	Function: ReadNotebook,
+}
+
+var EditNotebookDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name: "edit_notebook",
+		Description: `Edit a cell of a Jupyter notebook (.ipynb), numbered as read_notebook shows them. Use this instead of edit_file for notebooks.
+
+'replace' sets the source of the cell (clearing the outputs of code cells), 'insert' adds a new cell before it, or at the end when cell is the number of cells, 'delete' removes it.`,
+		Parameters: objectParameters([]string{"path", "cell", "action"}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "The relative path of the notebook.",
+			},
+			"cell": {
+				Type:        api.PropertyType{"integer"},
+				Description: "The number of the cell, starting at 0.",
+			},
+			"action": {
+				Type:        api.PropertyType{"string"},
+				Description: "What to do with the cell.",
+				Enum:        []any{"replace", "insert", "delete"},
+			},
+			"source": {
+				Type:        api.PropertyType{"string"},
+				Description: "The source of the cell, for replace and insert.",
+			},
+			"cell_type": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional type of an inserted cell, defaults to code. With replace, changes the type of the cell.",
+				Enum:        []any{"code", "markdown", "raw"},
+			},
+		}),
+	},
+	Function: EditNotebook,
+	Effect:   EffectWrite,
+}
+
+// maxCellOutput bounds the text output shown for each cell
+const maxCellOutput = 2000
+
+// notebook keeps the fields it does not use as they are, so editing a cell
+// leaves the rest of the file unchanged.
+type notebook struct {
+	fields map[string]json.RawMessage
+	cells  []map[string]json.RawMessage
+}
+
+func readNotebook(ctx context.Context, path string) (*notebook, *workspace.Root, string, error) {
+	root, abs, err := workspace.FromContext(ctx).Resolve(path)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	content, err := root.FS.ReadFile(abs)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	nb := &notebook{}
+	if err := json.Unmarshal(content, &nb.fields); err != nil {
+		return nil, nil, "", fmt.Errorf("invalid notebook: %v", err)
+	}
+	if err := json.Unmarshal(nb.fields["cells"], &nb.cells); err != nil {
+		return nil, nil, "", fmt.Errorf("invalid notebook cells: %v", err)
+	}
+	return nb, root, abs, nil
+}
+
+// marshal formats the notebook like Jupyter does, with sorted keys, an
+// indent of one space and HTML left unescaped.
+func (nb *notebook) marshal() ([]byte, error) {
+	cells, err := encodeJSON(nb.cells, "")
+	if err != nil {
+		return nil, err
+	}
+	nb.fields["cells"] = cells
+	return encodeJSON(nb.fields, " ")
+}
+
+func encodeJSON(v any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func cellString(cell map[string]json.RawMessage, key string) string {
+	var s string
+	_ = json.Unmarshal(cell[key], &s)
+	return s
+}
+
+// multiline decodes nbformat's multiline strings, a string or a list of
+// lines.
+func multiline(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var lines []string
+	_ = json.Unmarshal(raw, &lines)
+	return strings.Join(lines, "")
+}
+
+// splitMultiline encodes a multiline string as Jupyter writes it, a list
+// of lines keeping their line endings.
+func splitMultiline(s string) json.RawMessage {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if lines == nil {
+		lines = []string{}
+	}
+	buf, _ := encodeJSON(lines, "")
+	return buf
+}
+
+type ReadNotebookInput struct {
+	Path string `json:"path"`
+}
+
+func ReadNotebook(ctx context.Context, input json.RawMessage) (string, error) {
+	readNotebookInput := ReadNotebookInput{}
+	err := json.Unmarshal(input, &readNotebookInput)
+	if err != nil {
+		return "", err
+	}
+	if readNotebookInput.Path == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	nb, _, _, err := readNotebook(ctx, readNotebookInput.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%d cells\n", len(nb.cells))
+	for i, cell := range nb.cells {
+		kind := cellString(cell, "cell_type")
+		fmt.Fprintf(&out, "\n--- cell %d [%s]", i, kind)
+		var count *int
+		if json.Unmarshal(cell["execution_count"], &count) == nil && count != nil {
+			fmt.Fprintf(&out, " In [%d]", *count)
+		}
+		out.WriteString("\n" + strings.TrimRight(multiline(cell["source"]), "\n") + "\n")
+		if text := cellOutput(cell["outputs"]); text != "" {
+			fmt.Fprintf(&out, "--- output of cell %d\n%s\n", i, text)
+		}
+	}
+	return out.String(), nil
+}
+
+// cellOutput returns the text of a code cell's outputs, images and other
+// rich data are only named.
+func cellOutput(raw json.RawMessage) string {
+	var outputs []struct {
+		OutputType string                     `json:"output_type"`
+		Text       json.RawMessage            `json:"text"`
+		Data       map[string]json.RawMessage `json:"data"`
+		Ename      string                     `json:"ename"`
+		Evalue     string                     `json:"evalue"`
+	}
+	if json.Unmarshal(raw, &outputs) != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, o := range outputs {
+		switch o.OutputType {
+		case "stream":
+			b.WriteString(multiline(o.Text))
+		case "error":
+			fmt.Fprintf(&b, "%s: %s\n", o.Ename, o.Evalue)
+		case "execute_result", "display_data":
+			if text, ok := o.Data["text/plain"]; ok {
+				b.WriteString(strings.TrimRight(multiline(text), "\n") + "\n")
+			}
+			for mime := range o.Data {
+				if mime != "text/plain" {
+					fmt.Fprintf(&b, "[%s output]\n", mime)
+				}
+			}
+		}
+	}
+	text := strings.TrimRight(b.String(), "\n")
+	if len(text) > maxCellOutput {
+		text = text[:maxCellOutput] + fmt.Sprintf("\n[%d more bytes of output]", len(text)-maxCellOutput)
+	}
+	return text
+}
+
+type EditNotebookInput struct {
+	Path     string  `json:"path"`
+	Cell     int     `json:"cell"`
+	Action   string  `json:"action"`
+	Source   *string `json:"source,omitempty"`
+	CellType string  `json:"cell_type,omitempty"`
+}
+
+func EditNotebook(ctx context.Context, input json.RawMessage) (string, error) {
+	editNotebookInput := EditNotebookInput{}
+	err := json.Unmarshal(input, &editNotebookInput)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case editNotebookInput.Path == "":
+		return "", fmt.Errorf("invalid input parameters")
+	case editNotebookInput.CellType != "" && editNotebookInput.CellType != "code" && editNotebookInput.CellType != "markdown" && editNotebookInput.CellType != "raw":
+		return "", fmt.Errorf("invalid cell_type %q", editNotebookInput.CellType)
+	case (editNotebookInput.Action == "replace" || editNotebookInput.Action == "insert") && editNotebookInput.Source == nil:
+		return "", fmt.Errorf("source is required to %s a cell", editNotebookInput.Action)
+	}
+	nb, root, abs, err := readNotebook(ctx, editNotebookInput.Path)
+	if err != nil {
+		return "", err
+	}
+	last := len(nb.cells) - 1
+	if editNotebookInput.Action == "insert" {
+		last++
+	}
+	if editNotebookInput.Cell < 0 || editNotebookInput.Cell > last {
+		return "", fmt.Errorf("cell %d does not exist, the notebook has %d cells", editNotebookInput.Cell, len(nb.cells))
+	}
+
+	oldSource, newSource := "", ""
+	switch editNotebookInput.Action {
+	case "replace":
+		cell := nb.cells[editNotebookInput.Cell]
+		oldSource, newSource = multiline(cell["source"]), *editNotebookInput.Source
+		if editNotebookInput.CellType != "" && editNotebookInput.CellType != cellString(cell, "cell_type") {
+			nb.cells[editNotebookInput.Cell] = newCell(nb, editNotebookInput.CellType, newSource)
+			// keep the cell's identity and metadata
+			for _, key := range []string{"id", "metadata"} {
+				if v, ok := cell[key]; ok {
+					nb.cells[editNotebookInput.Cell][key] = v
+				}
+			}
+			break
+		}
+		cell["source"] = splitMultiline(newSource)
+		if cellString(cell, "cell_type") == "code" {
+			// the outputs are of the old source
+			cell["outputs"] = json.RawMessage("[]")
+			cell["execution_count"] = json.RawMessage("null")
+		}
+	case "insert":
+		kind := editNotebookInput.CellType
+		if kind == "" {
+			kind = "code"
+		}
+		newSource = *editNotebookInput.Source
+		cell := newCell(nb, kind, newSource)
+		nb.cells = append(nb.cells[:editNotebookInput.Cell], append([]map[string]json.RawMessage{cell}, nb.cells[editNotebookInput.Cell:]...)...)
+	case "delete":
+		oldSource = multiline(nb.cells[editNotebookInput.Cell]["source"])
+		nb.cells = append(nb.cells[:editNotebookInput.Cell], nb.cells[editNotebookInput.Cell+1:]...)
+	default:
+		return "", fmt.Errorf("invalid action %q, use replace, insert or delete", editNotebookInput.Action)
+	}
+
+	if DryRun(ctx) {
+		return fmt.Sprintf("dry run, the notebook was not changed, the edit would make these changes to cell %d:\n%s", editNotebookInput.Cell,
+			UnifiedDiff(fmt.Sprintf("%s cell %d", editNotebookInput.Path, editNotebookInput.Cell), oldSource, newSource)), nil
+	}
+	buf, err := nb.marshal()
+	if err != nil {
+		return "", err
+	}
+	if err := root.FS.WriteFile(abs, buf, 0644); err != nil {
+		return "", err
+	}
+	return "OK", nil
+}
+
+// newCell returns an empty cell of the kind, with an id when the notebook's
+// format (4.5 and later) requires one.
+func newCell(nb *notebook, kind, source string) map[string]json.RawMessage {
+	cell := map[string]json.RawMessage{
+		"cell_type": json.RawMessage(fmt.Sprintf("%q", kind)),
+		"metadata":  json.RawMessage("{}"),
+		"source":    splitMultiline(source),
+	}
+	if kind == "code" {
+		cell["outputs"] = json.RawMessage("[]")
+		cell["execution_count"] = json.RawMessage("null")
+	}
+	var major, minor int
+	_ = json.Unmarshal(nb.fields["nbformat"], &major)
+	_ = json.Unmarshal(nb.fields["nbformat_minor"], &minor)
+	if major > 4 || major == 4 && minor >= 5 {
+		id := make([]byte, 4)
+		_, _ = rand.Read(id)
+		cell["id"] = json.RawMessage(fmt.Sprintf("%q", hex.EncodeToString(id)))
+	}
+	return cell
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/session"
+)
+
+var WriteNoteDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name: "write_note",
+		Description: `Save a note to your scratchpad, replacing any note with the same title, or delete it by saving empty content.
+
+Use this to stash long intermediate results (findings, lists of files, command output you will need later) outside of the conversation. Only the titles of notes are shown each turn, read them back with read_notes.`,
+		Parameters: objectParameters([]string{"title", "content"}, map[string]Property{
+			"title": {
+				Type:        api.PropertyType{"string"},
+				Description: "A short, descriptive title for the note.",
+			},
+			"content": {
+				Type:        api.PropertyType{"string"},
+				Description: "The content of the note, empty to delete it.",
+			},
+		}),
+	},
+	Function: WriteNote,
+}
+
+var ReadNotesDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "read_notes",
+		Description: "Read notes from your scratchpad, either the note with the given title or all notes.",
+		Parameters: objectParameters(nil, map[string]Property{
+			"title": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional title of the note to read, all notes are returned if not provided.",
+			},
+		}),
+	},
+	Function: ReadNotes,
+}
+
+type WriteNoteInput struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+func WriteNote(ctx context.Context, input json.RawMessage) (string, error) {
+	writeNoteInput := WriteNoteInput{}
+	err := json.Unmarshal(input, &writeNoteInput)
+	if err != nil {
+		return "", err
+	}
+	if writeNoteInput.Title == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	s, ok := session.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("no session to keep notes in")
+	}
+
+	if writeNoteInput.Content == "" {
+		if !s.DeleteNote(writeNoteInput.Title) {
+			return "", fmt.Errorf("note %q not found", writeNoteInput.Title)
+		}
+		return fmt.Sprintf("Deleted note %q", writeNoteInput.Title), nil
+	}
+	s.WriteNote(writeNoteInput.Title, writeNoteInput.Content)
+	return fmt.Sprintf("Saved note %q", writeNoteInput.Title), nil
+}
+
+type ReadNotesInput struct {
+	Title string `json:"title,omitempty"`
+}
+
+func ReadNotes(ctx context.Context, input json.RawMessage) (string, error) {
+	readNotesInput := ReadNotesInput{}
+	err := json.Unmarshal(input, &readNotesInput)
+	if err != nil {
+		return "", err
+	}
+	s, ok := session.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("no session to keep notes in")
+	}
+
+	var sb strings.Builder
+	for _, n := range s.Notes {
+		if readNotesInput.Title == "" || n.Title == readNotesInput.Title {
+			fmt.Fprintf(&sb, "# %s\n%s\n\n", n.Title, n.Content)
+		}
+	}
+	if sb.Len() == 0 {
+		if readNotesInput.Title != "" {
+			return "", fmt.Errorf("note %q not found", readNotesInput.Title)
+		}
+		return "no notes", nil
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
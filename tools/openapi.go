@@ -0,0 +1,442 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var OpenAPIDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name: "openapi",
+		Description: `Query an OpenAPI (or Swagger 2) spec of the project instead of reading the whole file. Use this to keep clients and handlers consistent with the actual spec.
+
+Without endpoint or schema it lists the endpoints and schemas, with endpoint the parameters, request body and responses of an operation, with schema the properties of a schema.`,
+		Parameters: objectParameters([]string{}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional relative path of the spec, by default the openapi.* or swagger.* file of the project.",
+			},
+			"endpoint": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional operation to describe, a method and path, e.g. GET /users/{id}, or an operationId.",
+			},
+			"schema": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional name of a schema to describe, e.g. User.",
+			},
+			"filter": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional text the listed endpoints' path, tags or summary must contain.",
+			},
+		}),
+	},
+	Function: OpenAPI,
+}
+
+type OpenAPIInput struct {
+	Path     string `json:"path,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Schema   string `json:"schema,omitempty"`
+	Filter   string `json:"filter,omitempty"`
+}
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// openAPISpec is a parsed spec, as generic maps so that OpenAPI 3 and
+// Swagger 2 are read alike.
+type openAPISpec map[string]any
+
+func OpenAPI(ctx context.Context, input json.RawMessage) (string, error) {
+	openAPIInput := OpenAPIInput{}
+	err := json.Unmarshal(input, &openAPIInput)
+	if err != nil {
+		return "", err
+	}
+	ws := workspace.FromContext(ctx)
+	specPath := openAPIInput.Path
+	if specPath == "" {
+		specPath, err = findOpenAPISpec(ws)
+		if err != nil {
+			return "", err
+		}
+	}
+	root, abs, err := ws.Resolve(specPath)
+	if err != nil {
+		return "", err
+	}
+	content, err := root.FS.ReadFile(abs)
+	if err != nil {
+		return "", err
+	}
+	// YAML is a superset of JSON, so this reads both
+	var doc map[string]any
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return "", fmt.Errorf("invalid spec %s: %v", specPath, err)
+	}
+	spec := openAPISpec(doc)
+	if spec["openapi"] == nil && spec["swagger"] == nil {
+		return "", fmt.Errorf("%s is not an OpenAPI or Swagger spec", specPath)
+	}
+
+	switch {
+	case openAPIInput.Endpoint != "":
+		return spec.describeOperation(openAPIInput.Endpoint)
+	case openAPIInput.Schema != "":
+		return spec.describeSchema(openAPIInput.Schema)
+	}
+	return spec.list(specPath, openAPIInput.Filter), nil
+}
+
+// findOpenAPISpec returns the one spec in the primary root.
+func findOpenAPISpec(ws *workspace.Workspace) (string, error) {
+	root := ws.Primary()
+	files, err := root.FS.Walk(root.Path)
+	if err != nil {
+		return "", err
+	}
+	var specs []string
+	for _, f := range files {
+		if strings.HasPrefix(f, "node_modules/") || strings.HasPrefix(f, ".git/") || strings.HasPrefix(f, "vendor/") {
+			continue
+		}
+		name := strings.ToLower(path.Base(f))
+		ext := path.Ext(name)
+		if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			continue
+		}
+		if strings.HasPrefix(name, "openapi") || strings.HasPrefix(name, "swagger") {
+			specs = append(specs, ws.Display(root, filepath.Join(root.Path, f)))
+		}
+	}
+	switch len(specs) {
+	case 0:
+		return "", fmt.Errorf("no openapi.* or swagger.* spec found, give its path")
+	case 1:
+		return specs[0], nil
+	}
+	return "", fmt.Errorf("several specs found, give the path of one of: %s", strings.Join(specs, ", "))
+}
+
+func asMap(v any) map[string]any {
+	switch m := v.(type) {
+	case map[string]any:
+		return m
+	case map[any]any:
+		// YAML mappings with keys that are not all strings, e.g. the
+		// response codes 200 and default
+		rv := make(map[string]any, len(m))
+		for k, v := range m {
+			rv[fmt.Sprint(k)] = v
+		}
+		return rv
+	}
+	return nil
+}
+
+func asString(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// schemas returns the named schemas, components/schemas in OpenAPI 3 and
+// definitions in Swagger 2.
+func (s openAPISpec) schemas() map[string]any {
+	if schemas := asMap(asMap(s["components"])["schemas"]); schemas != nil {
+		return schemas
+	}
+	return asMap(s["definitions"])
+}
+
+// resolve follows a local $ref, such as #/components/parameters/Limit.
+func (s openAPISpec) resolve(v any) map[string]any {
+	m := asMap(v)
+	for i := 0; i < 10; i++ {
+		ref := asString(m["$ref"])
+		if !strings.HasPrefix(ref, "#/") {
+			return m
+		}
+		var cur any = map[string]any(s)
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			cur = asMap(cur)[part]
+		}
+		m = asMap(cur)
+	}
+	return m
+}
+
+func (s openAPISpec) list(specPath, filter string) string {
+	var b strings.Builder
+	info := asMap(s["info"])
+	fmt.Fprintf(&b, "%s: %s", specPath, asString(info["title"]))
+	if version := info["version"]; version != nil {
+		fmt.Fprintf(&b, " %v", version)
+	}
+	b.WriteString("\n")
+	filter = strings.ToLower(filter)
+	paths := asMap(s["paths"])
+	b.WriteString("\nendpoints:\n")
+	for _, p := range sortedKeys(paths) {
+		item := asMap(paths[p])
+		for _, method := range openAPIMethods {
+			op := asMap(item[method])
+			if op == nil {
+				continue
+			}
+			var tags []string
+			for _, t := range asSlice(op["tags"]) {
+				tags = append(tags, asString(t))
+			}
+			line := fmt.Sprintf("%s %s", strings.ToUpper(method), p)
+			if summary := asString(op["summary"]); summary != "" {
+				line += " - " + summary
+			}
+			if len(tags) > 0 {
+				line += " [" + strings.Join(tags, ", ") + "]"
+			}
+			if filter != "" && !strings.Contains(strings.ToLower(line), filter) {
+				continue
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	if schemas := s.schemas(); len(schemas) > 0 && filter == "" {
+		fmt.Fprintf(&b, "\nschemas: %s\n", strings.Join(sortedKeys(schemas), ", "))
+	}
+	return b.String()
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+func (s openAPISpec) describeOperation(endpoint string) (string, error) {
+	paths := asMap(s["paths"])
+	method, p, _ := strings.Cut(strings.TrimSpace(endpoint), " ")
+	method = strings.ToLower(method)
+	var op, item map[string]any
+	if item = asMap(paths[strings.TrimSpace(p)]); item != nil {
+		op = asMap(item[method])
+	}
+	if op == nil {
+		// an operationId
+		for _, candidate := range sortedKeys(paths) {
+			for _, m := range openAPIMethods {
+				if o := asMap(asMap(paths[candidate])[m]); o != nil && asString(o["operationId"]) == endpoint {
+					op, item, method, p = o, asMap(paths[candidate]), m, candidate
+				}
+			}
+		}
+	}
+	if op == nil {
+		return "", fmt.Errorf("no operation %q in the spec, list the endpoints to find it", endpoint)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", strings.ToUpper(method), strings.TrimSpace(p))
+	if id := asString(op["operationId"]); id != "" {
+		fmt.Fprintf(&b, " (%s)", id)
+	}
+	b.WriteString("\n")
+	for _, key := range []string{"summary", "description"} {
+		if text := strings.TrimSpace(asString(op[key])); text != "" {
+			b.WriteString(text + "\n")
+		}
+	}
+
+	// path level parameters apply to every operation, unless overridden
+	params := map[string]map[string]any{}
+	var order []string
+	for _, list := range []any{item["parameters"], op["parameters"]} {
+		for _, v := range asSlice(list) {
+			param := s.resolve(v)
+			key := asString(param["in"]) + " " + asString(param["name"])
+			if _, ok := params[key]; !ok {
+				order = append(order, key)
+			}
+			params[key] = param
+		}
+	}
+	var body map[string]any
+	if len(order) > 0 {
+		b.WriteString("\nparameters:\n")
+	}
+	for _, key := range order {
+		param := params[key]
+		if asString(param["in"]) == "body" {
+			// Swagger 2 request bodies are parameters
+			body = param
+			continue
+		}
+		schema := asMap(param["schema"])
+		if schema == nil {
+			schema = param
+		}
+		fmt.Fprintf(&b, "  %s (in %s): %s%s%s\n", asString(param["name"]), asString(param["in"]),
+			s.typeOf(schema), requiredMark(param["required"] == true), describe(param))
+	}
+
+	if rb := s.resolve(op["requestBody"]); rb != nil {
+		b.WriteString("\nrequest body:" + requiredMark(rb["required"] == true) + "\n")
+		content := asMap(rb["content"])
+		for _, mediaType := range sortedKeys(content) {
+			fmt.Fprintf(&b, "  %s: %s\n", mediaType, s.typeOf(asMap(asMap(content[mediaType])["schema"])))
+		}
+	} else if body != nil {
+		fmt.Fprintf(&b, "\nrequest body:%s\n  %s\n", requiredMark(body["required"] == true), s.typeOf(asMap(body["schema"])))
+	}
+
+	responses := asMap(op["responses"])
+	if len(responses) > 0 {
+		b.WriteString("\nresponses:\n")
+	}
+	for _, code := range sortedKeys(responses) {
+		resp := s.resolve(responses[code])
+		line := "  " + code
+		if d := strings.TrimSpace(asString(resp["description"])); d != "" {
+			line += " " + d
+		}
+		var types []string
+		if schema := asMap(resp["schema"]); schema != nil {
+			types = append(types, s.typeOf(schema))
+		}
+		content := asMap(resp["content"])
+		for _, mediaType := range sortedKeys(content) {
+			if schema := asMap(asMap(content[mediaType])["schema"]); schema != nil {
+				types = append(types, mediaType+" "+s.typeOf(schema))
+			}
+		}
+		if len(types) > 0 {
+			line += ": " + strings.Join(types, ", ")
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String(), nil
+}
+
+func (s openAPISpec) describeSchema(name string) (string, error) {
+	schemas := s.schemas()
+	schema := asMap(schemas[name])
+	if schema == nil {
+		return "", fmt.Errorf("no schema %q in the spec, it has: %s", name, strings.Join(sortedKeys(schemas), ", "))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", name, s.typeOf(schema))
+	if d := strings.TrimSpace(asString(schema["description"])); d != "" {
+		b.WriteString(d + "\n")
+	}
+	s.writeProperties(&b, schema, "  ")
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		for _, sub := range asSlice(schema[key]) {
+			// inline members contribute their properties
+			if sub := asMap(sub); sub["$ref"] == nil {
+				s.writeProperties(&b, sub, "  ")
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+func (s openAPISpec) writeProperties(b *strings.Builder, schema map[string]any, indent string) {
+	required := map[string]bool{}
+	for _, r := range asSlice(schema["required"]) {
+		required[asString(r)] = true
+	}
+	properties := asMap(schema["properties"])
+	for _, name := range sortedKeys(properties) {
+		prop := asMap(properties[name])
+		fmt.Fprintf(b, "%s%s: %s%s%s\n", indent, name, s.typeOf(prop), requiredMark(required[name]), describe(prop))
+	}
+}
+
+func requiredMark(required bool) string {
+	if required {
+		return " (required)"
+	}
+	return ""
+}
+
+func describe(m map[string]any) string {
+	d := strings.TrimSpace(asString(m["description"]))
+	if d == "" {
+		return ""
+	}
+	d, _, _ = strings.Cut(d, "\n")
+	return " - " + d
+}
+
+// typeOf renders a schema's type compactly, named schemas by name.
+func (s openAPISpec) typeOf(schema map[string]any) string {
+	if schema == nil {
+		return "any"
+	}
+	if ref := asString(schema["$ref"]); ref != "" {
+		return ref[strings.LastIndex(ref, "/")+1:]
+	}
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		if subs := asSlice(schema[key]); len(subs) > 0 {
+			var types []string
+			for _, sub := range subs {
+				types = append(types, s.typeOf(asMap(sub)))
+			}
+			return key + "(" + strings.Join(types, ", ") + ")"
+		}
+	}
+	t := asString(schema["type"])
+	if types := asSlice(schema["type"]); len(types) > 0 {
+		// OpenAPI 3.1 type lists, e.g. [string, "null"]
+		var names []string
+		for _, v := range types {
+			names = append(names, asString(v))
+		}
+		t = strings.Join(names, "|")
+	}
+	switch t {
+	case "array":
+		return "[]" + s.typeOf(asMap(schema["items"]))
+	case "":
+		if schema["properties"] != nil {
+			t = "object"
+		} else {
+			t = "any"
+		}
+	case "object":
+		if additional := asMap(schema["additionalProperties"]); additional != nil {
+			return "map[string]" + s.typeOf(additional)
+		}
+	}
+	if format := asString(schema["format"]); format != "" {
+		t += " (" + format + ")"
+	}
+	if enum := asSlice(schema["enum"]); len(enum) > 0 {
+		var values []string
+		for _, v := range enum {
+			values = append(values, fmt.Sprint(v))
+		}
+		t += " enum[" + strings.Join(values, "|") + "]"
+	}
+	if schema["nullable"] == true {
+		t += " nullable"
+	}
+	return t
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var ProfileBenchmarkDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "profile_benchmark",
+		Description: "Run the benchmarks of a Go package with CPU or memory profiling and return their results and the hottest functions from pprof. Use this to ground performance work in measurements, and run it again after a change to compare.",
+		Parameters: objectParameters([]string{"package", "bench"}, map[string]Property{
+			"package": {
+				Type:        api.PropertyType{"string"},
+				Description: "The single package to benchmark, e.g. ./parser.",
+			},
+			"bench": {
+				Type:        api.PropertyType{"string"},
+				Description: "Regular expression selecting the benchmarks, as with go test -bench, e.g. . for all.",
+			},
+			"profile": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional, what to profile: cpu time (the default) or mem, bytes allocated.",
+				Enum:        []any{"cpu", "mem"},
+			},
+			"top": {
+				Type:        api.PropertyType{"integer"},
+				Description: "Optional number of functions to return, defaults to 20.",
+			},
+		}),
+	},
+	Function: ProfileBenchmark,
+	Effect:   EffectExec,
+}
+
+type ProfileBenchmarkInput struct {
+	Package string `json:"package"`
+	Bench   string `json:"bench"`
+	Profile string `json:"profile,omitempty"`
+	Top     int    `json:"top,omitempty"`
+}
+
+const (
+	defaultProfileTop = 20
+	maxProfileTop     = 100
+)
+
+func ProfileBenchmark(ctx context.Context, input json.RawMessage) (string, error) {
+	profileBenchmarkInput := ProfileBenchmarkInput{}
+	err := json.Unmarshal(input, &profileBenchmarkInput)
+	if err != nil {
+		return "", err
+	}
+	if profileBenchmarkInput.Package == "" || profileBenchmarkInput.Bench == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	top := defaultProfileTop
+	if profileBenchmarkInput.Top > 0 {
+		top = min(profileBenchmarkInput.Top, maxProfileTop)
+	}
+	profileFlag, sampleIndex := "-cpuprofile", ""
+	switch profileBenchmarkInput.Profile {
+	case "", "cpu":
+	case "mem":
+		profileFlag, sampleIndex = "-memprofile", " -sample_index=alloc_space"
+	default:
+		return "", fmt.Errorf("invalid input parameters")
+	}
+
+	bench := fmt.Sprintf(`go test -run '^$' -bench %s -benchmem %s "$d/profile" -o "$d/test.bin" %s`,
+		workspace.ShellQuote(profileBenchmarkInput.Bench), profileFlag, workspace.ShellQuote(profileBenchmarkInput.Package))
+	root := workspace.FromContext(ctx).Primary()
+	if DryRun(ctx) {
+		return fmt.Sprintf("dry run, the benchmarks were not run, it would run in %s:\n%s", root.Path, bench), nil
+	}
+
+	// the profile is written and analyzed where the benchmarks run
+	script := fmt.Sprintf(`d=$(mktemp -d) || exit 1; %s; s=$?; echo %s; [ -s "$d/profile" ] && go tool pprof -top -nodecount=%d%s "$d/test.bin" "$d/profile" 2>&1; rm -rf "$d"; exit $s`,
+		bench, workspace.ShellQuote(profileMarker), top, sampleIndex)
+	out, _ := root.FS.Command(ctx, root.Path, script).CombinedOutput()
+	benchOutput, pprof, ok := strings.Cut(string(out), profileMarker+"\n")
+	if !ok || ctx.Err() != nil {
+		return "", fmt.Errorf("running the benchmarks failed:\n%s", out)
+	}
+	if strings.TrimSpace(pprof) == "" {
+		return fmt.Sprintf("no profile was written, the benchmark output was:\n%s", benchOutput), nil
+	}
+	return fmt.Sprintf("%s\n%s", strings.TrimSpace(benchOutput), pprof), nil
+}
@@ -0,0 +1,397 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var ProtoSchemaDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name: "proto_schema",
+		Description: `List the services, messages and enums of the Protocol Buffers (.proto) files of the project, or describe one of them.
+
+Without name it lists the services with their RPCs and the names of the messages and enums, with name it describes the matching declarations with their fields and values.`,
+		Parameters: objectParameters([]string{}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional relative path of a .proto file or of a directory to search, defaults to the working directory.",
+			},
+			"name": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional name of a service, message or enum to describe, e.g. GetUserRequest or user.v1.User.",
+			},
+		}),
+	},
+	Function: ProtoSchema,
+}
+
+var ProtoGenerateDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "proto_generate",
+		Description: "Generate code from the .proto files, with buf generate when the directory has a buf.gen.yaml, otherwise with protoc and the arguments given. Run it after changing .proto files, before changing the code using them.",
+		Parameters: objectParameters([]string{}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional relative path of the directory to generate in, defaults to the working directory.",
+			},
+			"protoc_args": {
+				Type:        api.PropertyType{"string"},
+				Description: "The protoc arguments, when there is no buf.gen.yaml, e.g. --go_out=. --go-grpc_out=. api/user.proto.",
+			},
+		}),
+	},
+	Function: ProtoGenerate,
+	Effect:   EffectExec,
+}
+
+type protoDecl struct {
+	kind, name string // kind is service, message or enum
+	// lines are the RPCs, fields or values
+	lines  []string
+	nested []*protoDecl
+}
+
+type protoFile struct {
+	path, pkg string
+	decls     []*protoDecl
+}
+
+// tokenizeProto splits a .proto file into identifiers, strings and
+// punctuation, dropping comments.
+func tokenizeProto(src string) []string {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != c {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(src))
+			tokens = append(tokens, src[i:end])
+			i = end
+		case unicode.IsSpace(rune(c)):
+			i++
+		case strings.IndexByte("{}()[]<>;=,", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			end := i
+			for end < len(src) && !unicode.IsSpace(rune(src[end])) && strings.IndexByte("{}()[]<>;=,\"'/", src[end]) < 0 {
+				end++
+			}
+			if end == i {
+				end++
+			}
+			tokens = append(tokens, src[i:end])
+			i = end
+		}
+	}
+	return tokens
+}
+
+type protoParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *protoParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+func (p *protoParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+// statement returns the tokens up to the end of the statement, skipping
+// a block that ends it, such as an option's or an rpc's.
+func (p *protoParser) statement() []string {
+	var tokens []string
+	for t := p.next(); t != "" && t != ";"; t = p.next() {
+		if t == "{" {
+			p.skipBlock()
+			break
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+func (p *protoParser) skipBlock() {
+	for depth := 1; depth > 0; {
+		switch p.next() {
+		case "":
+			return
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+	}
+}
+
+func parseProto(path, src string) *protoFile {
+	p := &protoParser{tokens: tokenizeProto(src)}
+	f := &protoFile{path: path}
+	for p.peek() != "" {
+		switch p.peek() {
+		case "package":
+			p.next()
+			f.pkg = strings.Join(p.statement(), "")
+		case "message", "enum", "service":
+			f.decls = append(f.decls, p.decl())
+		default:
+			p.statement()
+		}
+	}
+	return f
+}
+
+// decl parses a message, enum or service, the parser is at its keyword.
+func (p *protoParser) decl() *protoDecl {
+	d := &protoDecl{kind: p.next(), name: p.next()}
+	if p.next() != "{" {
+		return d
+	}
+	for {
+		switch t := p.peek(); t {
+		case "", "}":
+			p.next()
+			return d
+		case "message", "enum":
+			d.nested = append(d.nested, p.decl())
+		case "oneof":
+			p.next()
+			name := p.next()
+			p.next()
+			var fields []string
+			for p.peek() != "}" && p.peek() != "" {
+				if field := protoField(p.statement()); field != "" {
+					fields = append(fields, field)
+				}
+			}
+			p.next()
+			d.lines = append(d.lines, fmt.Sprintf("oneof %s { %s }", name, strings.Join(fields, "; ")))
+		case "option", "reserved", "extensions", ";":
+			p.statement()
+		default:
+			tokens := p.statement()
+			switch d.kind {
+			case "service":
+				if len(tokens) > 0 && tokens[0] == "rpc" {
+					d.lines = append(d.lines, protoRPC(tokens))
+				}
+			case "enum":
+				if len(tokens) >= 3 && tokens[1] == "=" {
+					d.lines = append(d.lines, tokens[0]+" = "+tokens[2])
+				}
+			default:
+				if field := protoField(tokens); field != "" {
+					d.lines = append(d.lines, field)
+				}
+			}
+		}
+	}
+}
+
+// protoField formats a field, dropping its options.
+func protoField(tokens []string) string {
+	if i := indexOf(tokens, "["); i >= 0 {
+		tokens = tokens[:i]
+	}
+	eq := indexOf(tokens, "=")
+	if eq < 2 || eq+1 >= len(tokens) {
+		return ""
+	}
+	typ := strings.Join(tokens[:eq-1], " ")
+	typ = strings.NewReplacer(" < ", "<", " , ", ",", " >", ">").Replace(typ)
+	return fmt.Sprintf("%s %s = %s", typ, tokens[eq-1], tokens[eq+1])
+}
+
+// protoRPC formats rpc Name(stream Req) returns (Resp).
+func protoRPC(tokens []string) string {
+	s := strings.NewReplacer("( ", "(", " )", ")").Replace(strings.Join(tokens, " "))
+	return strings.Replace(s, " (", "(", 1)
+}
+
+func indexOf(tokens []string, s string) int {
+	for i, t := range tokens {
+		if t == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *protoDecl) write(b *strings.Builder, indent string, full bool) {
+	fmt.Fprintf(b, "%s%s %s\n", indent, d.kind, d.name)
+	if full || d.kind == "service" {
+		for _, line := range d.lines {
+			fmt.Fprintf(b, "%s  %s\n", indent, line)
+		}
+	}
+	for _, n := range d.nested {
+		n.write(b, indent+"  ", full)
+	}
+}
+
+// find returns the declarations named name, or prefix.name.
+func (d *protoDecl) find(prefix, name string, found *[]*protoDecl) {
+	qualified := d.name
+	if prefix != "" {
+		qualified = prefix + "." + d.name
+	}
+	if name == qualified || strings.HasSuffix(qualified, "."+name) {
+		*found = append(*found, d)
+	}
+	for _, n := range d.nested {
+		n.find(qualified, name, found)
+	}
+}
+
+type ProtoSchemaInput struct {
+	Path string `json:"path,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+func ProtoSchema(ctx context.Context, input json.RawMessage) (string, error) {
+	protoSchemaInput := ProtoSchemaInput{}
+	err := json.Unmarshal(input, &protoSchemaInput)
+	if err != nil {
+		return "", err
+	}
+	ws := workspace.FromContext(ctx)
+	path := protoSchemaInput.Path
+	if path == "" {
+		path = "."
+	}
+	root, abs, err := ws.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	var paths []string
+	if strings.HasSuffix(abs, ".proto") {
+		paths = []string{abs}
+	} else {
+		files, err := root.FS.Walk(abs)
+		if err != nil {
+			return "", err
+		}
+		for _, f := range files {
+			if strings.HasSuffix(f, ".proto") && !strings.Contains("/"+f, "/node_modules/") && !strings.Contains("/"+f, "/vendor/") {
+				paths = append(paths, filepath.Join(abs, f))
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("no .proto files found in %s", path)
+	}
+
+	var b strings.Builder
+	for _, p := range paths {
+		src, err := root.FS.ReadFile(p)
+		if err != nil {
+			return "", err
+		}
+		f := parseProto(ws.Display(root, p), string(src))
+		if protoSchemaInput.Name == "" {
+			fmt.Fprintf(&b, "%s (package %s)\n", f.path, f.pkg)
+			for _, d := range f.decls {
+				d.write(&b, "  ", false)
+			}
+			continue
+		}
+		var found []*protoDecl
+		for _, d := range f.decls {
+			d.find(f.pkg, protoSchemaInput.Name, &found)
+		}
+		for _, d := range found {
+			fmt.Fprintf(&b, "%s (package %s)\n", f.path, f.pkg)
+			d.write(&b, "  ", true)
+		}
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("no service, message or enum named %s, list them without a name", protoSchemaInput.Name)
+	}
+	return b.String(), nil
+}
+
+type ProtoGenerateInput struct {
+	Path       string `json:"path,omitempty"`
+	ProtocArgs string `json:"protoc_args,omitempty"`
+}
+
+func ProtoGenerate(ctx context.Context, input json.RawMessage) (string, error) {
+	protoGenerateInput := ProtoGenerateInput{}
+	err := json.Unmarshal(input, &protoGenerateInput)
+	if err != nil {
+		return "", err
+	}
+	ws := workspace.FromContext(ctx)
+	path := protoGenerateInput.Path
+	if path == "" {
+		path = "."
+	}
+	root, dir, err := ws.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	command := ""
+	for _, name := range []string{"buf.gen.yaml", "buf.gen.yml"} {
+		if _, err := root.FS.ReadFile(filepath.Join(dir, name)); err == nil {
+			command = "buf generate --template " + name
+			break
+		}
+	}
+	if command == "" {
+		if protoGenerateInput.ProtocArgs == "" {
+			return "", fmt.Errorf("there is no buf.gen.yaml in %s, give the protoc arguments", ws.Display(root, dir))
+		}
+		command = "protoc"
+		for _, arg := range strings.Fields(protoGenerateInput.ProtocArgs) {
+			command += " " + workspace.ShellQuote(arg)
+		}
+	}
+	if DryRun(ctx) {
+		return fmt.Sprintf("dry run, no code was generated, it would run in %s:\n%s", ws.Display(root, dir), command), nil
+	}
+	out, err := root.FS.Command(ctx, dir, command).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %v\n%s", strings.Fields(command)[0], err, out)
+	}
+	if len(strings.TrimSpace(string(out))) == 0 {
+		return "OK", nil
+	}
+	return string(out), nil
+}
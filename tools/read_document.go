@@ -0,0 +1,429 @@
+package tools
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var ReadDocumentDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "read_document",
+		Description: "Extract the text of a PDF or office document (docx, pptx, xlsx, odt, odp, ods) in the workspace, such as a specification or design document. Use read_file for plain text files.",
+		Parameters: objectParameters([]string{"path"}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "The relative path of the document.",
+			},
+		}),
+	},
+	Function: ReadDocument,
+}
+
+type ReadDocumentInput struct {
+	Path string `json:"path"`
+}
+
+// the text returned, documents can be long
+const maxDocumentText = 100 * 1024
+
+func ReadDocument(ctx context.Context, input json.RawMessage) (string, error) {
+	readDocumentInput := ReadDocumentInput{}
+	err := json.Unmarshal(input, &readDocumentInput)
+	if err != nil {
+		return "", err
+	}
+	if readDocumentInput.Path == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	root, path, err := workspace.FromContext(ctx).Resolve(readDocumentInput.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var text string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".pdf":
+		text, err = pdfText(ctx, root, path)
+	case ".docx", ".pptx", ".xlsx", ".odt", ".odp", ".ods":
+		var content []byte
+		content, err = root.FS.ReadFile(path)
+		if err == nil {
+			text, err = officeText(content, ext)
+		}
+	default:
+		return "", fmt.Errorf("unsupported document %s, use read_file for text files", filepath.Base(path))
+	}
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "no text found, the document may only contain images", nil
+	}
+	if len(text) > maxDocumentText {
+		text = fmt.Sprintf("%s\n[%d more bytes not shown]", text[:maxDocumentText], len(text)-maxDocumentText)
+	}
+	return text, nil
+}
+
+// pdfText uses pdftotext (poppler) where the document is, it handles far
+// more PDFs than the built-in extraction it falls back to.
+func pdfText(ctx context.Context, root *workspace.Root, path string) (string, error) {
+	cmd := root.FS.Command(ctx, root.Path, "pdftotext -layout "+workspace.ShellQuote(path)+" -")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err == nil {
+		return string(out), nil
+	}
+	var exitErr *exec.ExitError
+	// 127 is sh's command not found
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 127 {
+		return "", fmt.Errorf("pdftotext: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	content, err := root.FS.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	text := simplePDFText(content)
+	return "(extracted without pdftotext, install poppler for better results)\n" + text, nil
+}
+
+var pdfStream = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+
+// simplePDFText extracts the literal strings shown by the text operators of
+// the uncompressed and Flate compressed content streams, which covers
+// simple PDFs but not those with embedded CID fonts.
+func simplePDFText(content []byte) string {
+	var out strings.Builder
+	for _, m := range pdfStream.FindAllSubmatchIndex(content, -1) {
+		dict := content[m[2]:m[3]]
+		data := content[m[1]:]
+		end := bytes.Index(data, []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		data = data[:end]
+		if bytes.Contains(dict, []byte("/Subtype")) {
+			// images, fonts and other embedded files
+			continue
+		}
+		switch {
+		case bytes.Contains(dict, []byte("/FlateDecode")):
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				continue
+			}
+			data, _ = io.ReadAll(zr)
+		case bytes.Contains(dict, []byte("/Filter")):
+			continue
+		}
+		out.WriteString(pdfContentText(data))
+	}
+	return out.String()
+}
+
+// pdfContentText interprets the text operators of a content stream.
+func pdfContentText(data []byte) string {
+	var out strings.Builder
+	var pending []string
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; {
+		case c == '(':
+			s, n := pdfLiteral(data[i:])
+			pending = append(pending, s)
+			i += n - 1
+		case c == '-' && len(pending) > 0:
+			// a large negative adjustment between the strings of a TJ
+			// array is a space between words
+			j := i + 1
+			for j < len(data) && (data[j] >= '0' && data[j] <= '9' || data[j] == '.') {
+				j++
+			}
+			if n, err := strconv.ParseFloat(string(data[i:j]), 64); err == nil && n < -200 {
+				pending = append(pending, " ")
+			}
+			i = j - 1
+		case c == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		case c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '\'' || c == '"' || c == '*':
+			j := i
+			for j < len(data) && (data[j] >= 'A' && data[j] <= 'Z' || data[j] >= 'a' && data[j] <= 'z' || data[j] == '\'' || data[j] == '"' || data[j] == '*') {
+				j++
+			}
+			switch string(data[i:j]) {
+			case "Tj", "TJ":
+				out.WriteString(strings.Join(pending, ""))
+			case "'", "\"":
+				out.WriteString("\n" + strings.Join(pending, ""))
+			case "Td", "TD", "T*", "ET":
+				if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+					out.WriteString("\n")
+				}
+			}
+			pending = pending[:0]
+			i = j - 1
+		}
+	}
+	return out.String()
+}
+
+// pdfLiteral decodes the literal string at the start of data, returning it
+// and the number of bytes it spans.
+func pdfLiteral(data []byte) (string, int) {
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '\\' && i+1 < len(data):
+			i++
+			switch e := data[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// a line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					j := i
+					for j < len(data) && j < i+3 && data[j] >= '0' && data[j] <= '7' {
+						j++
+					}
+					n, _ := strconv.ParseUint(string(data[i:j]), 8, 8)
+					b.WriteByte(byte(n))
+					i = j - 1
+				} else {
+					b.WriteByte(e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				b.WriteByte(c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return b.String(), i + 1
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), len(data)
+}
+
+// officeText extracts the text of an Office Open XML or OpenDocument file,
+// both are zip archives of XML parts.
+func officeText(content []byte, ext string) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return "", fmt.Errorf("invalid %s document: %v", ext, err)
+	}
+	parts := map[string]*zip.File{}
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+	read := func(name string) ([]byte, error) {
+		f, ok := parts[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid %s document: %s missing", ext, name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, 64<<20))
+	}
+	// numbered parts, e.g. ppt/slides/slide10.xml, in numeric order
+	numbered := func(prefix string) []string {
+		var names []string
+		for name := range parts {
+			if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ".xml") {
+				names = append(names, name)
+			}
+		}
+		number := func(name string) int {
+			n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".xml"))
+			return n
+		}
+		sort.Slice(names, func(i, j int) bool { return number(names[i]) < number(names[j]) })
+		return names
+	}
+
+	var out strings.Builder
+	switch ext {
+	case ".docx":
+		doc, err := read("word/document.xml")
+		if err != nil {
+			return "", err
+		}
+		return xmlText(doc, "t")
+	case ".pptx":
+		for i, name := range numbered("ppt/slides/slide") {
+			slide, err := read(name)
+			if err != nil {
+				return "", err
+			}
+			text, err := xmlText(slide, "t")
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&out, "--- slide %d\n%s\n", i+1, strings.TrimSpace(text))
+		}
+		return out.String(), nil
+	case ".xlsx":
+		var shared []string
+		if buf, err := read("xl/sharedStrings.xml"); err == nil {
+			shared, err = xlsxSharedStrings(buf)
+			if err != nil {
+				return "", err
+			}
+		}
+		for i, name := range numbered("xl/worksheets/sheet") {
+			sheet, err := read(name)
+			if err != nil {
+				return "", err
+			}
+			rows, err := xlsxRows(sheet, shared)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&out, "--- sheet %d\n%s\n", i+1, rows)
+		}
+		return out.String(), nil
+	default:
+		// OpenDocument keeps all of the text in content.xml
+		doc, err := read("content.xml")
+		if err != nil {
+			return "", err
+		}
+		return xmlText(doc, "")
+	}
+}
+
+// xmlText returns the character data of the elements named textElement,
+// or of all elements when it is empty, with paragraphs, rows and breaks on
+// their own lines.
+func xmlText(doc []byte, textElement string) (string, error) {
+	var out strings.Builder
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	inText := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return out.String(), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid document XML: %v", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case textElement:
+				inText++
+			case "tab":
+				out.WriteString("\t")
+			case "br", "line-break":
+				out.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case textElement:
+				inText--
+			case "p", "h", "tr", "table-row":
+				out.WriteString("\n")
+			case "tc", "table-cell":
+				out.WriteString("\t")
+			}
+		case xml.CharData:
+			if textElement == "" || inText > 0 {
+				out.Write(t)
+			}
+		}
+	}
+}
+
+func xlsxSharedStrings(buf []byte) ([]string, error) {
+	var sst struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := xml.Unmarshal(buf, &sst); err != nil {
+		return nil, fmt.Errorf("invalid shared strings: %v", err)
+	}
+	shared := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		shared[i] = si.Text
+		for _, r := range si.Runs {
+			shared[i] += r.Text
+		}
+	}
+	return shared, nil
+}
+
+// xlsxRows returns a sheet's rows with tab separated cells.
+func xlsxRows(buf []byte, shared []string) (string, error) {
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline struct {
+					Text string `xml:"t"`
+				} `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(buf, &sheet); err != nil {
+		return "", fmt.Errorf("invalid worksheet: %v", err)
+	}
+	var out strings.Builder
+	for _, row := range sheet.Rows {
+		cells := make([]string, len(row.Cells))
+		for i, c := range row.Cells {
+			switch c.Type {
+			case "s":
+				if n, err := strconv.Atoi(c.Value); err == nil && n >= 0 && n < len(shared) {
+					cells[i] = shared[n]
+				}
+			case "inlineStr":
+				cells[i] = c.Inline.Text
+			default:
+				cells[i] = c.Value
+			}
+		}
+		out.WriteString(strings.Join(cells, "\t") + "\n")
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var ReadFileDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "read_file",
+		Description: "Read the contents of a given relative file path. Use this when you want to see what's inside a file. Do not use this with directory names. In Go files, pass symbol to read just the declaration of a function, method, type, const or var.",
+		Parameters: objectParameters(nil, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "The relative path of a file in the working directory.",
+			},
+			"symbol": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional name of a function, method (Type.Method), type, const or var declared in the Go file, to read only its declaration, with its doc comment and line numbers.",
+			},
+		}),
+	},
+	Function: ReadFile,
+}
+
+type ReadFileInput struct {
+	Path   string `json:"path"`
+	Symbol string `json:"symbol,omitempty"`
+}
+
+func ReadFile(ctx context.Context, input json.RawMessage) (string, error) {
+	readFileInput := ReadFileInput{}
+	err := json.Unmarshal(input, &readFileInput)
+	if err != nil {
+		panic(err)
+	}
+
+	root, path, err := workspace.FromContext(ctx).Resolve(readFileInput.Path)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := root.FS.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// as edit_file expects it, with \n line endings
+	text, _, _ := decodeText(content)
+	if readFileInput.Symbol != "" {
+		return goSymbol(readFileInput.Path, []byte(text), readFileInput.Symbol)
+	}
+	return text, nil
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/session"
+)
+
+var RecallResultDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "recall_result",
+		Description: "Fetch the full content of an earlier tool result by its ID, e.g. R12. Large tool results start with their ID, [result R12], so they can be referred to after they were summarized away.",
+		Parameters: objectParameters([]string{"id"}, map[string]Property{
+			"id": {
+				Type:        api.PropertyType{"string"},
+				Description: "The ID of the result, e.g. R12.",
+			},
+		}),
+	},
+	Function: RecallResult,
+}
+
+type RecallResultInput struct {
+	ID string `json:"id"`
+}
+
+func RecallResult(ctx context.Context, input json.RawMessage) (string, error) {
+	recallResultInput := RecallResultInput{}
+	err := json.Unmarshal(input, &recallResultInput)
+	if err != nil {
+		return "", err
+	}
+	if recallResultInput.ID == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	s, ok := session.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("no session to recall results from")
+	}
+	id := strings.ToUpper(strings.TrimSpace(recallResultInput.ID))
+	r, ok := s.Result(id)
+	if !ok {
+		return "", fmt.Errorf("no result %s", id)
+	}
+	return r.Content, nil
+}
@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+// maxReplaceFiles is how many files replace_in_files changes at most in a
+// call, more are likely a pattern matching more than intended
+const maxReplaceFiles = 200
+
+// replaceDepth is how many directory levels below path replace_in_files
+// searches
+const replaceDepth = 64
+
+var ReplaceInFilesDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name: "replace_in_files",
+		Description: `Find and replace text in every file matching a glob, such as to rename an identifier across the project, rather than calling edit_file for each occurrence.
+
+Returns the number of replacements per file and the diff. The changes are only applied once the user approves them.`,
+		Parameters: objectParameters([]string{"glob", "find", "replace"}, map[string]Property{
+			"glob": {
+				Type:        api.PropertyType{"string"},
+				Description: "The files to change, e.g. *.go, matched against the path relative to path, or the file name when it has no /.",
+			},
+			"find": {
+				Type:        api.PropertyType{"string"},
+				Description: "The text to find, or a Go regular expression when regex is true.",
+			},
+			"replace": {
+				Type:        api.PropertyType{"string"},
+				Description: "The text to replace it with, when regex is true $1 or ${name} are the expression's groups.",
+			},
+			"regex": {
+				Type:        api.PropertyType{"boolean"},
+				Description: "Whether find is a regular expression, by default it is literal.",
+			},
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional relative path of the directory to search, by default the working directory.",
+			},
+		}),
+	},
+	Function: ReplaceInFiles,
+	Effect:   EffectWrite,
+}
+
+type ReplaceInFilesInput struct {
+	Glob    string `json:"glob"`
+	Find    string `json:"find"`
+	Replace string `json:"replace"`
+	Regex   bool   `json:"regex,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+func ReplaceInFiles(ctx context.Context, input json.RawMessage) (string, error) {
+	replaceInput := ReplaceInFilesInput{}
+	if err := json.Unmarshal(input, &replaceInput); err != nil {
+		return "", err
+	}
+	if replaceInput.Glob == "" || replaceInput.Find == "" {
+		return "", fmt.Errorf("glob and find are required")
+	}
+	if _, err := filepath.Match(replaceInput.Glob, ""); err != nil {
+		return "", fmt.Errorf("invalid glob %q: %w", replaceInput.Glob, err)
+	}
+	pattern := regexp.QuoteMeta(replaceInput.Find)
+	if replaceInput.Regex {
+		pattern = replaceInput.Find
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid regular expression: %w", err)
+	}
+
+	ws := workspace.FromContext(ctx)
+	root, dir, err := ws.Resolve(replaceInput.Path)
+	if err != nil {
+		return "", err
+	}
+	files, err := root.FS.List(dir, replaceDepth)
+	if err != nil {
+		return "", err
+	}
+
+	type change struct {
+		file    *textFile
+		display string
+		new     string
+		count   int
+	}
+	var changes []change
+	for _, rel := range files {
+		if strings.HasSuffix(rel, "/") || skippedPath(rel) || !matchGlob(replaceInput.Glob, rel) {
+			continue
+		}
+		path := filepath.Join(dir, rel)
+		if _, _, err := ws.Resolve(ws.Display(root, path)); err != nil {
+			// a symlink out of the root
+			continue
+		}
+		f, err := openText(root, path)
+		if err != nil {
+			return "", err
+		}
+		if strings.ContainsRune(f.content, 0) {
+			// binary
+			continue
+		}
+		count := len(re.FindAllStringIndex(f.content, -1))
+		if count == 0 {
+			continue
+		}
+		var updated string
+		if replaceInput.Regex {
+			updated = re.ReplaceAllString(f.content, replaceInput.Replace)
+		} else {
+			updated = re.ReplaceAllLiteralString(f.content, replaceInput.Replace)
+		}
+		if f.content == updated {
+			continue
+		}
+		changes = append(changes, change{f, ws.Display(root, path), updated, count})
+		if len(changes) > maxReplaceFiles {
+			return "", fmt.Errorf("more than %d files would change, narrow the glob or path", maxReplaceFiles)
+		}
+	}
+	if len(changes) == 0 {
+		return "no matches, no files were changed", nil
+	}
+
+	var summary, diff strings.Builder
+	paths := make([]string, len(changes))
+	total := 0
+	for i, c := range changes {
+		paths[i] = c.display
+		total += c.count
+		fmt.Fprintf(&summary, "%s: %d replacements\n", c.display, c.count)
+		diff.WriteString(UnifiedDiff(c.display, c.file.content, c.new))
+	}
+	report := fmt.Sprintf("%d replacements in %d files:\n%s\n%s", total, len(changes), summary.String(), diff.String())
+	if DryRun(ctx) {
+		return "dry run, no files were changed, the replacement would make these changes:\n" + report, nil
+	}
+	if err := Approve(ctx, paths, diff.String()); err != nil {
+		return fmt.Sprintf("no files were changed, %v", err), nil
+	}
+	for _, c := range changes {
+		if err := c.file.write(c.new); err != nil {
+			return "", fmt.Errorf("error writing %s, the files before it were changed: %w", c.display, err)
+		}
+	}
+	return report, nil
+}
+
+// matchGlob matches the glob against the relative path, or when it has no
+// separator against the file name, see filepath.Match.
+func matchGlob(glob, rel string) bool {
+	if ok, _ := filepath.Match(glob, rel); ok {
+		return true
+	}
+	if !strings.ContainsRune(glob, '/') {
+		ok, _ := filepath.Match(glob, filepath.Base(rel))
+		return ok
+	}
+	return false
+}
+
+// skippedPath reports whether the relative path is hidden or vendored,
+// such as in .git or node_modules.
+func skippedPath(rel string) bool {
+	return slices.ContainsFunc(strings.Split(filepath.ToSlash(rel), "/"), workspace.SkipDir)
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+const defaultCommandTimeout = 10 * time.Minute
+
+var RunCommandDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "run_command",
+		Description: "Run a shell command in the primary workspace root and return its combined stdout and stderr. Use this to build, run tests or inspect the environment.",
+		Parameters: objectParameters([]string{"command"}, map[string]Property{
+			"command": {
+				Type:        api.PropertyType{"string"},
+				Description: "The shell command to run, it is passed to sh -c.",
+			},
+			"timeout_seconds": {
+				Type:        api.PropertyType{"integer"},
+				Description: "Optional timeout in seconds, defaults to 600.",
+			},
+		}),
+	},
+	Function: RunCommand,
+	Effect:   EffectExec,
+}
+
+type RunCommandInput struct {
+	Command        string `json:"command"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+}
+
+func RunCommand(ctx context.Context, input json.RawMessage) (string, error) {
+	runCommandInput := RunCommandInput{}
+	err := json.Unmarshal(input, &runCommandInput)
+	if err != nil {
+		return "", err
+	}
+	if runCommandInput.Command == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+
+	timeout := defaultCommandTimeout
+	if runCommandInput.TimeoutSeconds > 0 {
+		timeout = time.Duration(runCommandInput.TimeoutSeconds) * time.Second
+	}
+	root := workspace.FromContext(ctx).Primary()
+	if DryRun(ctx) {
+		return fmt.Sprintf("dry run, the command was not run, it would run in %s:\n%s", root.Path, runCommandInput.Command), nil
+	}
+	return runScript(ctx, root, runCommandInput.Command, timeout)
+}
+
+// runScript runs the shell script in the root, streaming its output, and
+// returns the output with how it ended if it failed.
+func runScript(ctx context.Context, root *workspace.Root, script string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var out bytes.Buffer
+	w := io.MultiWriter(&out, Output(ctx))
+	cmd := root.FS.Command(ctx, root.Path, script)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		fmt.Fprintf(&out, "\ncommand timed out after %s", timeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		fmt.Fprint(&out, "\ncommand interrupted")
+	case errors.As(err, &exitErr):
+		fmt.Fprintf(&out, "\n%s", exitErr.Error())
+	case err != nil:
+		return "", err
+	}
+	return out.String(), nil
+}
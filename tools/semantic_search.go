@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/index"
+)
+
+const defaultSearchLimit = 5
+
+func NewSemanticSearch(idx *index.Index) Tool {
+	return Tool{
+		Definition: api.ToolFunction{
+			Name:        "semantic_search",
+			Description: "Search the files in the working directory for code and text related to a query, by meaning and by keywords, so natural language and exact identifiers both work. Returns the most relevant snippets with their file paths and line numbers.",
+			Parameters: objectParameters([]string{"query"}, map[string]Property{
+				"query": {
+					Type:        api.PropertyType{"string"},
+					Description: "What to search for, in natural language and/or identifiers.",
+				},
+				"limit": {
+					Type:        api.PropertyType{"integer"},
+					Description: "Optional maximum number of snippets to return, defaults to 5.",
+				},
+			}),
+		},
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			return semanticSearch(ctx, idx, input)
+		},
+	}
+}
+
+type SemanticSearchInput struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+func semanticSearch(ctx context.Context, idx *index.Index, input json.RawMessage) (string, error) {
+	semanticSearchInput := SemanticSearchInput{}
+	err := json.Unmarshal(input, &semanticSearchInput)
+	if err != nil {
+		return "", err
+	}
+	if semanticSearchInput.Query == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	limit := defaultSearchLimit
+	if semanticSearchInput.Limit > 0 {
+		limit = semanticSearchInput.Limit
+	}
+
+	results, err := idx.Search(ctx, semanticSearchInput.Query, limit)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, r := range results {
+		if CheckPath(ctx, r.Path) != nil {
+			// the index holds protected files too
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:%d-%d (score %.3f)\n```\n%s\n```\n", r.Path, r.StartLine, r.EndLine, r.Score, r.Text)
+	}
+	if sb.Len() == 0 {
+		return "no results", nil
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// goSymbol returns the declarations of the symbol in the Go source, with
+// their doc comments and the lines they span. A method is named Type.Method,
+// or just Method for those of every type.
+func goSymbol(path string, content []byte, symbol string) (string, error) {
+	if !strings.HasSuffix(path, ".go") {
+		return "", fmt.Errorf("symbols can only be read from Go files, read %s whole", path)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return "", fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	type decl struct {
+		name       string
+		start, end token.Pos
+	}
+	var decls []decl
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			start := d.Pos()
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			decls = append(decls, decl{symbolName(funcName(d)), start, d.End()})
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range d.Specs {
+				// a spec of a group is read alone, the only one with its
+				// keyword
+				start, end, doc := spec.Pos(), spec.End(), (*ast.CommentGroup)(nil)
+				if d.Lparen.IsValid() {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						doc = s.Doc
+					case *ast.ValueSpec:
+						doc = s.Doc
+					}
+				} else {
+					start, end, doc = d.Pos(), d.End(), d.Doc
+				}
+				if doc != nil {
+					start = doc.Pos()
+				}
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					decls = append(decls, decl{s.Name.Name, start, end})
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						decls = append(decls, decl{n.Name, start, end})
+					}
+				}
+			}
+		}
+	}
+
+	symbol = symbolName(symbol)
+	var found []decl
+	for _, d := range decls {
+		if d.name == symbol {
+			found = append(found, d)
+		}
+	}
+	if len(found) == 0 && !strings.Contains(symbol, ".") {
+		for _, d := range decls {
+			if strings.HasSuffix(d.name, "."+symbol) {
+				found = append(found, d)
+			}
+		}
+	}
+	if len(found) == 0 {
+		names := make([]string, len(decls))
+		for i, d := range decls {
+			names[i] = d.name
+		}
+		return "", fmt.Errorf("%s declares no %s, it declares: %s", path, symbol, strings.Join(names, ", "))
+	}
+
+	var sb strings.Builder
+	for i, d := range found {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		start, end := fset.Position(d.start), fset.Position(d.end)
+		// from the start of the line, for the indentation of a group's spec
+		fmt.Fprintf(&sb, "%s, lines %d-%d:\n%s\n", path, start.Line, end.Line, content[start.Offset-start.Column+1:end.Offset])
+	}
+	return sb.String(), nil
+}
+
+// symbolName returns the name as Type.Method, from (Type).Method or
+// (*Type).Method.
+func symbolName(name string) string {
+	return strings.NewReplacer("(", "", ")", "", "*", "").Replace(strings.TrimSpace(name))
+}
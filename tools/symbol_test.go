@@ -0,0 +1,60 @@
+package tools
+
+import "testing"
+
+func TestGoSymbol(t *testing.T) {
+	const src = `package p
+
+// T is a type.
+type T struct{}
+
+// M is a method.
+func (t *T) M() {}
+
+func F() {}
+
+const (
+	// A is a.
+	A = 1
+	B = 2
+)
+
+var V = 3
+
+func (g G[K]) M() {}
+`
+	for _, test := range []struct {
+		symbol, want string
+	}{
+		{"T", "p.go, lines 3-4:\n// T is a type.\ntype T struct{}\n"},
+		{"T.M", "p.go, lines 6-7:\n// M is a method.\nfunc (t *T) M() {}\n"},
+		{"(*T).M", "p.go, lines 6-7:\n// M is a method.\nfunc (t *T) M() {}\n"},
+		{"F", "p.go, lines 9-9:\nfunc F() {}\n"},
+		{"A", "p.go, lines 12-13:\n\t// A is a.\n\tA = 1\n"},
+		{"B", "p.go, lines 14-14:\n\tB = 2\n"},
+		{"V", "p.go, lines 17-17:\nvar V = 3\n"},
+		{"G.M", "p.go, lines 19-19:\nfunc (g G[K]) M() {}\n"},
+		// the method of every type
+		{"M", "p.go, lines 6-7:\n// M is a method.\nfunc (t *T) M() {}\n\np.go, lines 19-19:\nfunc (g G[K]) M() {}\n"},
+	} {
+		got, err := goSymbol("p.go", []byte(src), test.symbol)
+		if err != nil {
+			t.Errorf("%s: %v", test.symbol, err)
+		} else if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.symbol, got, test.want)
+		}
+	}
+
+	for _, test := range []struct {
+		path, src, symbol string
+	}{
+		{"p.go", src, "X"},
+		{"p.go", src, "T.X"},
+		{"p.py", "def f(): pass", "f"},
+		{"p.go", "package", "F"},
+	} {
+		if _, err := goSymbol(test.path, []byte(test.src), test.symbol); err == nil {
+			t.Errorf("%s in %s succeeded, want an error", test.symbol, test.path)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var TailFileDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name: "tail_file",
+		Description: `Return the last lines of a file, such as a log, optionally only those matching a pattern.
+
+With follow_seconds the file is also watched for that long and the lines appended meanwhile are returned, so you can watch a log while reproducing a problem.`,
+		Parameters: objectParameters([]string{"path"}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "The relative path of the file.",
+			},
+			"lines": {
+				Type:        api.PropertyType{"integer"},
+				Description: "Optional number of lines to return from the end of the file, defaults to 50.",
+			},
+			"pattern": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional regular expression, only matching lines are returned.",
+			},
+			"follow_seconds": {
+				Type:        api.PropertyType{"integer"},
+				Description: "Optional number of seconds to watch the file for new lines, at most 300.",
+			},
+		}),
+	},
+	Function: TailFile,
+}
+
+type TailFileInput struct {
+	Path          string `json:"path"`
+	Lines         int    `json:"lines,omitempty"`
+	Pattern       string `json:"pattern,omitempty"`
+	FollowSeconds int    `json:"follow_seconds,omitempty"`
+}
+
+const (
+	defaultTailLines = 50
+	maxTailLines     = 1000
+	maxFollow        = 300 * time.Second
+)
+
+func TailFile(ctx context.Context, input json.RawMessage) (string, error) {
+	tailFileInput := TailFileInput{}
+	err := json.Unmarshal(input, &tailFileInput)
+	if err != nil {
+		return "", err
+	}
+	if tailFileInput.Path == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	n := defaultTailLines
+	if tailFileInput.Lines > 0 {
+		n = min(tailFileInput.Lines, maxTailLines)
+	}
+	var pattern *regexp.Regexp
+	if tailFileInput.Pattern != "" {
+		pattern, err = regexp.Compile(tailFileInput.Pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+
+	root, path, err := workspace.FromContext(ctx).Resolve(tailFileInput.Path)
+	if err != nil {
+		return "", err
+	}
+	content, err := root.FS.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	out.WriteString(strings.Join(lastLines(string(content), pattern, n), "\n"))
+
+	if tailFileInput.FollowSeconds <= 0 {
+		return out.String(), nil
+	}
+	follow := min(time.Duration(tailFileInput.FollowSeconds)*time.Second, maxFollow)
+	followCtx, cancel := context.WithTimeout(ctx, follow)
+	defer cancel()
+	// tail follows the file through the root's FS, also when it is remote
+	var appended bytes.Buffer
+	cmd := root.FS.Command(followCtx, root.Path, "exec tail -n 0 -F "+workspace.ShellQuote(path))
+	cmd.WaitDelay = time.Second
+	cmd.Stdout = io.MultiWriter(&appended, Output(ctx))
+	start := time.Now()
+	_ = cmd.Run()
+
+	lines := lastLines(appended.String(), pattern, maxTailLines)
+	fmt.Fprintf(&out, "\n--- %d lines appended in %s", len(lines), time.Since(start).Round(time.Second))
+	if ctx.Err() != nil {
+		out.WriteString(", interrupted")
+	}
+	if len(lines) > 0 {
+		out.WriteString(":\n" + strings.Join(lines, "\n"))
+	}
+	return out.String(), nil
+}
+
+// lastLines returns the last n lines of s matching the pattern, which may
+// be nil to match all lines.
+func lastLines(s string, pattern *regexp.Regexp, n int) []string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if s == "" {
+		lines = nil
+	}
+	if pattern != nil {
+		var matching []string
+		for _, l := range lines {
+			if pattern.MatchString(l) {
+				matching = append(matching, l)
+			}
+		}
+		lines = matching
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
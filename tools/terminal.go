@@ -0,0 +1,257 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+// Terminals are tmux sessions, so interactive programs (REPLs, debuggers,
+// servers reading input) keep running between tool calls.
+
+var TerminalStartDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "terminal_start",
+		Description: "Start an interactive program in a new terminal, running in the background in the primary workspace root, and return its initial screen. Use this instead of run_command for programs that wait for input, such as REPLs and debuggers, then drive them with terminal_send.",
+		Parameters: objectParameters([]string{"name", "command"}, map[string]Property{
+			"name": {
+				Type:        api.PropertyType{"string"},
+				Description: "A short name for the terminal, letters, digits, - and _.",
+			},
+			"command": {
+				Type:        api.PropertyType{"string"},
+				Description: "The shell command to run in the terminal.",
+			},
+		}),
+	},
+	Function: TerminalStart,
+	Effect:   EffectExec,
+}
+
+var TerminalSendDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "terminal_send",
+		Description: "Type text into a terminal started with terminal_start, followed by Enter unless disabled, and return its screen after waiting for the program to react.",
+		Parameters: objectParameters([]string{"name", "text"}, map[string]Property{
+			"name": {
+				Type:        api.PropertyType{"string"},
+				Description: "The name of the terminal.",
+			},
+			"text": {
+				Type:        api.PropertyType{"string"},
+				Description: "The text to type. Send control keys with keys instead.",
+			},
+			"keys": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional tmux key names sent after the text, separated by spaces, e.g. C-c, C-d, Up, Tab or Escape.",
+			},
+			"enter": {
+				Type:        api.PropertyType{"boolean"},
+				Description: "Optional, whether to press Enter after the text, defaults to true.",
+			},
+			"wait_ms": {
+				Type:        api.PropertyType{"integer"},
+				Description: "Optional milliseconds to wait before capturing the screen, defaults to 500.",
+			},
+		}),
+	},
+	Function: TerminalSend,
+	Effect:   EffectExec,
+}
+
+var TerminalReadDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "terminal_read",
+		Description: "Return the screen of a terminal started with terminal_start, and optionally its scrollback.",
+		Parameters: objectParameters([]string{"name"}, map[string]Property{
+			"name": {
+				Type:        api.PropertyType{"string"},
+				Description: "The name of the terminal.",
+			},
+			"scrollback": {
+				Type:        api.PropertyType{"integer"},
+				Description: "Optional number of lines of scrollback to include above the screen.",
+			},
+		}),
+	},
+	Function: TerminalRead,
+}
+
+var TerminalStopDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "terminal_stop",
+		Description: "Stop a terminal started with terminal_start, killing the program in it.",
+		Parameters: objectParameters([]string{"name"}, map[string]Property{
+			"name": {
+				Type:        api.PropertyType{"string"},
+				Description: "The name of the terminal.",
+			},
+		}),
+	},
+	Function: TerminalStop,
+	Effect:   EffectExec,
+}
+
+const (
+	// terminals are tmux sessions named with this prefix
+	terminalPrefix = "dacs-"
+
+	terminalWidth, terminalHeight = 160, 48
+
+	defaultTerminalWait = 500 * time.Millisecond
+	maxTerminalWait     = 60 * time.Second
+	maxScrollback       = 2000
+)
+
+var terminalName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type TerminalStartInput struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+}
+
+func TerminalStart(ctx context.Context, input json.RawMessage) (string, error) {
+	terminalStartInput := TerminalStartInput{}
+	err := json.Unmarshal(input, &terminalStartInput)
+	if err != nil {
+		return "", err
+	}
+	if !terminalName.MatchString(terminalStartInput.Name) || terminalStartInput.Command == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	if DryRun(ctx) {
+		return fmt.Sprintf("dry run, the terminal was not started, it would run:\n%s", terminalStartInput.Command), nil
+	}
+	session := terminalPrefix + terminalStartInput.Name
+	dir := workspace.FromContext(ctx).Primary().Path
+	_, err = tmux(ctx, "new-session", "-d", "-s", session, "-c", dir, "-x", fmt.Sprint(terminalWidth), "-y", fmt.Sprint(terminalHeight), terminalStartInput.Command,
+		// keep the screen of programs that exit, until terminal_stop
+		";", "set-option", "-t", session, "remain-on-exit", "on")
+	if err != nil {
+		return "", err
+	}
+	time.Sleep(defaultTerminalWait)
+	return capturePane(ctx, session, 0)
+}
+
+type TerminalSendInput struct {
+	Name   string `json:"name"`
+	Text   string `json:"text"`
+	Keys   string `json:"keys,omitempty"`
+	Enter  *bool  `json:"enter,omitempty"`
+	WaitMS int    `json:"wait_ms,omitempty"`
+}
+
+func TerminalSend(ctx context.Context, input json.RawMessage) (string, error) {
+	terminalSendInput := TerminalSendInput{}
+	err := json.Unmarshal(input, &terminalSendInput)
+	if err != nil {
+		return "", err
+	}
+	if !terminalName.MatchString(terminalSendInput.Name) {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	if DryRun(ctx) {
+		return fmt.Sprintf("dry run, nothing was typed, it would type:\n%s", terminalSendInput.Text), nil
+	}
+	session := terminalPrefix + terminalSendInput.Name
+	if terminalSendInput.Text != "" {
+		// -l types the text literally instead of looking up key names
+		if _, err := tmux(ctx, "send-keys", "-t", session, "-l", terminalSendInput.Text); err != nil {
+			return "", err
+		}
+	}
+	if keys := strings.Fields(terminalSendInput.Keys); len(keys) > 0 {
+		if _, err := tmux(ctx, append([]string{"send-keys", "-t", session}, keys...)...); err != nil {
+			return "", err
+		}
+	}
+	if terminalSendInput.Enter == nil || *terminalSendInput.Enter {
+		if _, err := tmux(ctx, "send-keys", "-t", session, "Enter"); err != nil {
+			return "", err
+		}
+	}
+
+	wait := defaultTerminalWait
+	if terminalSendInput.WaitMS > 0 {
+		wait = min(time.Duration(terminalSendInput.WaitMS)*time.Millisecond, maxTerminalWait)
+	}
+	select {
+	case <-time.After(wait):
+	case <-ctx.Done():
+	}
+	return capturePane(context.WithoutCancel(ctx), session, 0)
+}
+
+type TerminalReadInput struct {
+	Name       string `json:"name"`
+	Scrollback int    `json:"scrollback,omitempty"`
+}
+
+func TerminalRead(ctx context.Context, input json.RawMessage) (string, error) {
+	terminalReadInput := TerminalReadInput{}
+	err := json.Unmarshal(input, &terminalReadInput)
+	if err != nil {
+		return "", err
+	}
+	if !terminalName.MatchString(terminalReadInput.Name) {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	return capturePane(ctx, terminalPrefix+terminalReadInput.Name, min(terminalReadInput.Scrollback, maxScrollback))
+}
+
+type TerminalStopInput struct {
+	Name string `json:"name"`
+}
+
+func TerminalStop(ctx context.Context, input json.RawMessage) (string, error) {
+	terminalStopInput := TerminalStopInput{}
+	err := json.Unmarshal(input, &terminalStopInput)
+	if err != nil {
+		return "", err
+	}
+	if !terminalName.MatchString(terminalStopInput.Name) {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	if DryRun(ctx) {
+		return "dry run, the terminal was not stopped", nil
+	}
+	if _, err := tmux(ctx, "kill-session", "-t", terminalPrefix+terminalStopInput.Name); err != nil {
+		return "", err
+	}
+	return "OK", nil
+}
+
+func capturePane(ctx context.Context, session string, scrollback int) (string, error) {
+	args := []string{"capture-pane", "-p", "-t", session}
+	if scrollback > 0 {
+		args = append(args, "-S", fmt.Sprint(-scrollback))
+	}
+	screen, err := tmux(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(screen, "\n"), nil
+}
+
+// tmux runs tmux through the primary root's FS, so terminals run where the
+// workspace is.
+func tmux(ctx context.Context, args ...string) (string, error) {
+	root := workspace.FromContext(ctx).Primary()
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = workspace.ShellQuote(arg)
+	}
+	out, err := root.FS.Command(ctx, root.Path, "tmux "+strings.Join(quoted, " ")).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("tmux %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
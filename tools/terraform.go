@@ -0,0 +1,224 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+var TerraformPlanDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "terraform_plan",
+		Description: "Run terraform plan in a directory, or read a saved plan, and summarize the resource changes: what would be created, updated, replaced and destroyed, with the attributes that change. Use this to verify infrastructure edits the way tests verify code.",
+		Parameters: objectParameters([]string{}, map[string]Property{
+			"path": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional relative path of the Terraform configuration directory, defaults to the working directory.",
+			},
+			"plan": {
+				Type:        api.PropertyType{"string"},
+				Description: "Optional relative path of a saved plan, a file written by terraform plan -out or its terraform show -json output, to summarize instead of planning.",
+			},
+		}),
+	},
+	Function: TerraformPlan,
+	Effect:   EffectExec,
+}
+
+type TerraformPlanInput struct {
+	Path string `json:"path,omitempty"`
+	Plan string `json:"plan,omitempty"`
+}
+
+const (
+	// separates the plan output from its JSON in the command output
+	planMarker = "--- dacs terraform plan ---"
+	// attributes listed for each changed resource
+	maxPlanAttributes = 10
+	// lines of a failed plan's output returned
+	maxPlanErrorLines = 60
+)
+
+func TerraformPlan(ctx context.Context, input json.RawMessage) (string, error) {
+	terraformPlanInput := TerraformPlanInput{}
+	err := json.Unmarshal(input, &terraformPlanInput)
+	if err != nil {
+		return "", err
+	}
+	ws := workspace.FromContext(ctx)
+
+	if terraformPlanInput.Plan != "" {
+		root, abs, err := ws.Resolve(terraformPlanInput.Plan)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasSuffix(abs, ".json") {
+			content, err := root.FS.ReadFile(abs)
+			if err != nil {
+				return "", err
+			}
+			return summarizePlan(content)
+		}
+		// a binary plan is rendered by terraform, in the configuration
+		// directory it was planned from
+		script := "terraform show -json -no-color " + workspace.ShellQuote(filepath.Base(abs))
+		out, err := root.FS.Command(ctx, filepath.Dir(abs), script).Output()
+		if err != nil {
+			return "", fmt.Errorf("reading the plan failed: %v\n%s", err, out)
+		}
+		return summarizePlan(out)
+	}
+
+	path := terraformPlanInput.Path
+	if path == "" {
+		path = "."
+	}
+	root, abs, err := ws.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	plan := "terraform plan -input=false -no-color -lock=false -out=\"$f\""
+	if DryRun(ctx) {
+		return fmt.Sprintf("dry run, terraform was not run, it would run in %s:\n%s", ws.Display(root, abs), plan), nil
+	}
+	script := fmt.Sprintf(`f=$(mktemp) || exit 1; %s; s=$?; echo %s; [ $s -eq 0 ] && terraform show -json -no-color "$f"; rm -f "$f"; exit $s`,
+		plan, workspace.ShellQuote(planMarker))
+	out, _ := root.FS.Command(ctx, abs, script).CombinedOutput()
+	planOutput, planJSON, ok := strings.Cut(string(out), planMarker+"\n")
+	if !ok || ctx.Err() != nil || strings.TrimSpace(planJSON) == "" {
+		lines := strings.Split(strings.TrimSpace(planOutput), "\n")
+		if len(lines) > maxPlanErrorLines {
+			lines = append([]string{"..."}, lines[len(lines)-maxPlanErrorLines:]...)
+		}
+		return "", fmt.Errorf("terraform plan failed:\n%s", strings.Join(lines, "\n"))
+	}
+	return summarizePlan([]byte(planJSON))
+}
+
+type terraformPlan struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions      []string       `json:"actions"`
+			Before       map[string]any `json:"before"`
+			After        map[string]any `json:"after"`
+			AfterUnknown map[string]any `json:"after_unknown"`
+		} `json:"change"`
+		ActionReason string `json:"action_reason"`
+	} `json:"resource_changes"`
+	OutputChanges map[string]struct {
+		Change struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"output_changes"`
+}
+
+// planActions are the kinds of change, in the order summarized, the most
+// destructive first.
+var planActions = []string{"destroy", "replace", "update", "create", "read"}
+
+func planAction(actions []string) string {
+	switch strings.Join(actions, ",") {
+	case "create":
+		return "create"
+	case "update":
+		return "update"
+	case "delete":
+		return "destroy"
+	case "delete,create", "create,delete":
+		return "replace"
+	case "read":
+		return "read"
+	}
+	return ""
+}
+
+func summarizePlan(content []byte) (string, error) {
+	var plan terraformPlan
+	if err := json.Unmarshal(content, &plan); err != nil {
+		return "", fmt.Errorf("invalid plan JSON: %v", err)
+	}
+	changes := map[string][]string{}
+	for _, rc := range plan.ResourceChanges {
+		action := planAction(rc.Change.Actions)
+		if action == "" {
+			continue
+		}
+		line := rc.Address
+		if action == "update" || action == "replace" {
+			if attrs := changedAttributes(rc.Change.Before, rc.Change.After, rc.Change.AfterUnknown); len(attrs) > 0 {
+				line += ": " + strings.Join(attrs, ", ")
+			}
+		}
+		if rc.ActionReason != "" {
+			line += " (" + strings.ReplaceAll(strings.ToLower(rc.ActionReason), "_", " ") + ")"
+		}
+		changes[action] = append(changes[action], line)
+	}
+
+	var b strings.Builder
+	var counts []string
+	for _, action := range planActions {
+		if n := len(changes[action]); n > 0 {
+			counts = append(counts, fmt.Sprintf("%d to %s", n, action))
+		}
+	}
+	if len(counts) == 0 {
+		b.WriteString("No changes, the infrastructure matches the configuration.\n")
+	} else {
+		fmt.Fprintf(&b, "Plan: %s.\n", strings.Join(counts, ", "))
+		if n := len(changes["destroy"]) + len(changes["replace"]); n > 0 {
+			fmt.Fprintf(&b, "Warning: %d resources would be destroyed or replaced.\n", n)
+		}
+	}
+	for _, action := range planActions {
+		for _, line := range changes[action] {
+			fmt.Fprintf(&b, "%s %s\n", action, line)
+		}
+	}
+
+	var outputs []string
+	for name, oc := range plan.OutputChanges {
+		if action := planAction(oc.Change.Actions); action != "" {
+			outputs = append(outputs, fmt.Sprintf("output %s: %s", name, action))
+		}
+	}
+	sort.Strings(outputs)
+	for _, line := range outputs {
+		b.WriteString(line + "\n")
+	}
+	return b.String(), nil
+}
+
+// changedAttributes returns the names of the top-level attributes that
+// differ, marking those only known after apply.
+func changedAttributes(before, after, unknown map[string]any) []string {
+	names := map[string]bool{}
+	for _, m := range []map[string]any{before, after, unknown} {
+		for name := range m {
+			names[name] = true
+		}
+	}
+	var attrs []string
+	for name := range names {
+		if isUnknown, _ := unknown[name].(bool); isUnknown {
+			attrs = append(attrs, name+" (known after apply)")
+		} else if !reflect.DeepEqual(before[name], after[name]) {
+			attrs = append(attrs, name)
+		}
+	}
+	slices.Sort(attrs)
+	if len(attrs) > maxPlanAttributes {
+		attrs = append(attrs[:maxPlanAttributes], fmt.Sprintf("%d more", len(attrs)-maxPlanAttributes))
+	}
+	return attrs
+}
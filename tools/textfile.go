@@ -0,0 +1,343 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/mschoch/dacs/workspace"
+)
+
+// textFile is a text file as the tools edit it, decoded with \n line
+// endings, and written back in its charset, byte order mark and line
+// endings. New files are written in those of the .editorconfig.
+type textFile struct {
+	root *workspace.Root
+	path string
+	// content is the text as read, "" for a new file
+	content string
+	exists  bool
+	charset string
+	crlf    bool
+	style   editorConfig
+}
+
+// openText reads the text file at the absolute path in the root, a file
+// that does not exist is opened as new.
+func openText(root *workspace.Root, path string) (*textFile, error) {
+	f := &textFile{root: root, path: path, style: loadEditorConfig(root, path)}
+	content, err := root.FS.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		f.charset = f.style.charset
+		f.crlf = f.style.endOfLine == "crlf"
+		return f, nil
+	} else if err != nil {
+		return nil, err
+	}
+	f.exists = true
+	f.content, f.charset, f.crlf = decodeText(content)
+	return f, nil
+}
+
+// write writes the text in the file's encoding, creating its directory.
+func (f *textFile) write(text string) error {
+	if f.style.insertFinalNewline && text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	data, err := encodeText(text, f.charset, f.crlf)
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", f.path, err)
+	}
+	if !f.exists {
+		if dir := filepath.Dir(f.path); dir != "." {
+			if err := f.root.FS.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+		}
+	}
+	return f.root.FS.WriteFile(f.path, data, 0644)
+}
+
+var (
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+)
+
+// decodeText returns the content as text with \n line endings, its charset
+// as named by EditorConfig, and whether its lines end in \r\n. Content that
+// is not UTF-8 and has no byte order mark is taken as latin1.
+func decodeText(content []byte) (text, charset string, crlf bool) {
+	switch {
+	case bytes.HasPrefix(content, utf8BOM):
+		text, charset = string(content[len(utf8BOM):]), "utf-8-bom"
+	case bytes.HasPrefix(content, utf16LEBOM):
+		text, charset = decodeUTF16(content[2:], binary.LittleEndian), "utf-16le"
+	case bytes.HasPrefix(content, utf16BEBOM):
+		text, charset = decodeUTF16(content[2:], binary.BigEndian), "utf-16be"
+	case utf8.Valid(content):
+		text, charset = string(content), "utf-8"
+	default:
+		runes := make([]rune, len(content))
+		for i, b := range content {
+			runes[i] = rune(b)
+		}
+		text, charset = string(runes), "latin1"
+	}
+	// by the majority, a file with mixed line endings is written with one
+	crlf = strings.Count(text, "\r\n") > strings.Count(text, "\n")/2
+	return strings.ReplaceAll(text, "\r\n", "\n"), charset, crlf
+}
+
+func decodeUTF16(content []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(content)/2)
+	for i := range units {
+		units[i] = order.Uint16(content[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// encodeText returns the text in the charset, with \r\n line endings if
+// crlf is set.
+func encodeText(text, charset string, crlf bool) ([]byte, error) {
+	if crlf {
+		text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+	}
+	switch charset {
+	case "utf-8-bom":
+		return append(bytes.Clone(utf8BOM), text...), nil
+	case "utf-16le", "utf-16be":
+		var order binary.AppendByteOrder = binary.LittleEndian
+		rv := bytes.Clone(utf16LEBOM)
+		if charset == "utf-16be" {
+			order, rv = binary.BigEndian, bytes.Clone(utf16BEBOM)
+		}
+		for _, u := range utf16.Encode([]rune(text)) {
+			rv = order.AppendUint16(rv, u)
+		}
+		return rv, nil
+	case "latin1":
+		rv := make([]byte, 0, len(text))
+		for _, r := range text {
+			if r > 0xff {
+				return nil, fmt.Errorf("%q cannot be written in latin1", r)
+			}
+			rv = append(rv, byte(r))
+		}
+		return rv, nil
+	}
+	return []byte(text), nil
+}
+
+// editorConfig are the EditorConfig properties of a file the tools write,
+// see https://editorconfig.org.
+type editorConfig struct {
+	indentStyle            string
+	indentSize             int
+	endOfLine              string
+	charset                string
+	insertFinalNewline     bool
+	trimTrailingWhitespace bool
+}
+
+// loadEditorConfig returns the properties of the .editorconfig files for
+// the absolute path, from its directory up to the one marked root or the
+// workspace root.
+func loadEditorConfig(root *workspace.Root, path string) editorConfig {
+	props := map[string]string{}
+	var dirs []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == root.Path || dir == filepath.Dir(dir) || !strings.HasPrefix(dir, root.Path) {
+			break
+		}
+	}
+	// the closest file applies last
+	var files []map[string]string
+	for _, dir := range dirs {
+		content, err := root.FS.ReadFile(filepath.Join(dir, ".editorconfig"))
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			continue
+		}
+		matched, isRoot := parseEditorConfig(string(content), filepath.ToSlash(rel))
+		files = append(files, matched)
+		if isRoot {
+			break
+		}
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		for k, v := range files[i] {
+			props[k] = v
+		}
+	}
+
+	rv := editorConfig{
+		indentStyle:            props["indent_style"],
+		endOfLine:              props["end_of_line"],
+		charset:                props["charset"],
+		insertFinalNewline:     props["insert_final_newline"] == "true",
+		trimTrailingWhitespace: props["trim_trailing_whitespace"] == "true",
+	}
+	size := props["indent_size"]
+	if size == "tab" || size == "" {
+		size = props["tab_width"]
+	}
+	rv.indentSize, _ = strconv.Atoi(size)
+	return rv
+}
+
+// parseEditorConfig returns the properties of the sections of the
+// .editorconfig matching the path relative to it, and whether it is marked
+// root.
+func parseEditorConfig(content, rel string) (props map[string]string, isRoot bool) {
+	props = map[string]string{}
+	matching, preamble := false, true
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[' && strings.HasSuffix(line, "]"):
+			preamble = false
+			re, err := editorConfigGlob(line[1 : len(line)-1])
+			matching = err == nil && re.MatchString(rel)
+		default:
+			k, v, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			k, v = strings.ToLower(strings.TrimSpace(k)), strings.ToLower(strings.TrimSpace(v))
+			if preamble && k == "root" {
+				isRoot = v == "true"
+			} else if matching {
+				props[k] = v
+			}
+		}
+	}
+	return props, isRoot
+}
+
+var numberRange = regexp.MustCompile(`^-?\d+\.\.-?\d+$`)
+
+// editorConfigGlob compiles a section name, a glob matching paths relative
+// to the .editorconfig, or file names in any directory when it has no /.
+func editorConfigGlob(glob string) (*regexp.Regexp, error) {
+	if strings.Contains(glob, "/") {
+		glob = strings.TrimPrefix(glob, "/")
+	} else {
+		glob = "**/" + glob
+	}
+	var sb strings.Builder
+	sb.WriteString("^")
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		case c == '{':
+			end := strings.IndexByte(glob[i:], '}')
+			if end >= 0 && numberRange.MatchString(glob[i+1:i+end]) {
+				// a range of numbers
+				sb.WriteString(`-?\d+`)
+				i += end
+				continue
+			}
+			braces++
+			sb.WriteString("(?:")
+		case c == '}' && braces > 0:
+			braces--
+			sb.WriteString(")")
+		case c == ',' && braces > 0:
+			sb.WriteString("|")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// conform indents the lines of text written by the model with the indent
+// style and removes trailing whitespace, if the .editorconfig says so. When
+// the text is part of a line, its first line is not indented unless it
+// starts a line, and its last not trimmed unless it ends one.
+func (ec editorConfig) conform(text string, startsLine, endsLine bool) string {
+	if ec.indentStyle == "" && !ec.trimTrailingWhitespace {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if ec.trimTrailingWhitespace && (i < len(lines)-1 || endsLine) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if ec.indentSize > 0 && (ec.indentStyle == "tab" || ec.indentStyle == "space") && (i > 0 || startsLine) {
+			body := strings.TrimLeft(line, " \t")
+			width := 0
+			for _, c := range line[:len(line)-len(body)] {
+				if c == '\t' {
+					width += ec.indentSize - width%ec.indentSize
+				} else {
+					width++
+				}
+			}
+			indent := strings.Repeat(" ", width)
+			if ec.indentStyle == "tab" {
+				indent = strings.Repeat("\t", width/ec.indentSize) + strings.Repeat(" ", width%ec.indentSize)
+			}
+			line = indent + body
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wholeLines reports whether every occurrence of s in text starts a line,
+// and whether every one ends a line.
+func wholeLines(text, s string) (starts, ends bool) {
+	starts, ends = true, true
+	for i := 0; s != ""; {
+		j := strings.Index(text[i:], s)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(s)
+		starts = starts && (start == 0 || text[start-1] == '\n')
+		ends = ends && (end == len(text) || text[end] == '\n' || strings.HasSuffix(s, "\n"))
+		i = end
+	}
+	return starts, ends
+}
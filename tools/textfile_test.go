@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"bytes"
+	"maps"
+	"testing"
+)
+
+func TestEditorConfigGlob(t *testing.T) {
+	for _, test := range []struct {
+		glob, rel string
+		want      bool
+	}{
+		{"*", "main.go", true},
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/dacs/main.go", true},
+		{"*.go", "main.gox", false},
+		{"/*.go", "main.go", true},
+		{"/*.go", "cmd/main.go", false},
+		{"src/*.go", "src/a.go", true},
+		{"src/*.go", "src/sub/a.go", false},
+		{"src/**.go", "src/sub/a.go", true},
+		{"**/test/*.go", "test/a.go", true},
+		{"**/test/*.go", "a/b/test/a.go", true},
+		{"?.md", "a.md", true},
+		{"?.md", "ab.md", false},
+		{"*.{js,ts}", "a.ts", true},
+		{"*.{js,ts}", "a.css", false},
+		{"file{1..3}.txt", "file2.txt", true},
+		{"file{1..3}.txt", "filex.txt", false},
+		{"[ab].c", "b.c", true},
+		{"[!ab].c", "b.c", false},
+		{"[!ab].c", "x.c", true},
+		{`\*.c`, "*.c", true},
+		{`\*.c`, "a.c", false},
+		{"a+b.txt", "a+b.txt", true},
+		{"a+b.txt", "aab.txt", false},
+	} {
+		re, err := editorConfigGlob(test.glob)
+		if err != nil {
+			t.Errorf("editorConfigGlob(%q): %v", test.glob, err)
+			continue
+		}
+		if got := re.MatchString(test.rel); got != test.want {
+			t.Errorf("%q matching %q = %t, want %t", test.glob, test.rel, got, test.want)
+		}
+	}
+}
+
+func TestParseEditorConfig(t *testing.T) {
+	const content = `# top-most
+root = true
+
+[*]
+indent_style = space
+indent_size = 4
+end_of_line = LF
+
+; Go is indented with tabs
+[*.go]
+indent_style = tab
+
+[Makefile]
+indent_style = tab
+not a property
+`
+	for _, test := range []struct {
+		rel  string
+		want map[string]string
+	}{
+		{"a.py", map[string]string{"indent_style": "space", "indent_size": "4", "end_of_line": "lf"}},
+		{"cmd/a.go", map[string]string{"indent_style": "tab", "indent_size": "4", "end_of_line": "lf"}},
+		{"Makefile", map[string]string{"indent_style": "tab", "indent_size": "4", "end_of_line": "lf"}},
+	} {
+		got, isRoot := parseEditorConfig(content, test.rel)
+		if !isRoot {
+			t.Errorf("%s: not root", test.rel)
+		}
+		if !maps.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.rel, got, test.want)
+		}
+	}
+
+	// root is only read before the first section
+	if _, isRoot := parseEditorConfig("[*]\nroot = true\n", "a.go"); isRoot {
+		t.Errorf("root in a section is root")
+	}
+}
+
+func TestDecodeEncodeText(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content []byte
+		text    string
+		charset string
+		crlf    bool
+	}{
+		{"utf-8", []byte("a\nb\n"), "a\nb\n", "utf-8", false},
+		{"crlf", []byte("a\r\nb\r\n"), "a\nb\n", "utf-8", true},
+		{"utf-8 bom", []byte("\xef\xbb\xbfa\n"), "a\n", "utf-8-bom", false},
+		{"utf-16le", []byte("\xff\xfeh\x00\xe9\x00\n\x00"), "hé\n", "utf-16le", false},
+		{"utf-16be", []byte("\xfe\xff\x00h\x00\xe9\x00\r\x00\n"), "hé\n", "utf-16be", true},
+		{"latin1", []byte("caf\xe9\n"), "café\n", "latin1", false},
+		{"empty", []byte{}, "", "utf-8", false},
+	} {
+		text, charset, crlf := decodeText(test.content)
+		if text != test.text || charset != test.charset || crlf != test.crlf {
+			t.Errorf("%s: decodeText = %q, %s, %t, want %q, %s, %t", test.name, text, charset, crlf, test.text, test.charset, test.crlf)
+			continue
+		}
+		content, err := encodeText(text, charset, crlf)
+		if err != nil {
+			t.Errorf("%s: encodeText: %v", test.name, err)
+		} else if !bytes.Equal(content, test.content) {
+			t.Errorf("%s: encodeText = %q, want %q", test.name, content, test.content)
+		}
+	}
+
+	// by the majority of the line endings
+	if _, _, crlf := decodeText([]byte("a\r\nb\r\nc\n")); !crlf {
+		t.Errorf("mostly crlf is not crlf")
+	}
+	if _, _, crlf := decodeText([]byte("a\r\nb\nc\n")); crlf {
+		t.Errorf("mostly lf is crlf")
+	}
+
+	if _, err := encodeText("€", "latin1", false); err == nil {
+		t.Errorf("encoding € in latin1 succeeded")
+	}
+}
+
+func TestConform(t *testing.T) {
+	tabs := editorConfig{indentStyle: "tab", indentSize: 4}
+	spaces := editorConfig{indentStyle: "space", indentSize: 2}
+	trim := editorConfig{trimTrailingWhitespace: true}
+	for _, test := range []struct {
+		name                 string
+		ec                   editorConfig
+		text                 string
+		startsLine, endsLine bool
+		want                 string
+	}{
+		{"unset", editorConfig{}, "  a  \n\tb", true, true, "  a  \n\tb"},
+		{"to tabs", tabs, "    a\n        b\n      c", true, true, "\ta\n\t\tb\n\t  c"},
+		{"to tabs mid line", tabs, "    a\n    b", false, true, "    a\n\tb"},
+		{"to spaces", spaces, "\ta\n\t\tb", true, true, "  a\n    b"},
+		{"mixed to spaces", spaces, " \tb", true, true, "  b"},
+		{"no size", editorConfig{indentStyle: "tab"}, "    a", true, true, "    a"},
+		{"trim", trim, "a  \nb \t", true, true, "a\nb"},
+		{"trim mid line", trim, "a  \nb  ", true, false, "a\nb  "},
+	} {
+		if got := test.ec.conform(test.text, test.startsLine, test.endsLine); got != test.want {
+			t.Errorf("%s: conform(%q) = %q, want %q", test.name, test.text, got, test.want)
+		}
+	}
+}
+
+func TestWholeLines(t *testing.T) {
+	for _, test := range []struct {
+		text, s      string
+		starts, ends bool
+	}{
+		{"a\nfoo\nb", "foo", true, true},
+		{"foo", "foo", true, true},
+		{"xfoo\n", "foo", false, true},
+		{"foo bar", "foo", true, false},
+		{"foo\nfoo bar", "foo", true, false},
+		{"a\nfoo\nb", "foo\n", true, true},
+		{"abc", "", true, true},
+	} {
+		starts, ends := wholeLines(test.text, test.s)
+		if starts != test.starts || ends != test.ends {
+			t.Errorf("wholeLines(%q, %q) = %t, %t, want %t, %t", test.text, test.s, starts, ends, test.starts, test.ends)
+		}
+	}
+}
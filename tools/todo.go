@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/mschoch/dacs/session"
+)
+
+var TodoWriteDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name: "todo_write",
+		Description: `Write the plan for a multi-step task as a list of todos, replacing the previous list.
+
+Use this at the start of any task with more than a couple of steps, and again whenever a step is started or finished, so the plan always reflects the current state. Only one todo should be in_progress at a time.`,
+		Parameters: objectParameters([]string{"todos"}, map[string]Property{
+			"todos": {
+				Type:        api.PropertyType{"array"},
+				Description: "The complete list of todos, in order.",
+				Items: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"content": map[string]any{
+							"type":        "string",
+							"description": "What needs to be done.",
+						},
+						"status": map[string]any{
+							"type": "string",
+							"enum": []string{session.TodoPending, session.TodoInProgress, session.TodoDone},
+						},
+					},
+					"required": []string{"content", "status"},
+				},
+			},
+		}),
+	},
+	Function: TodoWrite,
+}
+
+var TodoReadDefinition = Tool{
+	Definition: api.ToolFunction{
+		Name:        "todo_read",
+		Description: "Read the current list of todos for the task, with their status.",
+		Parameters:  objectParameters(nil, map[string]Property{}),
+	},
+	Function: TodoRead,
+}
+
+type TodoWriteInput struct {
+	Todos []session.Todo `json:"todos"`
+}
+
+func TodoWrite(ctx context.Context, input json.RawMessage) (string, error) {
+	todoWriteInput := TodoWriteInput{}
+	err := json.Unmarshal(input, &todoWriteInput)
+	if err != nil {
+		return "", err
+	}
+	s, ok := session.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("no session to keep todos in")
+	}
+
+	for n, t := range todoWriteInput.Todos {
+		t.Content = strings.TrimSpace(t.Content)
+		if t.Content == "" {
+			return "", fmt.Errorf("todo %d has no content", n+1)
+		}
+		switch t.Status {
+		case session.TodoPending, session.TodoInProgress, session.TodoDone:
+		case "":
+			t.Status = session.TodoPending
+		default:
+			return "", fmt.Errorf("todo %d has invalid status %q", n+1, t.Status)
+		}
+		todoWriteInput.Todos[n] = t
+	}
+	s.Todos = todoWriteInput.Todos
+
+	checklist := session.FormatTodos(s.Todos)
+	fmt.Fprintf(Output(ctx), "%s\n", checklist)
+	return checklist, nil
+}
+
+func TodoRead(ctx context.Context, _ json.RawMessage) (string, error) {
+	s, ok := session.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("no session to keep todos in")
+	}
+	return session.FormatTodos(s.Todos), nil
+}
@@ -0,0 +1,182 @@
+// Package tools contains the tools the agent exposes to the model.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/ollama/ollama/api"
+)
+
+type Tool struct {
+	Definition api.ToolFunction
+	Function   func(ctx context.Context, input json.RawMessage) (string, error)
+	// Effect is what the tool does outside of the session, the zero value
+	// is none. Tools with an effect must honor DryRun.
+	Effect Effect
+	// Namespace is where the tool comes from, empty for built-in tools,
+	// see Qualify.
+	Namespace string
+}
+
+// Effect classifies the side effects of a tool, for auditing.
+type Effect string
+
+const (
+	EffectNone    Effect = ""
+	EffectWrite   Effect = "write"
+	EffectDelete  Effect = "delete"
+	EffectExec    Effect = "exec"
+	EffectNetwork Effect = "network"
+)
+
+type outputKey struct{}
+
+// WithOutput returns a context that long-running tools stream their
+// incremental output to while they execute.
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, w)
+}
+
+// Output returns the writer set with WithOutput, or io.Discard.
+func Output(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(outputKey{}).(io.Writer); ok {
+		return w
+	}
+	return io.Discard
+}
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context in which tools with an effect report what
+// they would do rather than doing it.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+func DryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+type checkPathKey struct{}
+
+// WithCheckPath returns a context in which tools accessing paths they find
+// themselves, rather than those passed to them, have check deny them.
+func WithCheckPath(ctx context.Context, check func(path string) error) context.Context {
+	return context.WithValue(ctx, checkPathKey{}, check)
+}
+
+// CheckPath returns why the path may not be accessed, if so.
+func CheckPath(ctx context.Context, path string) error {
+	if check, ok := ctx.Value(checkPathKey{}).(func(string) error); ok {
+		return check(path)
+	}
+	return nil
+}
+
+type approveKey struct{}
+
+// WithApprove returns a context in which tools changing many files at once
+// have approve check the paths and their diff first, it returns why they
+// may not be changed, if so.
+func WithApprove(ctx context.Context, approve func(paths []string, diff string) error) context.Context {
+	return context.WithValue(ctx, approveKey{}, approve)
+}
+
+// Approve asks for the changes to the paths to be approved, they are when
+// no approve func was set.
+func Approve(ctx context.Context, paths []string, diff string) error {
+	if approve, ok := ctx.Value(approveKey{}).(func([]string, string) error); ok {
+		return approve(paths, diff)
+	}
+	return nil
+}
+
+// Property and Parameters are aliases of the anonymous structs used by
+// api.ToolFunction, so they can be assigned to it directly.
+type Property = struct {
+	Type        api.PropertyType `json:"type"`
+	Items       any              `json:"items,omitempty"`
+	Description string           `json:"description"`
+	Enum        []any            `json:"enum,omitempty"`
+}
+
+type Parameters = struct {
+	Type       string              `json:"type"`
+	Defs       any                 `json:"$defs,omitempty"`
+	Items      any                 `json:"items,omitempty"`
+	Required   []string            `json:"required"`
+	Properties map[string]Property `json:"properties"`
+}
+
+func objectParameters(required []string, properties map[string]Property) Parameters {
+	if required == nil {
+		required = []string{}
+	}
+	return Parameters{
+		Type:       "object",
+		Required:   required,
+		Properties: properties,
+	}
+}
+
+func Default() []Tool {
+	return []Tool{
+		ReadFileDefinition,
+		ListFilesDefinition,
+		EditFileDefinition,
+		ReplaceInFilesDefinition,
+		GenerateFromTemplateDefinition,
+		RunCommandDefinition,
+		TodoWriteDefinition,
+		TodoReadDefinition,
+		WriteNoteDefinition,
+		ReadNotesDefinition,
+		RecallResultDefinition,
+		GetCapabilitiesDefinition,
+		ListDependenciesDefinition,
+		DependencyInfoDefinition,
+		AnalyzeTraceDefinition,
+		TailFileDefinition,
+		TerminalStartDefinition,
+		TerminalSendDefinition,
+		TerminalReadDefinition,
+		TerminalStopDefinition,
+		DebugStartDefinition,
+		DebugBreakpointDefinition,
+		DebugCommandDefinition,
+		DebugInspectDefinition,
+		DebugStopDefinition,
+		TestCoverageDefinition,
+		ProfileBenchmarkDefinition,
+		ListTasksDefinition,
+		InspectDataDefinition,
+		ListArchiveDefinition,
+		ExtractArchiveDefinition,
+		CreateArchiveDefinition,
+		ReadDocumentDefinition,
+		ReadNotebookDefinition,
+		EditNotebookDefinition,
+		TerraformPlanDefinition,
+		OpenAPIDefinition,
+		ProtoSchemaDefinition,
+		ProtoGenerateDefinition,
+	}
+}
+
+// Select returns the tools with the names, in the order given.
+func Select(toolset []Tool, names []string) ([]Tool, error) {
+	var rv []Tool
+	for _, name := range names {
+		i := slices.IndexFunc(toolset, func(t Tool) bool { return t.Definition.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown tool %q", name)
+		}
+		rv = append(rv, toolset[i])
+	}
+	return rv, nil
+}
@@ -0,0 +1,63 @@
+package voice
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Recording is microphone input being written to a 16kHz mono WAV file,
+// the format whisper expects, by arecord (ALSA) or sox's rec.
+type Recording struct {
+	Path string
+	cmd  *exec.Cmd
+}
+
+// Record starts recording to a temporary file, until Stop is called.
+func Record() (*Recording, error) {
+	dir, err := os.MkdirTemp("", "dacs-voice")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "input.wav")
+
+	var cmd *exec.Cmd
+	switch {
+	case available("arecord"):
+		cmd = exec.Command("arecord", "-q", "-f", "S16_LE", "-r", "16000", "-c", "1", path)
+	case available("rec"):
+		cmd = exec.Command("rec", "-q", "-r", "16000", "-c", "1", "-b", "16", path)
+	default:
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("no recorder found, install arecord (alsa-utils) or rec (sox)")
+	}
+	if err = cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &Recording{Path: path, cmd: cmd}, nil
+}
+
+// Stop ends the recording, letting the recorder finish writing the file.
+func (r *Recording) Stop() error {
+	// both recorders finalize the file on an interrupt
+	if err := r.cmd.Process.Signal(os.Interrupt); err != nil {
+		return err
+	}
+	_ = r.cmd.Wait()
+	if _, err := os.Stat(r.Path); err != nil {
+		return fmt.Errorf("nothing was recorded: %v", err)
+	}
+	return nil
+}
+
+// Remove deletes the recorded file.
+func (r *Recording) Remove() error {
+	return os.RemoveAll(filepath.Dir(r.Path))
+}
+
+func available(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
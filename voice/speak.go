@@ -0,0 +1,161 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/mschoch/dacs/i18n"
+)
+
+// Speaker reads text aloud, with an OpenAI compatible /v1/audio/speech
+// endpoint when URL is set, otherwise with a local engine (say, espeak-ng,
+// espeak or spd-say).
+type Speaker struct {
+	URL   string
+	Model string
+	Voice string
+	// APIKey, when set, is sent as a bearer token.
+	APIKey string
+	Client *http.Client
+
+	m      sync.Mutex
+	cancel context.CancelFunc
+}
+
+func NewSpeaker(url, model, voice string) *Speaker {
+	return &Speaker{
+		URL:    url,
+		Model:  model,
+		Voice:  voice,
+		Client: http.DefaultClient,
+	}
+}
+
+// Speak says the markdown text in the background, interrupting anything
+// still being said. Code blocks and formatting are not read out.
+func (s *Speaker) Speak(markdown string) {
+	text := speakable(markdown)
+	if text == "" {
+		return
+	}
+
+	s.m.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.m.Unlock()
+
+	go func() {
+		defer cancel()
+		err := s.say(ctx, text)
+		if err != nil && ctx.Err() == nil {
+			fmt.Fprint(os.Stderr, i18n.T("Error: speaking: %s\n", err.Error()))
+		}
+	}()
+}
+
+// Stop interrupts anything being said.
+func (s *Speaker) Stop() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+func (s *Speaker) say(ctx context.Context, text string) error {
+	if s.URL == "" {
+		for _, engine := range [][]string{{"say"}, {"espeak-ng"}, {"espeak"}, {"spd-say", "-w"}} {
+			if available(engine[0]) {
+				return exec.CommandContext(ctx, engine[0], append(engine[1:], text)...).Run()
+			}
+		}
+		return fmt.Errorf("no speech engine found, install espeak-ng or set tts_url")
+	}
+
+	audio, err := s.synthesize(ctx, text)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp("", "dacs-speech-*.wav")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(audio)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return play(ctx, f.Name())
+}
+
+func (s *Speaker) synthesize(ctx context.Context, text string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{
+		"model":           s.Model,
+		"voice":           s.Voice,
+		"input":           text,
+		"response_format": "wav",
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("speech synthesis failed: %s: %s", resp.Status, strings.TrimSpace(string(buf)))
+	}
+	return buf, nil
+}
+
+func play(ctx context.Context, path string) error {
+	for _, player := range [][]string{{"afplay"}, {"paplay"}, {"aplay", "-q"}, {"ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet"}} {
+		if available(player[0]) {
+			return exec.CommandContext(ctx, player[0], append(player[1:], path)...).Run()
+		}
+	}
+	return fmt.Errorf("no audio player found, install aplay (alsa-utils) or ffplay")
+}
+
+var (
+	codeBlockRe = regexp.MustCompile("(?s)(```|~~~).*?(```|~~~|$)")
+	linkRe      = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markupRe    = regexp.MustCompile("[*_`#>|]+")
+)
+
+// speakable strips the parts of markdown that should not be read out.
+func speakable(markdown string) string {
+	s := codeBlockRe.ReplaceAllString(markdown, " ")
+	s = linkRe.ReplaceAllString(s, "$1")
+	s = markupRe.ReplaceAllString(s, "")
+	return strings.Join(strings.Fields(s), " ")
+}
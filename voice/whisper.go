@@ -0,0 +1,91 @@
+// Package voice records speech from the microphone and transcribes it with
+// a whisper server.
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Transcriber sends recordings to a whisper.cpp server (its /inference
+// endpoint) or an OpenAI compatible /v1/audio/transcriptions endpoint.
+type Transcriber struct {
+	URL string
+	// Model is sent as the model field, required by OpenAI compatible
+	// endpoints and ignored by whisper.cpp.
+	Model string
+	// APIKey, when set, is sent as a bearer token.
+	APIKey string
+	Client *http.Client
+}
+
+func NewTranscriber(url, model string) *Transcriber {
+	return &Transcriber{
+		URL:    url,
+		Model:  model,
+		Client: http.DefaultClient,
+	}
+}
+
+// Transcribe returns the text spoken in the audio file at path.
+func (t *Transcriber) Transcribe(ctx context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(part, f); err != nil {
+		return "", err
+	}
+	if t.Model != "" {
+		_ = w.WriteField("model", t.Model)
+	}
+	_ = w.WriteField("response_format", "json")
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if t.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	}
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("transcription failed: %s: %s", resp.Status, strings.TrimSpace(string(buf)))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err = json.Unmarshal(buf, &result); err != nil {
+		return "", fmt.Errorf("error parsing transcription: %v", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
@@ -0,0 +1,164 @@
+// Package watch polls a directory tree for changes to files matching a
+// pattern.
+package watch
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const DefaultInterval = 2 * time.Second
+
+type Event struct {
+	// Path is relative to the watched root.
+	Path string
+	// Op is "created" or "modified".
+	Op string
+}
+
+type Watcher struct {
+	Root string
+	// Pattern is matched against the path relative to Root, or when it
+	// has no separator against the file name, see filepath.Match.
+	Pattern  string
+	Interval time.Duration
+}
+
+func New(root, pattern string) *Watcher {
+	return &Watcher{
+		Root:     root,
+		Pattern:  pattern,
+		Interval: DefaultInterval,
+	}
+}
+
+type stat struct {
+	size    int64
+	modTime time.Time
+}
+
+// Run calls fn for every change to a matching file until the context is
+// canceled. A change is reported once the file stops changing for an
+// interval, so a file being written is reported once. Changes made while
+// fn runs, e.g. by the agent it started, are not reported.
+func (w *Watcher) Run(ctx context.Context, fn func(ctx context.Context, ev Event) error) error {
+	prev, err := w.snapshot()
+	if err != nil {
+		return err
+	}
+	pending := map[string]string{}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.Interval):
+		}
+
+		cur, err := w.snapshot()
+		if err != nil {
+			return err
+		}
+		changed := map[string]string{}
+		for path, s := range cur {
+			if old, ok := prev[path]; !ok {
+				changed[path] = "created"
+			} else if old != s {
+				changed[path] = "modified"
+			}
+		}
+
+		var settled []Event
+		for path, op := range pending {
+			if _, still := changed[path]; !still {
+				if _, exists := cur[path]; exists {
+					settled = append(settled, Event{Path: path, Op: op})
+				}
+				delete(pending, path)
+			}
+		}
+		for path, op := range changed {
+			if first, ok := pending[path]; ok && first == "created" {
+				op = first
+			}
+			pending[path] = op
+		}
+		prev = cur
+
+		if len(settled) == 0 {
+			continue
+		}
+		for _, ev := range settled {
+			if err = fn(ctx, ev); err != nil {
+				return err
+			}
+		}
+		if prev, err = w.snapshot(); err != nil {
+			return err
+		}
+		clear(pending)
+	}
+}
+
+func (w *Watcher) match(rel string) bool {
+	if ok, _ := filepath.Match(w.Pattern, rel); ok {
+		return true
+	}
+	if !strings.ContainsRune(w.Pattern, filepath.Separator) {
+		ok, _ := filepath.Match(w.Pattern, filepath.Base(rel))
+		return ok
+	}
+	return false
+}
+
+func (w *Watcher) snapshot() (map[string]stat, error) {
+	rv := map[string]stat{}
+	err := filepath.WalkDir(w.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// files may disappear while walking
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != w.Root && (name == ".git" || name == "vendor" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(w.Root, path)
+		if err != nil || !w.match(rel) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		rv[rel] = stat{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return rv, err
+}
+
+// Tail returns up to the last n bytes of the file at path.
+func Tail(path string, n int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > n {
+		if _, err = f.Seek(-n, io.SeekEnd); err != nil {
+			return "", err
+		}
+	}
+	buf, err := io.ReadAll(f)
+	return string(buf), err
+}
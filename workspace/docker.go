@@ -0,0 +1,37 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os/exec"
+)
+
+// dockerFS runs the tools inside a running container with docker exec.
+type dockerFS struct {
+	*remoteFS
+}
+
+func NewDocker(container string) FS {
+	return &dockerFS{
+		remoteFS: &remoteFS{
+			name: "docker " + container,
+			command: func(ctx context.Context, script string, stdin bool) *exec.Cmd {
+				args := []string{"exec"}
+				if stdin {
+					args = append(args, "-i")
+				}
+				args = append(args, container, "sh", "-c", script)
+				return exec.CommandContext(ctx, "docker", args...)
+			},
+		},
+	}
+}
+
+// WriteFile streams the content through docker exec as the container's
+// user, so the files keep their owner, and those existing their mode.
+func (d *dockerFS) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	p := ShellQuote(path)
+	_, err := d.run(fmt.Sprintf("if [ -e %[1]s ]; then cat > %[1]s; else cat > %[1]s && chmod %[2]o %[1]s; fi", p, perm), data)
+	return err
+}
@@ -0,0 +1,137 @@
+package workspace
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FS is where a root's files live and its commands run, so that the tools
+// work the same on the local machine and on remote backends.
+type FS interface {
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, data []byte, perm fs.FileMode) error
+	MkdirAll(path string, perm fs.FileMode) error
+	// Walk returns every path below dir, relative to it, directories
+	// have a trailing slash.
+	Walk(dir string) ([]string, error)
+	// List returns the paths below dir down to depth levels, as Walk does,
+	// sorted. Hidden and vendored directories below dir are listed but not
+	// descended into.
+	List(dir string, depth int) ([]string, error)
+	// RealPath resolves symlinks in the longest existing prefix of path.
+	RealPath(path string) (string, error)
+	// Command returns a command running script with sh, in dir.
+	Command(ctx context.Context, dir, script string) *exec.Cmd
+}
+
+type localFS struct{}
+
+var Local FS = localFS{}
+
+func (localFS) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (localFS) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(path, data, perm)
+}
+
+func (localFS) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (localFS) Walk(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if relPath != "." {
+			if info.IsDir() {
+				files = append(files, relPath+"/")
+			} else {
+				files = append(files, relPath)
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// listWorkers is how many directories localFS.List reads at once
+const listWorkers = 16
+
+func (localFS) List(dir string, depth int) ([]string, error) {
+	var (
+		m     sync.Mutex
+		files []string
+		errs  []error
+	)
+	sem := make(chan struct{}, listWorkers)
+	// the directories of a level are read at once, then those of the next
+	level := []string{""}
+	for d := 0; d < depth && len(level) > 0; d++ {
+		var next []string
+		var wg sync.WaitGroup
+		for _, rel := range level {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				entries, err := os.ReadDir(filepath.Join(dir, rel))
+				m.Lock()
+				defer m.Unlock()
+				if err != nil {
+					errs = append(errs, err)
+					return
+				}
+				for _, e := range entries {
+					path := filepath.Join(rel, e.Name())
+					if !e.IsDir() {
+						files = append(files, path)
+						continue
+					}
+					files = append(files, path+string(filepath.Separator))
+					if !SkipDir(e.Name()) {
+						next = append(next, path)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		level = next
+	}
+	if len(files) == 0 && len(errs) > 0 {
+		return nil, errs[0]
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// SkipDir reports whether a directory of the name is hidden or vendored,
+// such as .git or node_modules, and not worth descending into.
+func SkipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules"
+}
+
+func (localFS) RealPath(path string) (string, error) {
+	return evalExisting(path), nil
+}
+
+func (localFS) Command(ctx context.Context, dir, script string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "sh", "-c", script)
+	cmd.Dir = dir
+	return cmd
+}
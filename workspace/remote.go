@@ -0,0 +1,102 @@
+package workspace
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// remoteFS implements FS with POSIX shell utilities run on another
+// machine, or in a container, by the command func.
+type remoteFS struct {
+	name    string
+	command func(ctx context.Context, script string, stdin bool) *exec.Cmd
+}
+
+func (r *remoteFS) run(script string, stdin []byte) ([]byte, error) {
+	cmd := r.command(context.Background(), script, stdin != nil)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "No such file or directory") {
+			return nil, fmt.Errorf("%s: %w", msg, fs.ErrNotExist)
+		}
+		return nil, fmt.Errorf("%s: %v: %s", r.name, err, msg)
+	}
+	return out, nil
+}
+
+func (r *remoteFS) ReadFile(path string) ([]byte, error) {
+	return r.run("cat -- "+ShellQuote(path), nil)
+}
+
+func (r *remoteFS) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	_, err := r.run(fmt.Sprintf("cat > %s && chmod %o %s", ShellQuote(path), perm, ShellQuote(path)), data)
+	return err
+}
+
+func (r *remoteFS) MkdirAll(path string, perm fs.FileMode) error {
+	_, err := r.run(fmt.Sprintf("mkdir -p -m %o -- %s", perm, ShellQuote(path)), nil)
+	return err
+}
+
+func (r *remoteFS) Walk(dir string) ([]string, error) {
+	out, err := r.run(fmt.Sprintf("find %s -mindepth 1 -printf '%%y %%P\\n'", ShellQuote(dir)), nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseFind(out), nil
+}
+
+func (r *remoteFS) List(dir string, depth int) ([]string, error) {
+	out, err := r.run(fmt.Sprintf(`find %s -mindepth 1 -maxdepth %d \( -type d \( -name '.*' -o -name vendor -o -name node_modules \) -prune -printf '%%y %%P\n' \) -o -printf '%%y %%P\n'`,
+		ShellQuote(dir), depth), nil)
+	if err != nil {
+		return nil, err
+	}
+	files := parseFind(out)
+	slices.Sort(files)
+	return files, nil
+}
+
+func (r *remoteFS) RealPath(path string) (string, error) {
+	out, err := r.run("realpath -m -- "+ShellQuote(path), nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (r *remoteFS) Command(ctx context.Context, dir, script string) *exec.Cmd {
+	return r.command(ctx, fmt.Sprintf("cd %s && sh -c %s", ShellQuote(dir), ShellQuote(script)), false)
+}
+
+// parseFind parses the output of find -printf '%y %P\n'.
+func parseFind(out []byte) []string {
+	var rv []string
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		kind, path, ok := strings.Cut(line, " ")
+		if !ok || path == "" {
+			continue
+		}
+		if kind == "d" {
+			path += "/"
+		}
+		rv = append(rv, path)
+	}
+	return rv
+}
+
+// ShellQuote quotes s as a single sh word.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
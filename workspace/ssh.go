@@ -0,0 +1,23 @@
+package workspace
+
+import (
+	"context"
+	"os/exec"
+)
+
+// NewSSH returns an FS on a remote host, accessed through the ssh client
+// so the user's ssh config, agent and known hosts apply as usual.
+// Connections are multiplexed to avoid a handshake for every tool call.
+func NewSSH(host string) FS {
+	return &remoteFS{
+		name: "ssh " + host,
+		command: func(ctx context.Context, script string, _ bool) *exec.Cmd {
+			return exec.CommandContext(ctx, "ssh",
+				"-o", "BatchMode=yes",
+				"-o", "ControlMaster=auto",
+				"-o", "ControlPath=~/.ssh/dacs-%r@%h:%p",
+				"-o", "ControlPersist=60",
+				host, "--", script)
+		},
+	}
+}
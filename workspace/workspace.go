@@ -0,0 +1,190 @@
+// Package workspace maps the paths used by the tools onto one or more root
+// directories, and keeps the tools from reaching outside of them.
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mschoch/dacs/config"
+)
+
+type Root struct {
+	Name string
+	// Path is absolute, with symlinks resolved
+	Path string
+	FS   FS
+}
+
+type Workspace struct {
+	Roots []*Root
+}
+
+// New returns a workspace for the configured roots, or for the working
+// directory when there are none.
+func New(roots []config.Root) (*Workspace, error) {
+	if len(roots) == 0 {
+		roots = []config.Root{{Path: "."}}
+	}
+	rv := &Workspace{}
+	seen := map[string]bool{}
+	for _, r := range roots {
+		root, err := newRoot(r)
+		if err != nil {
+			return nil, err
+		}
+		if seen[root.Name] {
+			return nil, fmt.Errorf("duplicate workspace root name %q", root.Name)
+		}
+		seen[root.Name] = true
+		rv.Roots = append(rv.Roots, root)
+	}
+	return rv, nil
+}
+
+func newRoot(r config.Root) (*Root, error) {
+	rv := &Root{Name: r.Name, Path: r.Path, FS: Local}
+	switch {
+	case r.SSH != "" && r.Container != "":
+		return nil, fmt.Errorf("invalid workspace root %q: only one of ssh and container can be set", r.Path)
+	case r.SSH != "" || r.Container != "":
+		if !filepath.IsAbs(r.Path) {
+			return nil, fmt.Errorf("invalid workspace root %q: remote paths must be absolute", r.Path)
+		}
+		rv.FS = NewSSH(r.SSH)
+		if r.Container != "" {
+			rv.FS = NewDocker(r.Container)
+		}
+		// resolved on the remote side, as Resolve compares paths with
+		// their real paths there
+		path, err := rv.FS.RealPath(filepath.Clean(r.Path))
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace root %q: %w", r.Path, err)
+		}
+		rv.Path = path
+	default:
+		path, err := filepath.Abs(r.Path)
+		if err != nil {
+			return nil, err
+		}
+		rv.Path, err = filepath.EvalSymlinks(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace root %q: %w", r.Path, err)
+		}
+	}
+	if rv.Name == "" {
+		rv.Name = filepath.Base(rv.Path)
+	}
+	return rv, nil
+}
+
+// Primary is the root that paths without a root prefix refer to.
+func (w *Workspace) Primary() *Root {
+	return w.Roots[0]
+}
+
+// Resolve maps a tool path, optionally prefixed with a root name and a
+// colon (e.g. "frontend:src/app.ts"), to an absolute path. Paths that
+// leave their root, directly or through a symlink, are rejected.
+func (w *Workspace) Resolve(path string) (*Root, string, error) {
+	root, rel := w.Primary(), path
+	if name, rest, ok := strings.Cut(path, ":"); ok {
+		if r := w.root(name); r != nil {
+			root, rel = r, rest
+		}
+	}
+
+	abs := rel
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(root.Path, rel)
+	}
+	abs = filepath.Clean(abs)
+	if !within(root.Path, abs) {
+		return nil, "", fmt.Errorf("path %q is outside of the workspace root %s", path, root.Name)
+	}
+	realPath, err := root.FS.RealPath(abs)
+	if err != nil {
+		return nil, "", err
+	}
+	if !within(root.Path, realPath) {
+		return nil, "", fmt.Errorf("path %q is outside of the workspace root %s", path, root.Name)
+	}
+	return root, abs, nil
+}
+
+// Display returns the path the model should use to refer to abs, which
+// is prefixed with the root name unless it is in the primary root.
+func (w *Workspace) Display(root *Root, abs string) string {
+	rel, err := filepath.Rel(root.Path, abs)
+	if err != nil {
+		rel = abs
+	}
+	if root == w.Primary() {
+		return rel
+	}
+	return root.Name + ":" + rel
+}
+
+// Describe explains the roots to the model, it is empty for a single root.
+func (w *Workspace) Describe() string {
+	if len(w.Roots) < 2 {
+		return ""
+	}
+	var names []string
+	for _, r := range w.Roots {
+		names = append(names, r.Name+":")
+	}
+	return fmt.Sprintf("The workspace has multiple roots, prefix paths with the root name and a colon to select one: %s. Paths without a prefix refer to %s.",
+		strings.Join(names, ", "), w.Primary().Name)
+}
+
+func (w *Workspace) root(name string) *Root {
+	for _, r := range w.Roots {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// evalExisting resolves symlinks in the longest existing prefix of path.
+func evalExisting(path string) string {
+	rest := ""
+	for p := path; ; p = filepath.Dir(p) {
+		if resolved, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		if p == filepath.Dir(p) {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+	}
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, w *Workspace) context.Context {
+	return context.WithValue(ctx, contextKey{}, w)
+}
+
+// FromContext returns the workspace set with NewContext, or one rooted at
+// the working directory.
+func FromContext(ctx context.Context) *Workspace {
+	if w, ok := ctx.Value(contextKey{}).(*Workspace); ok {
+		return w
+	}
+	w, err := New(nil)
+	if err != nil {
+		dir, _ := os.Getwd()
+		return &Workspace{Roots: []*Root{{Name: filepath.Base(dir), Path: dir, FS: Local}}}
+	}
+	return w
+}